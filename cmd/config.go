@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/javanhut/harbinger/internal/conflict"
+	"github.com/javanhut/harbinger/internal/git"
+	"github.com/javanhut/harbinger/pkg/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	configShowOrigin bool
+	configRepoPath   string
+	configLayer      string
+	configInitForce  bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the harbinger configuration",
+	Long: `Harbinger reads the global config file (~/.harbinger.yaml by default), then merges
+.harbinger.yaml from the repository root and harbinger.yaml from the repository's git
+directory over it. Later files win key by key; lists replace rather than extend.
+HARBINGER_<KEY> environment variables (e.g. HARBINGER_AUTO_SYNC=true) override
+every file, and command-line flags override everything.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get KEY",
+	Short: "Print the effective value of a config key",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set KEY VALUE",
+	Short: "Set a config key in one of the config files, keeping its comments",
+	Long: `Set a top-level config key. VALUE is parsed as YAML, so lists can be given
+inline, e.g. harbinger config set ignore_branches '[main, release/*]'.
+The file is only written if it is still a valid config afterwards.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runConfigSet,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check every config file that applies to a repository",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigValidate,
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a config file with documented defaults",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigInit,
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print a JSON schema for the config file",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigSchema,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration for a repository",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigShow,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd, configGetCmd, configSetCmd, configValidateCmd, configInitCmd, configSchemaCmd)
+
+	configCmd.PersistentFlags().StringVarP(&configRepoPath, "path", "p", ".", "Repository whose config files are layered over the global one")
+	configShowCmd.Flags().BoolVar(&configShowOrigin, "origin", false, "Show which file each value came from")
+	for _, cmd := range []*cobra.Command{configSetCmd, configInitCmd} {
+		cmd.Flags().StringVar(&configLayer, "layer", "global", "Config file to write: global, repository or personal")
+	}
+	configInitCmd.Flags().BoolVar(&configInitForce, "force", false, "Replace an existing file")
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	repoPath, gitDir := configRepoDirs(configRepoPath)
+	cfg, origins, err := config.LoadWithOrigins(repoPath, gitDir)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if configShowOrigin {
+		for _, layer := range config.Layers(repoPath, gitDir) {
+			state := ""
+			if _, err := os.Stat(layer.Path); err != nil {
+				state = " (not found)"
+			}
+			fmt.Printf("# %-12s%s%s\n", layer.Name+":", layer.Path, state)
+		}
+		overrides := config.EnvOverrides()
+		for _, key := range config.Keys() {
+			if _, ok := overrides[key]; ok {
+				fmt.Printf("# %-12s%s\n", "env:", config.EnvName(key))
+			}
+		}
+		fmt.Println()
+	}
+
+	out, err := renderConfig(cfg, origins, configShowOrigin)
+	if err != nil {
+		return err
+	}
+	fmt.Print(out)
+	return nil
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadRepository(configRepoDirs(configRepoPath))
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	value, err := config.Get(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Println(value)
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	layer, err := findLayer(configLayer)
+	if err != nil {
+		return err
+	}
+	if err := config.SetValue(layer, args[0], args[1]); err != nil {
+		return err
+	}
+	fmt.Printf("Set %s in %s\n", args[0], layer.Path)
+	return nil
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	repoPath, gitDir := configRepoDirs(configRepoPath)
+
+	failed := false
+	for _, layer := range config.Layers(repoPath, gitDir) {
+		if _, err := os.Stat(layer.Path); err != nil {
+			continue
+		}
+		if _, _, err := config.LoadLayers([]config.Layer{layer}); err != nil {
+			fmt.Printf("✗ %v\n", err)
+			failed = true
+			continue
+		}
+		fmt.Printf("✓ %s\n", layer.Path)
+	}
+	if failed {
+		return fmt.Errorf("config is invalid")
+	}
+
+	// The merged values, environment overrides included, must also make
+	// sense together
+	cfg, err := config.LoadRepository(repoPath, gitDir)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return fmt.Errorf("config is invalid")
+	}
+	var problems []string
+	if err := cfg.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := git.ParseSyncStrategy(cfg.SyncStrategy); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := conflict.OptionsFromConfig(cfg); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Printf("✗ %s\n", problem)
+		}
+		return fmt.Errorf("config is invalid")
+	}
+
+	fmt.Println("Config is valid")
+	return nil
+}
+
+func runConfigInit(cmd *cobra.Command, args []string) error {
+	layer, err := findLayer(configLayer)
+	if err != nil {
+		return err
+	}
+	if err := config.Init(layer, configInitForce); err != nil {
+		return err
+	}
+	fmt.Printf("Created %s\n", layer.Path)
+	return nil
+}
+
+func runConfigSchema(cmd *cobra.Command, args []string) error {
+	schema, err := config.Schema()
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(schema))
+	return nil
+}
+
+// findLayer returns the config file named by --layer for the repository at --path
+func findLayer(name string) (config.Layer, error) {
+	repoPath, gitDir := configRepoDirs(configRepoPath)
+	for _, layer := range config.Layers(repoPath, gitDir) {
+		if layer.Name == name {
+			return layer, nil
+		}
+	}
+	if name == "repository" || name == "personal" {
+		return config.Layer{}, fmt.Errorf("the %s config file needs a git repository: %s is not one", name, configRepoPath)
+	}
+	return config.Layer{}, fmt.Errorf("unknown config layer %q: use global, repository or personal", name)
+}
+
+// configRepoDirs returns the working tree and git directory whose config
+// files apply to path, or empty strings outside a repository
+func configRepoDirs(path string) (string, string) {
+	repo, err := git.NewRepository(path)
+	if err != nil {
+		return "", ""
+	}
+	return repo.Path(), repo.CommonDir()
+}
+
+// renderConfig prints the config as YAML, optionally with the origin of each
+// top-level key as a trailing comment
+func renderConfig(cfg *config.Config, origins config.Origins, withOrigin bool) (string, error) {
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		return "", fmt.Errorf("failed to encode config: %w", err)
+	}
+	if withOrigin {
+		for i := 0; i+1 < len(doc.Content); i += 2 {
+			key := doc.Content[i]
+			key.LineComment = origins.Of(key.Value)
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return "", fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode config: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/javanhut/harbinger/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderConfig_Origins(t *testing.T) {
+	cfg := &config.Config{PollInterval: config.Duration(time.Minute), IgnoreBranches: []string{"main"}}
+	origins := config.Origins{"poll_interval": "/repo/.harbinger.yaml"}
+
+	out, err := renderConfig(cfg, origins, false)
+	require.NoError(t, err)
+	assert.Contains(t, out, "poll_interval: 1m\n")
+	assert.NotContains(t, out, "#")
+
+	out, err = renderConfig(cfg, origins, true)
+	require.NoError(t, err)
+	assert.Contains(t, out, "poll_interval: 1m # /repo/.harbinger.yaml\n")
+	assert.Contains(t, out, "ignore_branches: # default\n  - main\n")
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/javanhut/harbinger/internal/daemon"
+	"github.com/javanhut/harbinger/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	daemonDetach       bool
+	daemonInterval     time.Duration
+	daemonRemoteBranch string
+	daemonRemote       string
+	daemonWorktrees    bool
+	daemonSubmodules   bool
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Manage the harbinger daemon that monitors many repositories",
+	Long: `The harbinger daemon is a single long-lived process that monitors every repository
+listed under "repositories" in ~/.harbinger.yaml, plus any repository added at runtime.`,
+}
+
+var daemonStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the daemon",
+	Args:  cobra.NoArgs,
+	RunE:  runDaemonStart,
+}
+
+var daemonAddCmd = &cobra.Command{
+	Use:   "add [PATH]",
+	Short: "Add a repository to the running daemon",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runDaemonAdd,
+}
+
+var daemonRemoveCmd = &cobra.Command{
+	Use:   "remove [PATH]",
+	Short: "Remove a repository from the running daemon",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runDaemonRemove,
+}
+
+var daemonListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List repositories monitored by the daemon",
+	Args:  cobra.NoArgs,
+	RunE:  runDaemonList,
+}
+
+var daemonReloadCmd = &cobra.Command{
+	Use:   "reload [PATH]",
+	Short: "Re-read the config files now, for a repository or every repository",
+	Long: `Monitors pick up edited config files within a few seconds on their own. reload applies
+them immediately and re-reads the daemon's repositories list; sending SIGHUP to a monitor
+or daemon process does the same.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runDaemonReload,
+}
+
+var daemonCheckCmd = &cobra.Command{
+	Use:   "check [PATH]",
+	Short: "Check a repository (or every repository) for changes right now",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runDaemonControl(daemon.CommandCheck, "Requested an immediate check for"),
+}
+
+var daemonPauseCmd = &cobra.Command{
+	Use:   "pause [PATH]",
+	Short: "Pause periodic checks for a repository (or every repository)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runDaemonControl(daemon.CommandPause, "Paused"),
+}
+
+var daemonResumeCmd = &cobra.Command{
+	Use:   "resume [PATH]",
+	Short: "Resume periodic checks for a repository (or every repository)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runDaemonControl(daemon.CommandResume, "Resumed"),
+}
+
+var daemonIntervalCmd = &cobra.Command{
+	Use:   "interval DURATION [PATH]",
+	Short: "Change the polling interval of a repository (or every repository)",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE:  runDaemonInterval,
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.AddCommand(daemonStartCmd, daemonAddCmd, daemonRemoveCmd, daemonListCmd, daemonReloadCmd)
+	daemonCmd.AddCommand(daemonCheckCmd, daemonPauseCmd, daemonResumeCmd, daemonIntervalCmd)
+
+	daemonStartCmd.Flags().BoolVarP(&daemonDetach, "detach", "d", false, "Run the daemon in the background")
+	daemonAddCmd.Flags().DurationVarP(&daemonInterval, "interval", "i", 0, "Polling interval (defaults to poll_interval from config)")
+	daemonAddCmd.Flags().StringVarP(&daemonRemoteBranch, "remote-branch", "r", "", "Remote branch to monitor (e.g., 'main', 'develop')")
+	daemonAddCmd.Flags().StringVar(&daemonRemote, "remote", "", "Remote to compare against (defaults to the branch's upstream remote)")
+	daemonAddCmd.Flags().BoolVar(&daemonWorktrees, "worktrees", false, "Monitor every worktree of the repository")
+	daemonAddCmd.Flags().BoolVar(&daemonSubmodules, "submodules", false, "Also report submodules that are behind their upstream branch")
+	daemonRemoveCmd.Flags().StringVarP(&daemonRemoteBranch, "remote-branch", "r", "", "Only remove the monitor for this remote branch")
+}
+
+func runDaemonStart(cmd *cobra.Command, args []string) error {
+	socketPath := daemon.DefaultSocketPath()
+
+	if daemonDetach {
+		if daemon.IsRunning(socketPath) {
+			return fmt.Errorf("a harbinger daemon is already running")
+		}
+		pid, err := startDaemonProcess(socketPath)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Running harbinger daemon in background with process ID: %d\n", pid)
+		fmt.Printf("View logs: harbinger logs %d\n", pid)
+		fmt.Println("Stop daemon: harbinger stop --all")
+		return nil
+	}
+
+	listener, err := daemon.Listen(socketPath)
+	if err != nil {
+		return err
+	}
+
+	d := daemon.New()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Printf("Warning: failed to load config: %v", err)
+	} else {
+		for _, err := range d.Reconcile(cfg) {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	go d.WatchConfig()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		err := d.Serve(listener)
+		if err != nil {
+			log.Printf("[%s] Control socket error: %v", time.Now().Format(time.RFC3339), err)
+		}
+		serveErr <- err
+	}()
+
+	sigChan := make(chan os.Signal, 1)
+	notifySignals(sigChan)
+	reloadChan := make(chan os.Signal, 1)
+	notifyReload(reloadChan)
+
+	log.Printf("[%s] Harbinger daemon started (PID %d), control socket: %s", time.Now().Format(time.RFC3339), os.Getpid(), socketPath)
+
+	waitForStop(d, sigChan, reloadChan, serveErr)
+
+	d.Shutdown()
+	os.Remove(socketPath)
+	log.Printf("[%s] Harbinger daemon stopped", time.Now().Format(time.RFC3339))
+	return nil
+}
+
+// waitForStop blocks until a stop signal, a shutdown request or a control
+// socket failure, reloading the config files on every reload signal. A nil
+// serveErr channel is never ready.
+func waitForStop(d *daemon.Daemon, sigChan, reloadChan <-chan os.Signal, serveErr <-chan error) {
+	for {
+		select {
+		case <-sigChan:
+			log.Printf("[%s] Received stop signal", time.Now().Format(time.RFC3339))
+			return
+		case <-d.Done():
+			return
+		case <-serveErr:
+			return
+		case <-reloadChan:
+			log.Printf("[%s] Received reload signal, reloading configuration", time.Now().Format(time.RFC3339))
+			for _, err := range d.Reload() {
+				log.Printf("[%s] Warning: %v", time.Now().Format(time.RFC3339), err)
+			}
+		}
+	}
+}
+
+// startDaemonProcess launches the daemon in the background and waits until
+// its control socket accepts requests
+func startDaemonProcess(socketPath string) (int, error) {
+	pid, err := spawnDetached([]string{"daemon", "start"})
+	if err != nil {
+		return 0, err
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if daemon.IsRunning(socketPath) {
+			return pid, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return 0, fmt.Errorf("daemon (PID %d) did not start in time, check 'harbinger logs %d'", pid, pid)
+}
+
+func runDaemonAdd(cmd *cobra.Command, args []string) error {
+	path, err := pathArg(args)
+	if err != nil {
+		return err
+	}
+
+	req := daemon.Request{
+		Command:      daemon.CommandAdd,
+		Path:         path,
+		RemoteBranch: daemonRemoteBranch,
+		Remote:       daemonRemote,
+		Worktrees:    daemonWorktrees,
+		Submodules:   daemonSubmodules,
+	}
+	if daemonInterval > 0 {
+		req.PollInterval = daemonInterval.String()
+	}
+
+	if _, err := daemon.Send(daemon.DefaultSocketPath(), req); err != nil {
+		return fmt.Errorf("failed to add repository: %w", err)
+	}
+
+	fmt.Printf("Added %s to the harbinger daemon\n", path)
+	return nil
+}
+
+func runDaemonRemove(cmd *cobra.Command, args []string) error {
+	path, err := pathArg(args)
+	if err != nil {
+		return err
+	}
+
+	resp, err := daemon.Send(daemon.DefaultSocketPath(), daemon.Request{
+		Command:      daemon.CommandRemove,
+		Path:         path,
+		RemoteBranch: daemonRemoteBranch,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove repository: %w", err)
+	}
+
+	fmt.Printf("Removed %d monitor(s) for %s\n", resp.Removed, path)
+	return nil
+}
+
+func runDaemonList(cmd *cobra.Command, args []string) error {
+	resp, err := daemon.Send(daemon.DefaultSocketPath(), daemon.Request{Command: daemon.CommandList})
+	if err != nil {
+		return err
+	}
+
+	printDaemonMonitors(resp)
+	return nil
+}
+
+func runDaemonReload(cmd *cobra.Command, args []string) error {
+	req := daemon.Request{Command: daemon.CommandReload}
+	if len(args) > 0 {
+		path, err := pathArg(args)
+		if err != nil {
+			return err
+		}
+		req.Path = path
+	}
+
+	resp, err := sendControl(req)
+	if err != nil {
+		return fmt.Errorf("reload failed: %w", err)
+	}
+
+	fmt.Println("Configuration reloaded")
+	printDaemonMonitors(resp)
+	return nil
+}
+
+// runDaemonControl returns a command handler that sends a control request for
+// the repository in the optional PATH argument, or for all repositories
+func runDaemonControl(command, verb string) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		req := daemon.Request{Command: command}
+		if len(args) > 0 {
+			path, err := pathArg(args)
+			if err != nil {
+				return err
+			}
+			req.Path = path
+		}
+
+		resp, err := sendControl(req)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s %d monitor(s)\n", verb, resp.Affected)
+		return nil
+	}
+}
+
+func runDaemonInterval(cmd *cobra.Command, args []string) error {
+	interval, err := time.ParseDuration(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid interval: %w", err)
+	}
+
+	req := daemon.Request{Command: daemon.CommandInterval, PollInterval: interval.String()}
+	if len(args) > 1 {
+		path, err := pathArg(args[1:])
+		if err != nil {
+			return err
+		}
+		req.Path = path
+	}
+
+	resp, err := sendControl(req)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Set poll interval to %s for %d monitor(s)\n", interval, resp.Affected)
+	return nil
+}
+
+// sendControl delivers a control request to the daemon. Requests for a
+// repository the daemon does not own fall back to that repository's
+// standalone monitor socket.
+func sendControl(req daemon.Request) (*daemon.Response, error) {
+	resp, err := daemon.Send(daemon.DefaultSocketPath(), req)
+	if err == nil || req.Path == "" {
+		return resp, err
+	}
+
+	standaloneResp, standaloneErr := daemon.Send(getSocketFileForRepoAndBranch(req.Path, req.RemoteBranch), req)
+	if standaloneErr == nil {
+		return standaloneResp, nil
+	}
+	if errors.Is(standaloneErr, daemon.ErrNotRunning) {
+		return nil, err
+	}
+	return nil, standaloneErr
+}
+
+func printDaemonMonitors(resp *daemon.Response) {
+	if len(resp.Monitors) == 0 {
+		fmt.Println("The harbinger daemon is not monitoring any repositories")
+		return
+	}
+
+	fmt.Printf("Harbinger daemon (PID %d) is monitoring:\n", resp.PID)
+	fmt.Println("Interval\tRemote Branch\tRepository")
+	fmt.Println("--------\t-------------\t----------")
+	for _, mon := range resp.Monitors {
+		branch := mon.RemoteRef
+		if branch == "" {
+			branch = "(upstream)"
+		}
+		fmt.Printf("%s\t\t%s\t%s\n", mon.PollInterval, branch, mon.Path)
+	}
+}
+
+// pathArg returns the absolute repository path from the optional PATH argument
+func pathArg(args []string) (string, error) {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path for repository: %w", err)
+	}
+	return absPath, nil
+}
@@ -233,7 +233,7 @@ ignore_branches:
 	require.NoError(t, err)
 
 	// Verify config was loaded correctly
-	assert.Equal(t, "45s", cfg.PollInterval)
+	assert.Equal(t, "45s", cfg.PollInterval.String())
 	assert.Equal(t, "code", cfg.Editor)
 	assert.True(t, cfg.Notifications)
 	assert.False(t, cfg.AutoResolve)
@@ -129,11 +129,12 @@ func initConfig() {
 		configPath := filepath.Join(home, ".harbinger.yaml")
 		if _, err := os.Stat(configPath); os.IsNotExist(err) {
 			defaultConfig := &config.Config{
-				PollInterval:   "30s",
+				PollInterval:   config.Duration(config.DefaultPollInterval),
 				Editor:         "code",
 				Notifications:  true,
 				AutoResolve:    true,
 				AutoSync:       false,
+				SyncStrategy:   "merge",
 				IgnoreBranches: []string{"main", "master"},
 			}
 			if err := config.Save(defaultConfig); err != nil {
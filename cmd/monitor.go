@@ -9,7 +9,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/javanhut/harbinger/internal/monitor"
+	"github.com/javanhut/harbinger/internal/daemon"
 	"github.com/spf13/cobra"
 )
 
@@ -18,6 +18,10 @@ var (
 	repoPath     string
 	detach       bool
 	remoteBranch string
+	remoteName   string
+	standalone   bool
+	worktrees    bool
+	submodules   bool
 )
 
 var monitorCmd = &cobra.Command{
@@ -29,10 +33,14 @@ var monitorCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(monitorCmd)
-	monitorCmd.Flags().DurationVarP(&pollInterval, "interval", "i", 30*time.Second, "Polling interval for checking remote changes")
+	monitorCmd.Flags().DurationVarP(&pollInterval, "interval", "i", 0, "Polling interval for checking remote changes (default: poll_interval from config, or 30s)")
 	monitorCmd.Flags().StringVarP(&repoPath, "path", "p", ".", "Path to the Git repository to monitor")
 	monitorCmd.Flags().BoolVarP(&detach, "detach", "d", false, "Run monitor in the background")
 	monitorCmd.Flags().StringVarP(&remoteBranch, "remote-branch", "r", "", "Remote branch to monitor (e.g., 'main', 'develop')")
+	monitorCmd.Flags().StringVar(&remoteName, "remote", "", "Remote to compare against (defaults to the branch's upstream remote)")
+	monitorCmd.Flags().BoolVar(&worktrees, "worktrees", false, "Monitor every worktree of the repository")
+	monitorCmd.Flags().BoolVar(&submodules, "submodules", false, "Also report submodules that are behind their upstream branch")
+	monitorCmd.Flags().BoolVar(&standalone, "standalone", false, "With --detach, run a separate background process instead of using the shared daemon")
 }
 
 func runMonitor(cmd *cobra.Command, args []string) error {
@@ -49,54 +57,138 @@ func runMonitor(cmd *cobra.Command, args []string) error {
 
 	fmt.Println("Starting Git conflict monitor...")
 
-	// Create monitor
-	m, err := monitor.New(repoPath, monitor.Options{
+	// A single-repository daemon gives foreground and standalone monitors the
+	// same control socket as the shared daemon
+	d := daemon.New()
+	if err := d.Add(daemon.Spec{
+		Path:         repoPath,
 		PollInterval: pollInterval,
 		RemoteBranch: remoteBranch,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create monitor: %w", err)
+		Remote:       remoteName,
+		Worktrees:    worktrees,
+		Submodules:   submodules,
+	}); err != nil {
+		return err
+	}
+
+	socketPath := getSocketFileForRepoAndBranch(repoPath, remoteBranch)
+	if listener, err := daemon.Listen(socketPath); err != nil {
+		log.Printf("Warning: control socket unavailable: %v", err)
+	} else {
+		go d.Serve(listener)
+		defer os.Remove(socketPath)
 	}
 
 	// Setup signal handling
 	sigChan := make(chan os.Signal, 1)
 	notifySignals(sigChan)
+	reloadChan := make(chan os.Signal, 1)
+	notifyReload(reloadChan)
 
-	// Start monitoring
-	if err := m.Start(); err != nil {
-		return fmt.Errorf("failed to start monitor: %w", err)
+	for _, mon := range d.List() {
+		fmt.Printf("Monitoring repository at %s (checking every %s)\n", mon.Path, mon.PollInterval)
 	}
-
-	fmt.Printf("Monitoring repository at %s (checking every %s)\n", repoPath, pollInterval)
 	fmt.Println("Press Ctrl+C to stop...")
 
-	// Wait for interrupt
-	<-sigChan
+	// Wait for interrupt or a shutdown request on the control socket
+	waitForStop(d, sigChan, reloadChan, nil)
 
 	fmt.Println("\nStopping monitor...")
-	if err := m.Stop(); err != nil {
-		log.Printf("Error stopping monitor: %v", err)
-	}
+	d.Shutdown()
 
 	return nil
 }
 
 func runDetachedMonitor() error {
-	// Get current executable path
-	exe, err := os.Executable()
+	if standalone {
+		return runStandaloneMonitor()
+	}
+
+	socketPath := daemon.DefaultSocketPath()
+	if !daemon.IsRunning(socketPath) {
+		pid, err := startDaemonProcess(socketPath)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Started harbinger daemon with process ID: %d\n", pid)
+	}
+
+	req := daemon.Request{
+		Command:      daemon.CommandAdd,
+		Path:         repoPath,
+		RemoteBranch: remoteBranch,
+		Remote:       remoteName,
+		Worktrees:    worktrees,
+		Submodules:   submodules,
+	}
+	if pollInterval > 0 {
+		req.PollInterval = pollInterval.String()
+	}
+	resp, err := daemon.Send(socketPath, req)
 	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
+		return fmt.Errorf("failed to register repository with daemon: %w", err)
 	}
 
+	fmt.Printf("Monitoring repository: %s\n", repoPath)
+	fmt.Printf("View logs: harbinger logs %d\n", resp.PID)
+	fmt.Printf("Stop monitor: harbinger stop %s\n", repoPath)
+
+	return nil
+}
+
+// runStandaloneMonitor runs the monitor in its own background process,
+// tracked by a repository-specific PID file instead of the daemon
+func runStandaloneMonitor() error {
 	// Build command args without the detach flag
 	args := []string{"monitor"}
-	if pollInterval != 30*time.Second {
+	if pollInterval > 0 {
 		args = append(args, "--interval", pollInterval.String())
 	}
 	args = append(args, "--path", repoPath)
 	if remoteBranch != "" {
 		args = append(args, "--remote-branch", remoteBranch)
 	}
+	if remoteName != "" {
+		args = append(args, "--remote", remoteName)
+	}
+	if worktrees {
+		args = append(args, "--worktrees")
+	}
+	if submodules {
+		args = append(args, "--submodules")
+	}
+
+	pid, err := spawnDetached(args)
+	if err != nil {
+		return err
+	}
+
+	// Write PID to file for later stopping
+	pidFile := getPIDFileForRepoAndBranch(repoPath, remoteBranch)
+	if err := writePIDFile(pidFile, pid); err != nil {
+		log.Printf("Warning: failed to write PID file: %v", err)
+	}
+
+	fmt.Printf("Running harbinger in background with process ID: %d\n", pid)
+	fmt.Printf("Monitoring repository: %s\n", repoPath)
+	fmt.Printf("View logs: harbinger logs %d\n", pid)
+	fmt.Printf("Stop monitor: harbinger stop %d\n", pid)
+
+	return nil
+}
+
+// spawnDetached re-executes harbinger with the given arguments in the
+// background, sending its output to a PID-specific log file
+func spawnDetached(args []string) (int, error) {
+	// Get current executable path
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	if cfgFile != "" {
+		args = append(args, "--config", cfgFile)
+	}
 
 	// Start process in background
 	cmd := exec.Command(exe, args...)
@@ -106,43 +198,31 @@ func runDetachedMonitor() error {
 	// Create log file in the user's home directory
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return 0, fmt.Errorf("failed to get home directory: %w", err)
 	}
 
 	// Use a temporary PID for the log file name
 	tempPID := os.Getpid()
 	logPath := filepath.Join(home, fmt.Sprintf(".harbinger.temp.%d.log", tempPID))
-	
+
 	// Redirect output to log file
 	if err := redirectOutputToLog(cmd, logPath); err != nil {
-		return fmt.Errorf("failed to redirect output: %w", err)
+		return 0, fmt.Errorf("failed to redirect output: %w", err)
 	}
 
 	// Start the process
 	if err := cmd.Start(); err != nil {
 		os.Remove(logPath)
-		return fmt.Errorf("failed to start background process: %w", err)
+		return 0, fmt.Errorf("failed to start background process: %w", err)
 	}
 
 	// Now rename the log file with the actual PID
 	actualLogPath := getLogFileForPID(cmd.Process.Pid)
 	if err := os.Rename(logPath, actualLogPath); err != nil {
-		// If rename fails, keep the temp name
-		actualLogPath = logPath
+		log.Printf("Warning: failed to rename log file, logs remain in %s", logPath)
 	}
 
-	// Write PID to file for later stopping
-	pidFile := getPIDFileForRepoAndBranch(repoPath, remoteBranch)
-	if err := writePIDFile(pidFile, cmd.Process.Pid); err != nil {
-		log.Printf("Warning: failed to write PID file: %v", err)
-	}
-
-	fmt.Printf("Running harbinger in background with process ID: %d\n", cmd.Process.Pid)
-	fmt.Printf("Monitoring repository: %s\n", repoPath)
-	fmt.Printf("View logs: harbinger logs %d\n", cmd.Process.Pid)
-	fmt.Printf("Stop monitor: harbinger stop %d\n", cmd.Process.Pid)
-
-	return nil
+	return cmd.Process.Pid, nil
 }
 
 func getPIDFileDefaultPath() string {
@@ -188,6 +268,12 @@ func getPIDFileForRepoAndBranch(repoPath, branch string) string {
 	return filepath.Join(home, fmt.Sprintf(".harbinger-%s-%s.pid", safeRepoName, hash[:8]))
 }
 
+// getSocketFileForRepoAndBranch returns the control socket path used by a
+// foreground or standalone monitor, next to its PID file
+func getSocketFileForRepoAndBranch(repoPath, branch string) string {
+	return strings.TrimSuffix(getPIDFileForRepoAndBranch(repoPath, branch), ".pid") + ".sock"
+}
+
 // Simple string hash function for generating unique IDs
 func hashString(s string) uint32 {
 	var h uint32 = 2166136261
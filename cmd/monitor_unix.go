@@ -16,6 +16,11 @@ func notifySignals(sigChan chan os.Signal) {
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 }
 
+func notifyReload(reloadChan chan os.Signal) {
+	// SIGHUP asks a running monitor or daemon to re-read its config files
+	signal.Notify(reloadChan, syscall.SIGHUP)
+}
+
 func setPlatformProcessAttributes(cmd *exec.Cmd) {
 	// On Unix-like systems, create a new process group
 	// to prevent signals from being passed to the parent.
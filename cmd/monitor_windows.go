@@ -17,6 +17,11 @@ func notifySignals(sigChan chan os.Signal) {
 	signal.Notify(sigChan, os.Interrupt)
 }
 
+func notifyReload(reloadChan chan os.Signal) {
+	// There is no SIGHUP on Windows; config files are still watched and
+	// "harbinger daemon reload" reloads them on request.
+}
+
 func setPlatformProcessAttributes(cmd *exec.Cmd) {
 	// On Windows, we can create a new process group to prevent the new process
 	// from being affected by Ctrl+C events in the parent console.
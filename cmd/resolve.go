@@ -1,25 +1,46 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
 	"os"
-	"path/filepath"
+	"path"
 	"strings"
 
 	"github.com/javanhut/harbinger/internal/conflict"
 	"github.com/javanhut/harbinger/internal/git"
+	"github.com/javanhut/harbinger/pkg/config"
 	"github.com/spf13/cobra"
 )
 
+var (
+	resolveStrategy string
+	resolvePaths    []string
+	resolveEditor   string
+)
+
 var resolveCmd = &cobra.Command{
 	Use:   "resolve",
 	Short: "Manually resolve merge conflicts in the current repository",
-	Long:  `Launch the interactive conflict resolution UI to manually resolve any merge conflicts in the current repository.`,
-	RunE:  runResolve,
+	Long: `Launch the interactive conflict resolution UI to manually resolve any merge conflicts in the current repository.
+
+Conflicts from a merge, rebase, cherry-pick or revert are supported. Once they are
+resolved you can continue, skip or abort the operation from the same prompt.
+
+Files matching a resolve_rules entry in the config are resolved automatically first,
+then generated files handled by an enabled regenerator are rebuilt.
+With --strategy, conflicted files (or those matching --paths) are resolved without
+prompting, and the command fails if any conflicts remain. Files matching --paths
+take the strategy ahead of the config; without --paths the strategy only applies
+to the files the rules and regenerators left.`,
+	RunE: runResolve,
 }
 
 func init() {
 	rootCmd.AddCommand(resolveCmd)
+	resolveCmd.Flags().StringVar(&resolveStrategy, "strategy", "", "Resolve without prompting: ours, theirs or union")
+	resolveCmd.Flags().StringVar(&resolveEditor, "editor", "", "Editor for conflicted files (default: editor from config, then $EDITOR)")
+	resolveCmd.Flags().StringSliceVar(&resolvePaths, "paths", nil, "Glob patterns of files to resolve with --strategy (default: all conflicted files)")
 }
 
 func runResolve(cmd *cobra.Command, args []string) error {
@@ -35,64 +56,202 @@ func runResolve(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to initialize repository: %w", err)
 	}
 
-	// Check if we're in a merge state
-	gitDir := filepath.Join(wd, ".git")
-	mergeHeadPath := filepath.Join(gitDir, "MERGE_HEAD")
-	if _, err := os.Stat(mergeHeadPath); os.IsNotExist(err) {
-		fmt.Println("No merge conflicts detected. Repository is in a clean state.")
-		return nil
+	options, err := resolveOptions(repo)
+	if err != nil {
+		return err
 	}
+	resolver := conflict.NewResolverWithOptions(repo, options)
+
+	// A rebase or multi-commit cherry-pick can stop again after continuing,
+	// so keep going until the operation finishes or the user leaves
+	for {
+		op, err := repo.CurrentOperation()
+		if err != nil {
+			return fmt.Errorf("failed to detect in-progress operation: %w", err)
+		}
+
+		// Find conflicted files
+		conflicts, err := findConflictedFiles(repo)
+		if err != nil {
+			return fmt.Errorf("failed to find conflicted files: %w", err)
+		}
+
+		if op == nil && len(conflicts) == 0 {
+			fmt.Println("No merge conflicts detected. Repository is in a clean state.")
+			return nil
+		}
+
+		if op != nil {
+			fmt.Printf("In progress: %s\n\n", op.Describe())
+		}
+
+		if len(conflicts) == 0 {
+			fmt.Println("No conflicted files left.")
+		} else {
+			fmt.Printf("Found %d conflicted file(s):\n", len(conflicts))
+			for _, conflict := range conflicts {
+				fmt.Printf("  - %s\n", conflict.Summary())
+			}
+			fmt.Println()
+
+			if resolveStrategy != "" {
+				remaining := resolver.AutoResolve(conflicts)
+				if len(remaining) > 0 {
+					fmt.Printf("\n%d conflicted file(s) remain:\n", len(remaining))
+					for _, c := range remaining {
+						fmt.Printf("  - %s\n", c.Summary())
+					}
+					// Not a usage error, scripts only need the exit status
+					cmd.SilenceUsage = true
+					return fmt.Errorf("%d conflict(s) left unresolved", len(remaining))
+				}
+				fmt.Println("\nAll conflicts resolved.")
+			} else if err := resolver.ResolveConflicts(conflicts); err != nil {
+				// Launch conflict resolution UI
+				return fmt.Errorf("failed to resolve conflicts: %w", err)
+			}
+		}
+
+		if op == nil {
+			// Unmerged files without an operation come from commands such as
+			// git stash pop, which keeps the stash entry when it conflicts
+			fmt.Println("\nIf these conflicts came from 'git stash pop', the stash entry was kept: drop it with 'git stash drop' once you are done.")
+			return nil
+		}
+		if resolveStrategy != "" {
+			fmt.Printf("Run 'git %s --continue' to finish.\n", op.Kind)
+			return nil
+		}
+
+		again, err := promptOperation(repo, op)
+		if err != nil || !again {
+			return err
+		}
+		fmt.Println()
+	}
+}
 
-	// Find conflicted files
-	conflicts, err := findConflictedFiles(repo)
+// promptOperation asks whether to continue, skip or abort an in-progress
+// operation once its conflicts are dealt with. It reports whether the
+// operation stopped again and needs another round of resolution.
+func promptOperation(repo *git.Repository, op *git.Operation) (bool, error) {
+	remaining, err := repo.GetConflictedFiles()
 	if err != nil {
-		return fmt.Errorf("failed to find conflicted files: %w", err)
+		return false, err
 	}
 
-	if len(conflicts) == 0 {
-		fmt.Println("No conflicted files found.")
-		return nil
+	fmt.Println()
+	if len(remaining) == 0 {
+		fmt.Printf("  [c] Continue the %s\n", op.Kind)
+	} else {
+		fmt.Printf("  %d file(s) are still conflicted, resolve them to continue\n", len(remaining))
+	}
+	if op.CanSkip() {
+		fmt.Printf("  [s] Skip %s\n", describeCommit(op))
 	}
+	fmt.Printf("  [a] Abort the %s\n", op.Kind)
+	fmt.Println("  [q] Leave it for now")
+	fmt.Print("Your choice: ")
 
-	fmt.Printf("Found %d conflicted file(s):\n", len(conflicts))
-	for _, conflict := range conflicts {
-		fmt.Printf("  - %s\n", conflict.File)
+	reader := bufio.NewReader(os.Stdin)
+	choice, err := reader.ReadString('\n')
+	if err != nil && strings.TrimSpace(choice) == "" {
+		// No more input, leave the operation as it is
+		choice = "q"
 	}
-	fmt.Println()
 
-	// Launch conflict resolution UI
-	resolver := conflict.NewResolver(repo)
-	if err := resolver.ResolveConflicts(conflicts); err != nil {
-		return fmt.Errorf("failed to resolve conflicts: %w", err)
+	switch strings.TrimSpace(strings.ToLower(choice)) {
+	case "c":
+		if len(remaining) > 0 {
+			return promptOperation(repo, op)
+		}
+		if err := repo.ContinueOperation(op); err != nil {
+			return false, err
+		}
+	case "s":
+		if !op.CanSkip() {
+			return promptOperation(repo, op)
+		}
+		if err := repo.SkipOperation(op); err != nil {
+			return false, err
+		}
+	case "a":
+		if err := repo.AbortOperation(op); err != nil {
+			return false, err
+		}
+		fmt.Printf("Aborted the %s.\n", op.Kind)
+		return false, nil
+	case "q":
+		fmt.Printf("Left the %s in progress. Run 'harbinger resolve' or 'git %s --continue' when ready.\n", op.Kind, op.Kind)
+		return false, nil
+	default:
+		return promptOperation(repo, op)
 	}
 
-	return nil
+	next, err := repo.CurrentOperation()
+	if err != nil {
+		return false, err
+	}
+	if next == nil {
+		fmt.Printf("The %s is complete.\n", op.Kind)
+		return false, nil
+	}
+	return true, nil
 }
 
-func findConflictedFiles(repo *git.Repository) ([]git.Conflict, error) {
-	// Get list of conflicted files from git status
-	conflictedFiles, err := repo.GetConflictedFiles()
-	if err != nil {
-		return nil, err
+// describeCommit names the commit an operation is stopped at
+func describeCommit(op *git.Operation) string {
+	if op.Commit == "" {
+		return "this commit"
 	}
+	if len(op.Commit) > 8 {
+		return op.Commit[:8]
+	}
+	return op.Commit
+}
 
-	var conflicts []git.Conflict
-	for _, file := range conflictedFiles {
-		// Read file content
-		fullPath := filepath.Join(repo.Path(), file)
-		content, err := os.ReadFile(fullPath)
+// resolveOptions builds the resolver options for this run: --strategy for
+// the files matching --paths, then the resolve_rules and regenerators from
+// the config. Without --paths, --strategy only resolves the files the config
+// left.
+func resolveOptions(repo *git.Repository) (conflict.Options, error) {
+	var rules []conflict.Rule
+	var fallback conflict.Strategy
+	if resolveStrategy != "" {
+		strategy, err := conflict.ParseStrategy(resolveStrategy)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read file %s: %w", file, err)
+			return conflict.Options{}, err
 		}
-
-		// Check if file contains conflict markers
-		if strings.Contains(string(content), "<<<<<<<") {
-			conflicts = append(conflicts, git.Conflict{
-				File:    file,
-				Content: string(content),
-			})
+		if len(resolvePaths) == 0 {
+			fallback = strategy
 		}
+		for _, pattern := range resolvePaths {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return conflict.Options{}, fmt.Errorf("invalid --paths pattern %q: %w", pattern, err)
+			}
+			rules = append(rules, conflict.Rule{Pattern: pattern, Strategy: strategy})
+		}
+	} else if len(resolvePaths) > 0 {
+		return conflict.Options{}, fmt.Errorf("--paths requires --strategy")
+	}
+
+	cfg, err := config.LoadRepository(repo.Path(), repo.CommonDir())
+	if err != nil {
+		return conflict.Options{}, fmt.Errorf("failed to load config: %w", err)
+	}
+	options, err := conflict.OptionsFromConfig(cfg)
+	if err != nil {
+		return conflict.Options{}, err
 	}
+	options.Rules = append(rules, options.Rules...)
+	options.Fallback = fallback
+	if resolveEditor != "" {
+		options.Editor = resolveEditor
+	}
+	return options, nil
+}
 
-	return conflicts, nil
+func findConflictedFiles(repo *git.Repository) ([]git.Conflict, error) {
+	// The index knows every unmerged path, including deleted and binary ones
+	return repo.GetUnmergedConflicts()
 }
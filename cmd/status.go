@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/javanhut/harbinger/internal/daemon"
+	"github.com/javanhut/harbinger/internal/git"
+	"github.com/javanhut/harbinger/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statusPath   string
+	statusAll    bool
+	statusOutput string
+	statusFetch  bool
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Report how a repository is in sync with its remote",
+	Long: `Reports the branch, upstream, ahead/behind counts, uncommitted changes, in-progress
+merge or rebase and predicted conflicting files for the current repository, or for every
+monitored repository with --all. Running monitors are queried over their control sockets.`,
+	Args: cobra.NoArgs,
+	RunE: runStatus,
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().StringVarP(&statusPath, "path", "p", ".", "Path to the Git repository to report on")
+	statusCmd.Flags().BoolVarP(&statusAll, "all", "a", false, "Report on every monitored repository")
+	statusCmd.Flags().StringVarP(&statusOutput, "output", "o", "text", "Output format: text or json")
+	statusCmd.Flags().BoolVar(&statusFetch, "fetch", false, "Fetch from remotes before reporting")
+}
+
+// repoStatus is the sync report for a repository together with the state of
+// any monitor watching it
+type repoStatus struct {
+	*git.SyncReport
+	Monitors []monitorState `json:"monitors,omitempty"`
+}
+
+// monitorState is a monitor reported by a control socket together with the
+// process that owns it
+type monitorState struct {
+	PID int `json:"pid"`
+	daemon.MonitorInfo
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	if statusOutput != "text" && statusOutput != "json" {
+		return fmt.Errorf("invalid output format %q: must be text or json", statusOutput)
+	}
+
+	monitors := collectRunningMonitors()
+
+	var paths []string
+	if statusAll {
+		paths = monitoredRepoPaths(monitors)
+	} else {
+		absPath, err := filepath.Abs(statusPath)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path for repository: %w", err)
+		}
+		paths = []string{absPath}
+	}
+
+	statuses := make([]repoStatus, 0, len(paths))
+	for _, path := range paths {
+		status, err := buildRepoStatus(path, monitors)
+		if err != nil {
+			if !statusAll {
+				return err
+			}
+			status = repoStatus{SyncReport: &git.SyncReport{Path: path, Errors: []string{err.Error()}}}
+		}
+		statuses = append(statuses, status)
+	}
+
+	if statusOutput == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if statusAll {
+			return encoder.Encode(statuses)
+		}
+		return encoder.Encode(statuses[0])
+	}
+
+	if len(statuses) == 0 {
+		fmt.Println("No repositories are being monitored")
+		return nil
+	}
+	for _, status := range statuses {
+		printRepoStatus(status)
+	}
+	return nil
+}
+
+func buildRepoStatus(path string, monitors []monitorState) (repoStatus, error) {
+	repo, err := git.NewRepository(path)
+	if err != nil {
+		return repoStatus{}, fmt.Errorf("failed to initialize repository: %w", err)
+	}
+
+	if statusFetch {
+		if err := repo.Fetch(); err != nil {
+			return repoStatus{}, err
+		}
+	}
+
+	report, err := repo.SyncReport()
+	if err != nil {
+		return repoStatus{}, err
+	}
+
+	status := repoStatus{SyncReport: report}
+	for _, mon := range monitors {
+		if mon.Path == repo.Path() {
+			status.Monitors = append(status.Monitors, mon)
+		}
+	}
+	return status, nil
+}
+
+// monitoredRepoPaths returns every repository with a running monitor or an
+// entry in the config's repositories list, without duplicates
+func monitoredRepoPaths(monitors []monitorState) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	add := func(path string) {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	for _, mon := range monitors {
+		add(mon.Path)
+	}
+	if cfg, err := config.Load(); err == nil {
+		specs, _ := daemon.SpecsFromConfig(cfg)
+		for _, spec := range specs {
+			add(spec.Path)
+		}
+	}
+	return paths
+}
+
+// collectRunningMonitors asks the daemon and every standalone monitor for
+// their current state
+func collectRunningMonitors() []monitorState {
+	var monitors []monitorState
+
+	if resp, err := daemon.Send(daemon.DefaultSocketPath(), daemon.Request{Command: daemon.CommandStatus}); err == nil {
+		for _, info := range resp.Monitors {
+			monitors = append(monitors, monitorState{PID: resp.PID, MonitorInfo: info})
+		}
+	}
+
+	for _, mon := range findAllMonitors() {
+		socketFile := mon.SocketFile()
+		if socketFile == "" {
+			monitors = append(monitors, monitorState{PID: mon.PID, MonitorInfo: daemon.MonitorInfo{Path: mon.RepoPath}})
+			continue
+		}
+		resp, err := daemon.Send(socketFile, daemon.Request{Command: daemon.CommandStatus})
+		if err != nil {
+			monitors = append(monitors, monitorState{PID: mon.PID, MonitorInfo: daemon.MonitorInfo{Path: mon.RepoPath, LastError: err.Error()}})
+			continue
+		}
+		for _, info := range resp.Monitors {
+			monitors = append(monitors, monitorState{PID: resp.PID, MonitorInfo: info})
+		}
+	}
+
+	return monitors
+}
+
+func printRepoStatus(status repoStatus) {
+	report := status.SyncReport
+
+	fmt.Printf("%s\n", report.Path)
+	if report.Branch != "" {
+		if report.Upstream != "" {
+			fmt.Printf("  Branch:       %s (tracking %s)\n", report.Branch, report.Upstream)
+		} else {
+			fmt.Printf("  Branch:       %s (no upstream)\n", report.Branch)
+		}
+
+		if report.Upstream == "" {
+			fmt.Println("  Sync:         nothing to compare against")
+		} else if report.InSync() {
+			fmt.Println("  Sync:         up to date")
+		} else {
+			fmt.Printf("  Sync:         %d ahead, %d behind\n", report.Ahead, report.Behind)
+		}
+
+		if report.UncommittedChanges {
+			fmt.Println("  Working tree: uncommitted changes")
+		} else {
+			fmt.Println("  Working tree: clean")
+		}
+	}
+	if report.InProgress != "" {
+		fmt.Printf("  In progress:  %s (run 'harbinger resolve' to finish it)\n", report.InProgress)
+	}
+	if len(report.PredictedConflicts) > 0 {
+		fmt.Printf("  Predicted conflicts (%d):\n", len(report.PredictedConflicts))
+		for _, file := range report.PredictedConflicts {
+			fmt.Printf("    - %s\n", file)
+		}
+	}
+	for _, mon := range status.Monitors {
+		fmt.Printf("  Monitor:      %s\n", describeMonitor(mon))
+	}
+	for _, msg := range report.Errors {
+		fmt.Printf("  Error:        %s\n", msg)
+	}
+	fmt.Println()
+}
+
+func describeMonitor(mon monitorState) string {
+	parts := []string{fmt.Sprintf("PID %d", mon.PID)}
+	if mon.PollInterval != "" {
+		state := "running"
+		if mon.Paused {
+			state = "paused"
+		}
+		parts = append(parts, fmt.Sprintf("%s every %s", state, mon.PollInterval))
+	}
+	if mon.Ignored {
+		parts = append(parts, "skipping "+mon.Branch+" (ignore_branches)")
+	} else if mon.RemoteRef != "" {
+		parts = append(parts, "against "+mon.RemoteRef)
+	}
+	if !mon.LastCheck.IsZero() {
+		parts = append(parts, "last check "+mon.LastCheck.Format(time.RFC3339))
+	}
+	if mon.LastError != "" {
+		parts = append(parts, "error: "+mon.LastError)
+	}
+	return strings.Join(parts, ", ")
+}
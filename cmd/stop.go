@@ -7,7 +7,9 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/javanhut/harbinger/internal/daemon"
 	"github.com/spf13/cobra"
 )
 
@@ -16,16 +18,18 @@ var (
 )
 
 var stopCmd = &cobra.Command{
-	Use:   "stop [PID]",
+	Use:   "stop [PATH|PID]",
 	Short: "Stop harbinger background monitors",
-	Long:  `Stops harbinger monitor processes running in the background. If no PID is specified, lists all running monitors.`,
-	RunE:  runStop,
-	Args:  cobra.MaximumNArgs(1),
+	Long: `Stops harbinger monitors running in the background. A repository path removes that
+repository from the daemon, a PID stops the daemon or a standalone monitor. If nothing is
+specified, lists all running monitors.`,
+	RunE: runStop,
+	Args: cobra.MaximumNArgs(1),
 }
 
 func init() {
 	rootCmd.AddCommand(stopCmd)
-	stopCmd.Flags().BoolVarP(&stopAll, "all", "a", false, "Stop all running monitors")
+	stopCmd.Flags().BoolVarP(&stopAll, "all", "a", false, "Stop the daemon and all running monitors")
 }
 
 func runStop(cmd *cobra.Command, args []string) error {
@@ -39,57 +43,83 @@ func runStop(cmd *cobra.Command, args []string) error {
 	}
 
 	// Stop specific monitor by PID
-	pid, err := strconv.Atoi(args[0])
-	if err != nil {
-		return fmt.Errorf("invalid PID: %w", err)
+	if pid, err := strconv.Atoi(args[0]); err == nil {
+		return stopMonitorByPID(pid)
 	}
 
-	return stopMonitorByPID(pid)
+	return stopRepository(args[0])
 }
 
 func listRunningMonitors() error {
+	daemonResp, daemonErr := daemon.Send(daemon.DefaultSocketPath(), daemon.Request{Command: daemon.CommandList})
 	monitors := findAllMonitors()
 
-	if len(monitors) == 0 {
+	if daemonErr != nil && len(monitors) == 0 {
 		fmt.Println("No harbinger monitors are currently running")
 		return nil
 	}
 
-	fmt.Println("Running harbinger monitors:")
-	fmt.Println("PID\tRepository")
-	fmt.Println("---\t----------")
+	if daemonErr == nil {
+		printDaemonMonitors(daemonResp)
+		fmt.Println()
+	}
+
+	if len(monitors) > 0 {
+		fmt.Println("Standalone harbinger monitors:")
+		fmt.Println("PID\tRepository")
+		fmt.Println("---\t----------")
 
-	for _, mon := range monitors {
-		fmt.Printf("%d\t%s\n", mon.PID, mon.RepoPath)
+		for _, mon := range monitors {
+			fmt.Printf("%d\t%s\n", mon.PID, mon.RepoPath)
+		}
+		fmt.Println()
 	}
 
-	fmt.Println("\nUse 'harbinger stop <PID>' to stop a specific monitor")
+	fmt.Println("Use 'harbinger stop <PATH>' to stop monitoring a repository")
+	fmt.Println("Use 'harbinger stop <PID>' to stop the daemon or a standalone monitor")
 	fmt.Println("Use 'harbinger stop --all' to stop all monitors")
 
 	return nil
 }
 
 func stopAllMonitors() error {
-	monitors := findAllMonitors()
+	stoppedCount := 0
+	daemonStopped := false
 
-	if len(monitors) == 0 {
-		fmt.Println("No harbinger monitors are currently running")
-		return nil
+	if resp, err := daemon.Send(daemon.DefaultSocketPath(), daemon.Request{Command: daemon.CommandList}); err == nil {
+		if err := stopDaemon(); err != nil {
+			return err
+		}
+		fmt.Printf("Stopped harbinger daemon (PID: %d)\n", resp.PID)
+		stoppedCount += len(resp.Monitors)
+		daemonStopped = true
 	}
 
-	stoppedCount := 0
-	for _, mon := range monitors {
+	for _, mon := range findAllMonitors() {
 		if err := stopMonitor(mon); err == nil {
 			fmt.Printf("Stopped monitor %d for %s\n", mon.PID, mon.RepoPath)
 			stoppedCount++
 		}
 	}
 
+	if stoppedCount == 0 && !daemonStopped {
+		fmt.Println("No harbinger monitors are currently running")
+		return nil
+	}
+
 	fmt.Printf("\nStopped %d monitor(s)\n", stoppedCount)
 	return nil
 }
 
 func stopMonitorByPID(pid int) error {
+	if resp, err := daemon.Send(daemon.DefaultSocketPath(), daemon.Request{Command: daemon.CommandPing}); err == nil && resp.PID == pid {
+		if err := stopDaemon(); err != nil {
+			return err
+		}
+		fmt.Printf("Stopped harbinger daemon (PID: %d)\n", pid)
+		return nil
+	}
+
 	monitors := findAllMonitors()
 
 	for _, mon := range monitors {
@@ -105,7 +135,70 @@ func stopMonitorByPID(pid int) error {
 	return fmt.Errorf("no harbinger monitor found with PID %d", pid)
 }
 
+// stopRepository removes a repository from the daemon and stops any
+// standalone monitors for it
+func stopRepository(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for repository: %w", err)
+	}
+
+	resp, daemonErr := daemon.Send(daemon.DefaultSocketPath(), daemon.Request{
+		Command: daemon.CommandRemove,
+		Path:    absPath,
+	})
+	stopped := 0
+	if daemonErr == nil {
+		stopped = resp.Removed
+	}
+
+	for _, mon := range findAllMonitors() {
+		if mon.RepoPath != absPath {
+			continue
+		}
+		if err := stopMonitor(mon); err != nil {
+			return err
+		}
+		stopped++
+	}
+
+	if stopped == 0 {
+		if daemonErr != nil {
+			return fmt.Errorf("failed to stop monitoring %s: %w", absPath, daemonErr)
+		}
+		return fmt.Errorf("no harbinger monitor found for %s", absPath)
+	}
+
+	fmt.Printf("Stopped %d monitor(s) for %s\n", stopped, absPath)
+	return nil
+}
+
+// stopDaemon asks the daemon to shut down and waits for its socket to go away
+func stopDaemon() error {
+	socketPath := daemon.DefaultSocketPath()
+	if _, err := daemon.Send(socketPath, daemon.Request{Command: daemon.CommandShutdown}); err != nil {
+		return fmt.Errorf("failed to stop daemon: %w", err)
+	}
+
+	for i := 0; i < 50; i++ {
+		if !daemon.IsRunning(socketPath) {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("daemon did not shut down in time")
+}
+
 func stopMonitor(mon monitorInfo) error {
+	// Ask the monitor to shut down gracefully over its control socket first
+	if socketFile := mon.SocketFile(); socketFile != "" {
+		if _, err := daemon.Send(socketFile, daemon.Request{Command: daemon.CommandShutdown}); err == nil && waitForProcessExit(mon.PID) {
+			os.Remove(mon.PIDFile)
+			cleanupLogFile(mon.PID)
+			return nil
+		}
+	}
+
 	// Find process
 	process, err := os.FindProcess(mon.PID)
 	if err != nil {
@@ -130,12 +223,33 @@ func stopMonitor(mon monitorInfo) error {
 	return nil
 }
 
+// monitorInfo describes a standalone monitor tracked by a PID file
 type monitorInfo struct {
 	PID      int
 	RepoPath string
 	PIDFile  string
 }
 
+// SocketFile returns the control socket of a standalone monitor, or an empty
+// string for the legacy PID file that has no socket next to it
+func (mon monitorInfo) SocketFile() string {
+	if !strings.HasPrefix(filepath.Base(mon.PIDFile), ".harbinger-") {
+		return ""
+	}
+	return strings.TrimSuffix(mon.PIDFile, ".pid") + ".sock"
+}
+
+// waitForProcessExit waits up to two seconds for a process to exit
+func waitForProcessExit(pid int) bool {
+	for i := 0; i < 20; i++ {
+		if !isProcessRunning(pid) {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
+
 func findAllMonitors() []monitorInfo {
 	var monitors []monitorInfo
 
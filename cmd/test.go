@@ -11,6 +11,7 @@ import (
 	"github.com/javanhut/harbinger/internal/git"
 	"github.com/javanhut/harbinger/internal/notify"
 	"github.com/javanhut/harbinger/internal/ui"
+	"github.com/javanhut/harbinger/pkg/config"
 	"github.com/spf13/cobra"
 )
 
@@ -115,6 +116,26 @@ func testNotificationSystem() error {
 
 	notifier := notify.New()
 
+	// Configured webhooks and notifiers get the test notifications too
+	if cfg, err := config.Load(); err == nil {
+		options, err := notify.OptionsFromConfig(cfg)
+		if err != nil {
+			color.Red("Skipping configured notifiers: %v", err)
+		} else {
+			// The desktop and the log are always tested
+			var sinks []notify.Sink
+			for _, sink := range options.Sinks {
+				if sink.Name() != "desktop" && sink.Name() != "log" {
+					sinks = append(sinks, sink)
+				}
+			}
+			if len(sinks) > 0 {
+				notifier.Configure(notify.Options{Desktop: true, Sinks: sinks})
+				fmt.Printf("Also sending to %d configured notifier(s)\n", len(sinks))
+			}
+		}
+	}
+
 	// Test each type of notification
 	notifications := []struct {
 		name     string
@@ -140,6 +161,7 @@ func testNotificationSystem() error {
 		fmt.Printf("\rSending test notification %d/%d: %s ✓           \n", i+1, len(notifications), notification.name)
 	}
 
+	notifier.Wait()
 	color.Green("✓ All notification types sent")
 	fmt.Println()
 
@@ -0,0 +1,135 @@
+package conflict
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Choice is how a single conflicted hunk is resolved
+type Choice string
+
+const (
+	ChoiceOurs           Choice = "ours"
+	ChoiceTheirs         Choice = "theirs"
+	ChoiceOursThenTheirs Choice = "ours-then-theirs"
+	ChoiceTheirsThenOurs Choice = "theirs-then-ours"
+	ChoiceBase           Choice = "base"
+)
+
+// Hunk is one conflicted region of a file
+type Hunk struct {
+	Ours    string
+	Theirs  string
+	Base    string
+	HasBase bool
+}
+
+// Resolve returns the text that replaces the hunk for the given choice
+func (h Hunk) Resolve(choice Choice) (string, error) {
+	switch choice {
+	case ChoiceOurs:
+		return h.Ours, nil
+	case ChoiceTheirs:
+		return h.Theirs, nil
+	case ChoiceOursThenTheirs:
+		return joinSides(h.Ours, h.Theirs), nil
+	case ChoiceTheirsThenOurs:
+		return joinSides(h.Theirs, h.Ours), nil
+	case ChoiceBase:
+		if !h.HasBase {
+			return "", fmt.Errorf("hunk has no base version, use diff3 or zdiff3 conflict style")
+		}
+		return h.Base, nil
+	default:
+		return "", fmt.Errorf("unknown choice %q", choice)
+	}
+}
+
+// joinSides concatenates two sides of a hunk, making sure the first one ends
+// with a newline so their lines are not glued together
+func joinSides(first, second string) string {
+	if first != "" && second != "" && !strings.HasSuffix(first, "\n") {
+		first += "\n"
+	}
+	return first + second
+}
+
+// hunksOf returns the conflicted hunks in parsed sections, in file order
+func hunksOf(sections []ConflictSection) ([]Hunk, error) {
+	var hunks []Hunk
+	err := walkHunks(sections, func(ConflictSection) {}, func(h Hunk) error {
+		hunks = append(hunks, h)
+		return nil
+	})
+	return hunks, err
+}
+
+// applyChoices rebuilds a file from its sections, replacing the i-th hunk
+// with the text chosen by choices[i]
+func applyChoices(sections []ConflictSection, choices []Choice) (string, error) {
+	var result strings.Builder
+	index := 0
+	err := walkHunks(sections, func(section ConflictSection) {
+		result.WriteString(section.Content)
+	}, func(h Hunk) error {
+		if index >= len(choices) {
+			return fmt.Errorf("no choice for hunk %d", index+1)
+		}
+		text, err := h.Resolve(choices[index])
+		if err != nil {
+			return fmt.Errorf("hunk %d: %w", index+1, err)
+		}
+		result.WriteString(text)
+		index++
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if index != len(choices) {
+		return "", fmt.Errorf("got %d choices for %d hunks", len(choices), index)
+	}
+	return result.String(), nil
+}
+
+// walkHunks calls normal for each section outside a conflict and hunk for
+// each complete conflict. Sections that do not form ours[, base], theirs
+// sequences mean the markers are malformed.
+func walkHunks(sections []ConflictSection, normal func(ConflictSection), hunk func(Hunk) error) error {
+	var current *Hunk
+	for _, section := range sections {
+		switch section.Type {
+		case "ours":
+			if current != nil {
+				return fmt.Errorf("malformed conflict markers: unterminated conflict")
+			}
+			current = &Hunk{Ours: section.Content}
+		case "base":
+			if current == nil || current.HasBase {
+				return fmt.Errorf("malformed conflict markers: unexpected base section")
+			}
+			current.Base = section.Content
+			current.HasBase = true
+		case "malformed":
+			return fmt.Errorf("malformed conflict markers: unterminated conflict")
+		case "theirs":
+			if current == nil {
+				return fmt.Errorf("malformed conflict markers: unexpected separator")
+			}
+			current.Theirs = section.Content
+			if err := hunk(*current); err != nil {
+				return err
+			}
+			current = nil
+		default:
+			if current != nil {
+				return fmt.Errorf("malformed conflict markers: unterminated conflict")
+			}
+			normal(section)
+		}
+	}
+	if current != nil {
+		return fmt.Errorf("malformed conflict markers: unterminated conflict")
+	}
+	return nil
+}
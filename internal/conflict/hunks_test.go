@@ -0,0 +1,97 @@
+package conflict
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const twoHunks = `header
+<<<<<<< HEAD
+ours one
+=======
+theirs one
+>>>>>>> branch
+middle
+
+<<<<<<< HEAD
+ours two
+=======
+theirs two
+>>>>>>> branch
+footer
+`
+
+func TestHunksOf(t *testing.T) {
+	hunks, err := hunksOf(parseConflict(twoHunks))
+	require.NoError(t, err)
+	require.Len(t, hunks, 2)
+
+	assert.Equal(t, "ours one\n", hunks[0].Ours)
+	assert.Equal(t, "theirs one\n", hunks[0].Theirs)
+	assert.False(t, hunks[0].HasBase)
+	assert.Equal(t, "ours two\n", hunks[1].Ours)
+	assert.Equal(t, "theirs two\n", hunks[1].Theirs)
+}
+
+func TestApplyChoices(t *testing.T) {
+	sections := parseConflict(twoHunks)
+
+	tests := []struct {
+		name     string
+		choices  []Choice
+		expected string
+	}{
+		{
+			name:     "ours then theirs",
+			choices:  []Choice{ChoiceOurs, ChoiceTheirs},
+			expected: "header\nours one\nmiddle\n\ntheirs two\nfooter\n",
+		},
+		{
+			name:     "both orders",
+			choices:  []Choice{ChoiceOursThenTheirs, ChoiceTheirsThenOurs},
+			expected: "header\nours one\ntheirs one\nmiddle\n\ntheirs two\nours two\nfooter\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := applyChoices(sections, tt.choices)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, content)
+		})
+	}
+}
+
+func TestApplyChoices_Errors(t *testing.T) {
+	sections := parseConflict(twoHunks)
+
+	_, err := applyChoices(sections, []Choice{ChoiceOurs})
+	assert.Error(t, err)
+
+	_, err = applyChoices(sections, []Choice{ChoiceOurs, ChoiceTheirs, ChoiceOurs})
+	assert.Error(t, err)
+
+	_, err = applyChoices(sections, []Choice{ChoiceOurs, ChoiceBase})
+	assert.ErrorContains(t, err, "no base version")
+
+	_, err = applyChoices(sections, []Choice{ChoiceOurs, "mine"})
+	assert.ErrorContains(t, err, "unknown choice")
+
+	_, err = hunksOf(parseConflict("<<<<<<< HEAD\nours\n"))
+	assert.ErrorContains(t, err, "unterminated conflict")
+}
+
+func TestHunk_ResolveJoinsSidesOnNewLines(t *testing.T) {
+	hunk := Hunk{Ours: "no newline", Theirs: "theirs\n"}
+
+	text, err := hunk.Resolve(ChoiceOursThenTheirs)
+	require.NoError(t, err)
+	assert.Equal(t, "no newline\ntheirs\n", text)
+
+	hunk = Hunk{Ours: "ours\n", Theirs: "theirs\n", Base: "base\n", HasBase: true}
+	text, err = hunk.Resolve(ChoiceBase)
+	require.NoError(t, err)
+	assert.Equal(t, "base\n", text)
+}
@@ -0,0 +1,246 @@
+package conflict
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/javanhut/harbinger/internal/git"
+	"github.com/javanhut/harbinger/pkg/config"
+)
+
+// Regenerator resolves a generated file, such as a lockfile, by taking one
+// side of the conflict and re-running the tool that produces it
+type Regenerator struct {
+	Name string
+	// Files are the patterns of the generated files, matched like rule patterns
+	Files []string
+	// Side is the version kept before regenerating, ours or theirs
+	Side Strategy
+	// Command runs in the directory of the conflicted file
+	Command []string
+	// Related are files next to the generated one that the command may also
+	// update, such as go.mod. They are staged unless they are still conflicted.
+	Related []string
+}
+
+// Matches reports whether the regenerator handles a repository-relative path
+func (g Regenerator) Matches(file string) bool {
+	for _, pattern := range g.Files {
+		if matchPattern(pattern, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinRegenerators are the lockfiles harbinger knows how to rebuild.
+// None of them run unless enabled in the config.
+var builtinRegenerators = []Regenerator{
+	{
+		Name:    "go",
+		Files:   []string{"go.sum"},
+		Side:    StrategyTheirs,
+		Command: []string{"go", "mod", "tidy"},
+		Related: []string{"go.mod"},
+	},
+	{
+		Name:    "npm",
+		Files:   []string{"package-lock.json"},
+		Side:    StrategyTheirs,
+		Command: []string{"npm", "install", "--package-lock-only", "--ignore-scripts"},
+	},
+	{
+		Name:    "yarn",
+		Files:   []string{"yarn.lock"},
+		Side:    StrategyTheirs,
+		Command: []string{"yarn", "install", "--ignore-scripts"},
+	},
+	{
+		Name:    "cargo",
+		Files:   []string{"Cargo.lock"},
+		Side:    StrategyTheirs,
+		Command: []string{"cargo", "update", "--workspace"},
+	},
+}
+
+// Registry holds the regenerators a Resolver may use, in lookup order
+type Registry struct {
+	regenerators []Regenerator
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a regenerator, replacing any registered under the same name
+func (r *Registry) Register(g Regenerator) error {
+	if g.Name == "" {
+		return fmt.Errorf("regenerator has no name")
+	}
+	if len(g.Files) == 0 {
+		return fmt.Errorf("regenerator %s: no files", g.Name)
+	}
+	for _, pattern := range g.Files {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("regenerator %s: invalid pattern %q: %w", g.Name, pattern, err)
+		}
+	}
+	if len(g.Command) == 0 {
+		return fmt.Errorf("regenerator %s: no command", g.Name)
+	}
+	if g.Side != StrategyOurs && g.Side != StrategyTheirs {
+		return fmt.Errorf("regenerator %s: side must be ours or theirs, not %q", g.Name, g.Side)
+	}
+
+	for i, existing := range r.regenerators {
+		if existing.Name == g.Name {
+			r.regenerators[i] = g
+			return nil
+		}
+	}
+	r.regenerators = append(r.regenerators, g)
+	return nil
+}
+
+// Lookup returns the first regenerator handling file
+func (r *Registry) Lookup(file string) (Regenerator, bool) {
+	if r == nil {
+		return Regenerator{}, false
+	}
+	for _, g := range r.regenerators {
+		if g.Matches(file) {
+			return g, true
+		}
+	}
+	return Regenerator{}, false
+}
+
+// Len returns the number of registered regenerators
+func (r *Registry) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.regenerators)
+}
+
+// RegistryFromConfig registers the regenerators enabled in the config. An
+// entry named after a built-in regenerator only needs to override the
+// fields it changes.
+func RegistryFromConfig(entries []config.RegeneratorConfig) (*Registry, error) {
+	registry := NewRegistry()
+	for _, entry := range entries {
+		var g Regenerator
+		for _, builtin := range builtinRegenerators {
+			if builtin.Name == entry.Name {
+				g = builtin
+				break
+			}
+		}
+		g.Name = entry.Name
+
+		if len(entry.Files) > 0 {
+			g.Files = entry.Files
+		}
+		if entry.Command != "" {
+			g.Command = strings.Fields(entry.Command)
+		}
+		if entry.Side != "" {
+			side, err := ParseStrategy(entry.Side)
+			if err != nil {
+				return nil, fmt.Errorf("regenerator %s: %w", entry.Name, err)
+			}
+			g.Side = side
+		}
+		if g.Side == "" {
+			g.Side = StrategyTheirs
+		}
+
+		if err := registry.Register(g); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// ApplyRegenerators resolves every conflict handled by a regenerator and
+// returns the ones left. Conflicts are restored if their command fails.
+func (r *Resolver) ApplyRegenerators(conflicts []git.Conflict) []git.Conflict {
+	var remaining []git.Conflict
+	for _, conflict := range conflicts {
+		g, ok := r.regenerators.Lookup(conflict.File)
+		if !ok || conflict.Predicted {
+			remaining = append(remaining, conflict)
+			continue
+		}
+
+		if err := r.regenerate(conflict, g); err != nil {
+			color.Red("❌ Could not regenerate %s with %s: %v", conflict.File, g.Name, err)
+			remaining = append(remaining, conflict)
+		}
+	}
+	return remaining
+}
+
+// regenerate takes the regenerator's side of a conflict, runs its command
+// and stages the result
+func (r *Resolver) regenerate(conflict git.Conflict, g Regenerator) error {
+	if err := r.applyStrategy(conflict, g.Side); err != nil {
+		return err
+	}
+
+	dir := filepath.Join(r.repo.Path(), filepath.Dir(conflict.File))
+	color.Yellow("Regenerating %s with %s...\n", conflict.File, strings.Join(g.Command, " "))
+	cmd := exec.Command(g.Command[0], g.Command[1:]...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if restoreErr := r.restoreConflict(conflict.File); restoreErr != nil {
+			return fmt.Errorf("%s failed: %w (and restoring the conflict failed: %v)", g.Command[0], err, restoreErr)
+		}
+		return fmt.Errorf("%s failed: %w: %s", g.Command[0], err, strings.TrimSpace(string(output)))
+	}
+
+	paths := []string{conflict.File}
+	for _, related := range g.Related {
+		file := filepath.Join(filepath.Dir(conflict.File), related)
+		if _, err := os.Stat(filepath.Join(r.repo.Path(), file)); err != nil {
+			continue
+		}
+		if r.isUnmerged(file) {
+			continue
+		}
+		paths = append(paths, file)
+	}
+
+	args := append([]string{"add", "-A", "--"}, paths...)
+	cmd = exec.Command("git", args...)
+	cmd.Dir = r.repo.Path()
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to stage regenerated files: %w", err)
+	}
+
+	color.Green("✓ Regenerated %s\n", conflict.File)
+	return nil
+}
+
+// restoreConflict puts a file back into its conflicted state, markers and all
+func (r *Resolver) restoreConflict(file string) error {
+	cmd := exec.Command("git", "checkout", "--merge", "--", file)
+	cmd.Dir = r.repo.Path()
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// isUnmerged reports whether file still has unresolved conflicts in the index
+func (r *Resolver) isUnmerged(file string) bool {
+	cmd := exec.Command("git", "ls-files", "--unmerged", "--", file)
+	cmd.Dir = r.repo.Path()
+	output, err := cmd.Output()
+	return err != nil || len(output) > 0
+}
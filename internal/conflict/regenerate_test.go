@@ -0,0 +1,147 @@
+package conflict
+
+import (
+	"testing"
+
+	"github.com/javanhut/harbinger/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryFromConfig(t *testing.T) {
+	registry, err := RegistryFromConfig([]config.RegeneratorConfig{
+		{Name: "go"},
+		{Name: "npm", Command: "npm install --package-lock-only"},
+		{Name: "proto", Files: []string{"*.pb.go"}, Command: "make proto", Side: "ours"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, registry.Len())
+
+	g, ok := registry.Lookup("tools/go.sum")
+	require.True(t, ok)
+	assert.Equal(t, []string{"go", "mod", "tidy"}, g.Command)
+	assert.Equal(t, StrategyTheirs, g.Side)
+
+	g, ok = registry.Lookup("package-lock.json")
+	require.True(t, ok)
+	assert.Equal(t, []string{"npm", "install", "--package-lock-only"}, g.Command)
+
+	g, ok = registry.Lookup("api/v1/api.pb.go")
+	require.True(t, ok)
+	assert.Equal(t, "proto", g.Name)
+	assert.Equal(t, StrategyOurs, g.Side)
+
+	// Built-ins only run when enabled
+	_, ok = registry.Lookup("yarn.lock")
+	assert.False(t, ok)
+}
+
+func TestRegistryFromConfig_Invalid(t *testing.T) {
+	tests := []config.RegeneratorConfig{
+		{Name: "custom", Command: "make"},
+		{Name: "custom", Files: []string{"gen.txt"}},
+		{Name: "go", Side: "union"},
+		{Name: "custom", Files: []string{"["}, Command: "make"},
+		{Files: []string{"gen.txt"}, Command: "make"},
+	}
+
+	for _, entry := range tests {
+		_, err := RegistryFromConfig([]config.RegeneratorConfig{entry})
+		assert.Error(t, err, "%+v", entry)
+	}
+}
+
+func TestRegistryRegister_ReplacesByName(t *testing.T) {
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(Regenerator{Name: "gen", Files: []string{"a.txt"}, Side: StrategyOurs, Command: []string{"true"}}))
+	require.NoError(t, registry.Register(Regenerator{Name: "gen", Files: []string{"b.txt"}, Side: StrategyOurs, Command: []string{"true"}}))
+
+	assert.Equal(t, 1, registry.Len())
+	_, ok := registry.Lookup("a.txt")
+	assert.False(t, ok)
+	_, ok = registry.Lookup("b.txt")
+	assert.True(t, ok)
+}
+
+func TestApplyRegenerators(t *testing.T) {
+	repo, conflicts := newConflictedRepo(t,
+		map[string]string{"deps.lock": "a 1\n", "deps.txt": "a\n"},
+		map[string]string{"deps.lock": "a 1\nb 1\n", "deps.txt": "a\nb\n"},
+		map[string]string{"deps.lock": "a 1\nc 1\n", "deps.txt": "a\nc\n"},
+	)
+	require.Len(t, conflicts, 2)
+
+	// The "tool" rebuilds the lockfile from the manifest
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(Regenerator{
+		Name:    "deps",
+		Files:   []string{"deps.lock"},
+		Side:    StrategyTheirs,
+		Command: []string{"sh", "-c", "sed 's/$/ 1/' deps.txt > deps.lock"},
+	}))
+	resolver := NewResolverWithOptions(repo, Options{
+		Rules:        []Rule{{Pattern: "deps.txt", Strategy: StrategyUnion}},
+		Regenerators: registry,
+	})
+
+	assert.Empty(t, resolver.AutoResolve(conflicts))
+	assert.Equal(t, "a\nb\nc\n", readFile(t, repo, "deps.txt"))
+	assert.Equal(t, "a 1\nb 1\nc 1\n", readFile(t, repo, "deps.lock"))
+
+	files, err := repo.GetConflictedFiles()
+	require.NoError(t, err)
+	assert.Empty(t, files)
+	assert.Equal(t, "", runGit(t, repo.Path(), "diff", "--name-only"), "regenerated file should be staged")
+}
+
+func TestAutoResolve_FallbackAfterRegenerators(t *testing.T) {
+	repo, conflicts := newConflictedRepo(t,
+		map[string]string{"deps.lock": "a 1\n", "deps.txt": "a\n", "CHANGELOG": "a\n"},
+		map[string]string{"deps.lock": "a 1\nb 1\n", "deps.txt": "a\nb\n", "CHANGELOG": "a\nb\n"},
+		map[string]string{"deps.lock": "a 1\nc 1\n", "deps.txt": "a\nc\n", "CHANGELOG": "a\nc\n"},
+	)
+	require.Len(t, conflicts, 3)
+
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(Regenerator{
+		Name:    "deps",
+		Files:   []string{"deps.lock"},
+		Side:    StrategyTheirs,
+		Command: []string{"sh", "-c", "sed 's/$/ 1/' deps.txt > deps.lock"},
+	}))
+	resolver := NewResolverWithOptions(repo, Options{
+		Rules:        []Rule{{Pattern: "deps.txt", Strategy: StrategyUnion}},
+		Regenerators: registry,
+		Fallback:     StrategyTheirs,
+	})
+
+	assert.Empty(t, resolver.AutoResolve(conflicts))
+	assert.Equal(t, "a\nb\nc\n", readFile(t, repo, "deps.txt"), "the rule wins over the fallback")
+	assert.Equal(t, "a 1\nb 1\nc 1\n", readFile(t, repo, "deps.lock"), "the lockfile is regenerated, not taken from theirs")
+	assert.Equal(t, "a\nc\n", readFile(t, repo, "CHANGELOG"))
+}
+
+func TestApplyRegenerators_CommandFailureRestoresConflict(t *testing.T) {
+	repo, conflicts := newConflictedRepo(t,
+		map[string]string{"go.sum": "a v1\n"},
+		map[string]string{"go.sum": "a v2\n"},
+		map[string]string{"go.sum": "a v3\n"},
+	)
+
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(Regenerator{
+		Name:    "broken",
+		Files:   []string{"go.sum"},
+		Side:    StrategyTheirs,
+		Command: []string{"sh", "-c", "exit 1"},
+	}))
+	resolver := NewResolverWithOptions(repo, Options{Regenerators: registry})
+
+	remaining := resolver.ApplyRegenerators(conflicts)
+	require.Len(t, remaining, 1)
+
+	files, err := repo.GetConflictedFiles()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go.sum"}, files)
+	assert.Contains(t, readFile(t, repo, "go.sum"), "<<<<<<<")
+}
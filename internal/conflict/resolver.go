@@ -11,71 +11,124 @@ import (
 	"github.com/fatih/color"
 	"github.com/javanhut/harbinger/internal/git"
 	"github.com/javanhut/harbinger/internal/ui"
+	"github.com/javanhut/harbinger/pkg/config"
 )
 
 type Resolver struct {
-	repo *git.Repository
+	repo         *git.Repository
+	rules        []Rule
+	regenerators *Registry
+	fallback     Strategy
+	editor       string
+}
+
+// Options configures a Resolver
+type Options struct {
+	// Rules are applied to matching files before the interactive UI, first match wins
+	Rules []Rule
+	// Regenerators rebuild generated files not matched by a rule
+	Regenerators *Registry
+	// Fallback, if set, resolves the files that neither a rule nor a
+	// regenerator resolved
+	Fallback Strategy
+	// Editor is the command used to edit conflicted files, falling back to
+	// $EDITOR and then to the first of code, vim, nano or vi found
+	Editor string
+}
+
+// OptionsFromConfig builds resolver options from the resolve_rules and
+// regenerators config sections
+func OptionsFromConfig(cfg *config.Config) (Options, error) {
+	rules, err := RulesFromConfig(cfg.ResolveRules)
+	if err != nil {
+		return Options{}, fmt.Errorf("invalid resolve_rules: %w", err)
+	}
+	registry, err := RegistryFromConfig(cfg.Regenerators)
+	if err != nil {
+		return Options{}, fmt.Errorf("invalid regenerators: %w", err)
+	}
+	return Options{Rules: rules, Regenerators: registry, Editor: cfg.Editor}, nil
 }
 
 func NewResolver(repo *git.Repository) *Resolver {
-	return &Resolver{repo: repo}
+	return NewResolverWithOptions(repo, Options{})
+}
+
+// NewResolverWithOptions creates a resolver that applies the given rules
+func NewResolverWithOptions(repo *git.Repository, options Options) *Resolver {
+	return &Resolver{repo: repo, rules: options.Rules, regenerators: options.Regenerators, fallback: options.Fallback, editor: options.Editor}
+}
+
+// AutoResolve applies the rules, then the regenerators, then the fallback
+// strategy, and returns the conflicts none of them could resolve
+func (r *Resolver) AutoResolve(conflicts []git.Conflict) []git.Conflict {
+	if len(r.rules) > 0 {
+		conflicts = r.ApplyRules(conflicts)
+	}
+	if r.regenerators.Len() > 0 {
+		conflicts = r.ApplyRegenerators(conflicts)
+	}
+	if r.fallback != "" {
+		conflicts = r.applyRules(conflicts, []Rule{{Pattern: "*", Strategy: r.fallback}})
+	}
+	return conflicts
 }
 
 func (r *Resolver) ResolveConflicts(conflicts []git.Conflict) error {
 	ui := ui.NewTerminalUI()
 
+	if len(r.rules) > 0 || r.regenerators.Len() > 0 || r.fallback != "" {
+		conflicts = r.AutoResolve(conflicts)
+		if len(conflicts) == 0 {
+			color.Green("\nAll conflicts resolved automatically!")
+			return nil
+		}
+	}
+
+	predicted := 0
 	for i, conflict := range conflicts {
+		if conflict.Predicted {
+			predicted++
+			if !r.previewConflict(ui, conflict, i+1, len(conflicts)) {
+				return nil
+			}
+			continue
+		}
 		if err := r.resolveConflict(ui, conflict, i+1, len(conflicts)); err != nil {
 			return err
 		}
 	}
 
+	if predicted == len(conflicts) {
+		color.Yellow("\nThese conflicts will occur when you merge. Run 'harbinger resolve' once they do.")
+		return nil
+	}
 	color.Green("\nAll conflicts resolved!")
 	return nil
 }
 
-func (r *Resolver) resolveConflict(ui *ui.TerminalUI, conflict git.Conflict, current, total int) error {
-	ui.Clear()
+// previewConflict shows a predicted conflict, which only exists in the
+// would-be merge result, and reports whether to continue to the next one
+func (r *Resolver) previewConflict(ui *ui.TerminalUI, conflict git.Conflict, current, total int) bool {
+	displayConflict(ui, conflict, r.sections(conflict), fmt.Sprintf("Predicted Conflict (%d/%d)", current, total))
 
-	// Display header with box
-	header := fmt.Sprintf("Conflict Resolution (%d/%d)\nFile: %s", current, total, conflict.File)
-	ui.DrawBox(header)
+	fmt.Println(strings.Repeat("═", 50))
+	color.Cyan("This conflict will occur when you merge. Nothing has been changed yet.")
 	fmt.Println()
+	color.White("Press Enter to continue, or q to stop: ")
 
-	// Parse and display conflict with better formatting
-	sections := parseConflict(conflict.Content)
+	reader := bufio.NewReader(os.Stdin)
+	choice, _ := reader.ReadString('\n')
+	return strings.TrimSpace(strings.ToLower(choice)) != "q"
+}
 
-	for _, section := range sections {
-		switch section.Type {
-		case "ours":
-			color.Green("┌─ YOUR CHANGES " + strings.Repeat("─", 30) + "┐")
-			color.Green("│")
-			lines := strings.Split(strings.TrimSpace(section.Content), "\n")
-			for _, line := range lines {
-				color.Green("│ " + line)
-			}
-			color.Green("└" + strings.Repeat("─", 47) + "┘")
-			fmt.Println()
-		case "theirs":
-			color.Red("┌─ THEIR CHANGES " + strings.Repeat("─", 29) + "┐")
-			color.Red("│")
-			lines := strings.Split(strings.TrimSpace(section.Content), "\n")
-			for _, line := range lines {
-				color.Red("│ " + line)
-			}
-			color.Red("└" + strings.Repeat("─", 47) + "┘")
-			fmt.Println()
-		case "normal":
-			// Show context lines in a muted color
-			if strings.TrimSpace(section.Content) != "" {
-				color.HiBlack("Context:")
-				lines := strings.Split(strings.TrimSpace(section.Content), "\n")
-				for _, line := range lines {
-					color.HiBlack("  " + line)
-				}
-				fmt.Println()
-			}
-		}
+func (r *Resolver) resolveConflict(ui *ui.TerminalUI, conflict git.Conflict, current, total int) error {
+	sections := r.sections(conflict)
+	displayConflict(ui, conflict, sections, fmt.Sprintf("Conflict Resolution (%d/%d)", current, total))
+	hunks, _ := hunksOf(sections)
+	hasBase := len(hunks) > 0
+	for _, hunk := range hunks {
+		hasBase = hasBase && hunk.HasBase
 	}
 
 	// Show options in a nice menu
@@ -88,6 +141,12 @@ func (r *Resolver) resolveConflict(ui *ui.TerminalUI, conflict git.Conflict, cur
 	color.HiBlack("  [4] Skip this file")
 	color.Magenta("  [5] Show diff")
 	color.Cyan("  [6] Show help")
+	if conflict.HasMarkers() || conflict.Type == "" {
+		color.Blue("  [7] Choose hunk by hunk")
+	}
+	if hasBase {
+		color.Yellow("  [8] Accept the base version")
+	}
 	fmt.Println()
 	color.White("Your choice: ")
 
@@ -97,8 +156,14 @@ func (r *Resolver) resolveConflict(ui *ui.TerminalUI, conflict git.Conflict, cur
 
 	switch choice {
 	case "1":
+		if conflict.Type != "" && conflict.Ours == nil {
+			return r.acceptDeletion(conflict.File)
+		}
 		return r.acceptOurs(conflict.File)
 	case "2":
+		if conflict.Type != "" && conflict.Theirs == nil {
+			return r.acceptDeletion(conflict.File)
+		}
 		return r.acceptTheirs(conflict.File)
 	case "3":
 		return r.editInEditor(conflict.File)
@@ -111,6 +176,21 @@ func (r *Resolver) resolveConflict(ui *ui.TerminalUI, conflict git.Conflict, cur
 	case "6":
 		r.showHelp()
 		return r.resolveConflict(ui, conflict, current, total)
+	case "7":
+		resolved, err := r.resolveHunks(ui, conflict)
+		if err != nil {
+			color.Red("❌ %v", err)
+			waitForEnter()
+		}
+		if !resolved {
+			return r.resolveConflict(ui, conflict, current, total)
+		}
+		return nil
+	case "8":
+		if !hasBase {
+			return r.resolveConflict(ui, conflict, current, total)
+		}
+		return r.acceptBase(conflict.File, sections, len(hunks))
 	default:
 		color.Red("❌ Invalid choice. Please try again.")
 		fmt.Println()
@@ -154,25 +234,296 @@ func (r *Resolver) acceptTheirs(file string) error {
 	return nil
 }
 
-func (r *Resolver) editInEditor(file string) error {
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		// Try common editors
-		for _, e := range []string{"code", "vim", "nano", "vi"} {
-			if _, err := exec.LookPath(e); err == nil {
-				editor = e
-				break
+// resolveHunks steps through each conflicted hunk of a file, asking which
+// side to keep, then writes the combined result and stages it. It reports
+// false if the user went back to the file menu.
+func (r *Resolver) resolveHunks(ui *ui.TerminalUI, conflict git.Conflict) (bool, error) {
+	sections := r.sections(conflict)
+	hunks, err := hunksOf(sections)
+	if err != nil {
+		return false, err
+	}
+	if len(hunks) == 0 {
+		return false, fmt.Errorf("no conflict markers found in %s", conflict.File)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	choices := make([]Choice, 0, len(hunks))
+	for i := 0; i < len(hunks); i++ {
+		hunk := hunks[i]
+		ui.Clear()
+		ui.DrawBox(fmt.Sprintf("Hunk %d/%d\nFile: %s", i+1, len(hunks), conflict.File))
+		fmt.Println()
+		printSide(color.Green, "YOUR CHANGES", hunk.Ours)
+		if hunk.HasBase {
+			printSide(color.Yellow, "BASE", hunk.Base)
+		}
+		printSide(color.Red, "THEIR CHANGES", hunk.Theirs)
+
+		fmt.Println(strings.Repeat("═", 50))
+		color.Green("  [1] Keep yours")
+		color.Red("  [2] Keep theirs")
+		color.Cyan("  [3] Keep both (yours first)")
+		color.Cyan("  [4] Keep both (theirs first)")
+		if hunk.HasBase {
+			color.Yellow("  [5] Keep base")
+		}
+		if i > 0 {
+			color.HiBlack("  [b] Back to the previous hunk")
+		}
+		color.HiBlack("  [q] Back to the file menu")
+		fmt.Println()
+		color.White("Your choice: ")
+
+		input, _ := reader.ReadString('\n')
+		switch strings.TrimSpace(strings.ToLower(input)) {
+		case "1":
+			choices = append(choices, ChoiceOurs)
+		case "2":
+			choices = append(choices, ChoiceTheirs)
+		case "3":
+			choices = append(choices, ChoiceOursThenTheirs)
+		case "4":
+			choices = append(choices, ChoiceTheirsThenOurs)
+		case "5":
+			if !hunk.HasBase {
+				i--
+				continue
+			}
+			choices = append(choices, ChoiceBase)
+		case "b":
+			if i > 0 {
+				choices = choices[:i-1]
+				i -= 2
+			} else {
+				i--
+			}
+		case "q":
+			return false, nil
+		default:
+			i--
+		}
+	}
+
+	content, err := applyChoices(sections, choices)
+	if err != nil {
+		return false, err
+	}
+	if err := r.writeResolved(conflict.File, content); err != nil {
+		return false, err
+	}
+
+	color.Green("✓ Resolved %d hunk(s) in %s\n", len(hunks), conflict.File)
+	return true, nil
+}
+
+// acceptBase resolves every hunk of a file with its base version, which is
+// only known when the markers were written in diff3 or zdiff3 style
+func (r *Resolver) acceptBase(file string, sections []ConflictSection, hunkCount int) error {
+	choices := make([]Choice, hunkCount)
+	for i := range choices {
+		choices[i] = ChoiceBase
+	}
+
+	content, err := applyChoices(sections, choices)
+	if err != nil {
+		return err
+	}
+	if err := r.writeResolved(file, content); err != nil {
+		return err
+	}
+
+	color.Green("✓ Accepted the base version for %s\n", file)
+	return nil
+}
+
+// sections parses a conflict using the marker size git uses for its file
+func (r *Resolver) sections(conflict git.Conflict) []ConflictSection {
+	markerSize := defaultMarkerSize
+	if r.repo != nil && r.repo.Path() != "" {
+		markerSize = r.repo.ConflictMarkerSize(conflict.File)
+	}
+	return parseConflictWithMarkerSize(conflict.Content, markerSize)
+}
+
+// writeResolved replaces a conflicted file with its resolved content and stages it
+func (r *Resolver) writeResolved(file, content string) error {
+	fullPath := filepath.Join(r.repo.Path(), file)
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(fullPath); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.WriteFile(fullPath, []byte(content), mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+
+	cmd := exec.Command("git", "add", "--", file)
+	cmd.Dir = r.repo.Path()
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to stage file: %w", err)
+	}
+	return nil
+}
+
+// printSide shows one side of a hunk in a box
+func printSide(print func(format string, a ...interface{}), title, content string) {
+	print("%s", "┌─ "+title+" "+strings.Repeat("─", 44-len(title))+"┐")
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		print("%s", "│ "+line)
+	}
+	print("%s", "└"+strings.Repeat("─", 47)+"┘")
+	fmt.Println()
+}
+
+func waitForEnter() {
+	color.HiBlack("Press Enter to continue...")
+	reader := bufio.NewReader(os.Stdin)
+	reader.ReadString('\n')
+}
+
+// contextLines is how many lines of context are shown next to each hunk
+const contextLines = 3
+
+// displayConflict clears the screen and shows a conflict's header and hunks
+func displayConflict(ui *ui.TerminalUI, conflict git.Conflict, sections []ConflictSection, title string) {
+	ui.Clear()
+
+	// Display header with box
+	header := fmt.Sprintf("%s\nFile: %s", title, conflict.File)
+	if conflict.Type != "" {
+		header += "\nType: " + conflict.Type
+	}
+	ui.DrawBox(header)
+	fmt.Println()
+
+	// Deleted, renamed, binary and submodule conflicts have no markers to show
+	if conflict.Type != "" && !conflict.HasMarkers() {
+		color.Yellow(conflict.Content)
+		fmt.Println()
+		describeStage("Yours", conflict.Ours)
+		describeStage("Theirs", conflict.Theirs)
+		fmt.Println()
+		return
+	}
+
+	// Display conflict with better formatting
+	for i, section := range sections {
+		switch section.Type {
+		case "ours":
+			color.Green("┌─ YOUR CHANGES " + strings.Repeat("─", 30) + "┐")
+			color.Green("│")
+			lines := strings.Split(strings.TrimSpace(section.Content), "\n")
+			for _, line := range lines {
+				color.Green("│ " + line)
+			}
+			color.Green("└" + strings.Repeat("─", 47) + "┘")
+			fmt.Println()
+		case "base":
+			printSide(color.Yellow, "BASE (common ancestor)", section.Content)
+		case "malformed":
+			color.Yellow("⚠ Malformed conflict markers, edit this part by hand:")
+			printSide(color.Yellow, "UNPARSED", section.Content)
+		case "theirs":
+			color.Red("┌─ THEIR CHANGES " + strings.Repeat("─", 29) + "┐")
+			color.Red("│")
+			lines := strings.Split(strings.TrimSpace(section.Content), "\n")
+			for _, line := range lines {
+				color.Red("│ " + line)
+			}
+			color.Red("└" + strings.Repeat("─", 47) + "┘")
+			fmt.Println()
+		case "normal":
+			// Show context lines in a muted color
+			if strings.TrimSpace(section.Content) != "" {
+				color.HiBlack("Context:")
+				lines := trimContext(strings.Split(strings.TrimSpace(section.Content), "\n"), i > 0, i < len(sections)-1)
+				for _, line := range lines {
+					color.HiBlack("  " + line)
+				}
+				fmt.Println()
 			}
 		}
-		if editor == "" {
-			return fmt.Errorf("no editor found. Please set EDITOR environment variable")
+	}
+}
+
+// trimContext keeps the lines of a context section that are next to a hunk:
+// the first lines when it follows a hunk and the last lines when it precedes one
+func trimContext(lines []string, afterHunk, beforeHunk bool) []string {
+	if len(lines) <= 2*contextLines {
+		return lines
+	}
+
+	var trimmed []string
+	if afterHunk {
+		trimmed = append(trimmed, lines[:contextLines]...)
+	}
+	trimmed = append(trimmed, "...")
+	if beforeHunk {
+		trimmed = append(trimmed, lines[len(lines)-contextLines:]...)
+	}
+	return trimmed
+}
+
+// acceptDeletion resolves a conflict in favor of the side that deleted the file
+func (r *Resolver) acceptDeletion(file string) error {
+	cmd := exec.Command("git", "rm", "--quiet", "--", file)
+	cmd.Dir = r.repo.Path()
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to remove file: %w", err)
+	}
+
+	color.Green("✓ Accepted the deletion of %s\n", file)
+	return nil
+}
+
+// describeStage prints which version of a path one side of a conflict has
+func describeStage(side string, stage *git.ConflictStage) {
+	switch {
+	case stage == nil:
+		color.HiBlack("  %s: deleted", side)
+	case stage.Mode == git.SubmoduleMode:
+		color.HiBlack("  %s: submodule at %s", side, shortOID(stage.OID))
+	default:
+		color.HiBlack("  %s: %s (%s)", side, stage.Path, shortOID(stage.OID))
+	}
+}
+
+func shortOID(oid string) string {
+	if len(oid) > 8 {
+		return oid[:8]
+	}
+	return oid
+}
+
+// editorCommand returns the editor and its arguments, e.g. "code --wait":
+// the configured editor, then $EDITOR, then the first common editor found
+func (r *Resolver) editorCommand() ([]string, error) {
+	for _, editor := range []string{r.editor, os.Getenv("EDITOR")} {
+		if fields := strings.Fields(editor); len(fields) > 0 {
+			return fields, nil
+		}
+	}
+
+	// Try common editors
+	for _, e := range []string{"code", "vim", "nano", "vi"} {
+		if _, err := exec.LookPath(e); err == nil {
+			return []string{e}, nil
 		}
 	}
+	return nil, fmt.Errorf("no editor found. Please set editor in the config or the EDITOR environment variable")
+}
+
+func (r *Resolver) editInEditor(file string) error {
+	editor, err := r.editorCommand()
+	if err != nil {
+		return err
+	}
 
 	fullPath := filepath.Join(r.repo.Path(), file)
-	color.Yellow("Opening %s in %s...\n", file, editor)
+	color.Yellow("Opening %s in %s...\n", file, editor[0])
 
-	cmd := exec.Command(editor, fullPath)
+	cmd := exec.Command(editor[0], append(editor[1:], fullPath)...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -236,45 +587,132 @@ func (r *Resolver) showHelp() {
 	color.Magenta("  Show Diff:")
 	fmt.Println("    View the differences between versions")
 	fmt.Println()
+	color.Blue("  Choose Hunk by Hunk:")
+	fmt.Println("    Keep yours, theirs, both or the base for each conflicted region")
+	fmt.Println("    The combined result is written back and staged")
+	fmt.Println()
+	color.Yellow("  Accept Base:")
+	fmt.Println("    Keep the common ancestor's version of every conflicted region")
+	fmt.Println("    Available with merge.conflictStyle set to diff3 or zdiff3")
+	fmt.Println()
 	color.HiBlack("Press Enter to continue...")
 	reader := bufio.NewReader(os.Stdin)
 	reader.ReadString('\n')
 }
 
 type ConflictSection struct {
-	Type    string // "ours", "theirs", "normal"
+	Type    string // "ours", "base", "theirs", "normal" or "malformed"
 	Content string
 }
 
+// defaultMarkerSize is the length of git's conflict markers unless the
+// conflict-marker-size attribute says otherwise
+const defaultMarkerSize = 7
+
+// parseConflict splits a file into normal text and the sides of each
+// conflict, using git's default marker size
 func parseConflict(content string) []ConflictSection {
-	lines := strings.Split(content, "\n")
+	return parseConflictWithMarkerSize(content, defaultMarkerSize)
+}
+
+// parseConflictWithMarkerSize splits a file into normal text and the ours,
+// base (diff3 and zdiff3 styles) and theirs sides of each conflict. Section
+// contents keep their line endings, so joining them back together reproduces
+// the file without its markers.
+//
+// Only markers of exactly markerSize characters count, so longer markers
+// from nested conflicts stay part of the text around them. A conflict that
+// is never closed is returned verbatim, markers included, as a "malformed"
+// section.
+func parseConflictWithMarkerSize(content string, markerSize int) []ConflictSection {
+	if markerSize <= 0 {
+		markerSize = defaultMarkerSize
+	}
+
 	sections := []ConflictSection{}
+	current := ConflictSection{Type: "normal"}
+	// raw holds the unparsed text of the open conflict, in case it is
+	// malformed, and start is the index of its first section
+	raw := ""
+	start := 0
+
+	flush := func() {
+		if current.Content != "" {
+			sections = append(sections, current)
+		}
+	}
+	// abandon turns the open conflict back into text
+	abandon := func() {
+		sections = append(sections[:start], ConflictSection{Type: "malformed", Content: raw})
+		raw = ""
+		current = ConflictSection{Type: "normal"}
+	}
+	// closeSide ends the current side of a conflict, keeping empty sides
+	closeSide := func(next string) {
+		sections = append(sections, current)
+		current = ConflictSection{Type: next}
+	}
 
-	currentSection := ConflictSection{Type: "normal", Content: ""}
-	inConflict := false
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if line == "" {
+			continue
+		}
+		inConflict := current.Type != "normal"
 
-	for _, line := range lines {
-		if strings.HasPrefix(line, "<<<<<<<") {
-			if strings.TrimSpace(currentSection.Content) != "" {
-				sections = append(sections, currentSection)
+		switch {
+		case isMarker(line, '<', markerSize):
+			if inConflict {
+				// A second opening marker before the first conflict closed
+				abandon()
 			}
-			currentSection = ConflictSection{Type: "ours", Content: ""}
-			inConflict = true
-		} else if strings.HasPrefix(line, "=======") && inConflict {
-			sections = append(sections, currentSection)
-			currentSection = ConflictSection{Type: "theirs", Content: ""}
-		} else if strings.HasPrefix(line, ">>>>>>>") && inConflict {
-			sections = append(sections, currentSection)
-			currentSection = ConflictSection{Type: "normal", Content: ""}
-			inConflict = false
-		} else {
-			currentSection.Content += line + "\n"
+			flush()
+			start = len(sections)
+			current = ConflictSection{Type: "ours"}
+			raw = line
+			continue
+		case inConflict && current.Type == "ours" && isMarker(line, '|', markerSize):
+			closeSide("base")
+		case inConflict && current.Type != "theirs" && isSeparator(line, markerSize):
+			closeSide("theirs")
+		case inConflict && isMarker(line, '>', markerSize):
+			if current.Type != "theirs" {
+				// Closing marker without a separator
+				raw += line
+				abandon()
+				continue
+			}
+			closeSide("normal")
+			raw = ""
+			continue
+		default:
+			current.Content += line
 		}
-	}
 
-	if strings.TrimSpace(currentSection.Content) != "" {
-		sections = append(sections, currentSection)
+		if inConflict {
+			raw += line
+		}
 	}
 
+	if current.Type != "normal" {
+		abandon()
+		return sections
+	}
+	flush()
 	return sections
 }
+
+// isMarker reports whether line is a conflict marker of exactly size
+// repetitions of char, optionally followed by a label
+func isMarker(line string, char byte, size int) bool {
+	if len(line) < size || strings.Count(line[:size], string(char)) != size {
+		return false
+	}
+	rest := line[size:]
+	return rest == "" || rest[0] == ' ' || rest[0] == '\n' || rest[0] == '\r'
+}
+
+// isSeparator reports whether line is the separator between the two sides
+// of a conflict, which never has a label
+func isSeparator(line string, size int) bool {
+	return isMarker(line, '=', size) && strings.TrimRight(line[size:], "\r\n") == ""
+}
@@ -9,13 +9,26 @@ import (
 )
 
 func TestNewResolver(t *testing.T) {
-	repo := &git.Repository{Path: "/test/path"}
+	repo := &git.Repository{}
 	resolver := NewResolver(repo)
 
 	assert.NotNil(t, resolver)
 	assert.Equal(t, repo, resolver.repo)
 }
 
+func TestResolver_EditorCommand(t *testing.T) {
+	t.Setenv("EDITOR", "nano")
+
+	resolver := NewResolverWithOptions(&git.Repository{}, Options{Editor: "code --wait"})
+	editor, err := resolver.editorCommand()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code", "--wait"}, editor, "the configured editor wins over $EDITOR")
+
+	editor, err = NewResolver(&git.Repository{}).editorCommand()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nano"}, editor)
+}
+
 func TestParseConflict(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -225,7 +238,7 @@ their change
 
 func TestResolver_Integration(t *testing.T) {
 	// Create a mock repository
-	repo := &git.Repository{Path: t.TempDir()}
+	repo := &git.Repository{}
 	resolver := NewResolver(repo)
 
 	// Verify resolver was created properly
@@ -263,3 +276,120 @@ line 2`,
 	assert.True(t, hasOurs, "Should have 'ours' section")
 	assert.True(t, hasTheirs, "Should have 'theirs' section")
 }
+
+func TestTrimContext(t *testing.T) {
+	lines := []string{"1", "2", "3", "4", "5", "6", "7", "8"}
+
+	assert.Equal(t, []string{"1", "2", "3"}, trimContext(lines[:3], true, true))
+	assert.Equal(t, []string{"...", "6", "7", "8"}, trimContext(lines, false, true))
+	assert.Equal(t, []string{"1", "2", "3", "..."}, trimContext(lines, true, false))
+	assert.Equal(t, []string{"1", "2", "3", "...", "6", "7", "8"}, trimContext(lines, true, true))
+}
+
+func TestParseConflict_Diff3AndZdiff3(t *testing.T) {
+	for _, style := range []string{"diff3", "zdiff3"} {
+		t.Run(style, func(t *testing.T) {
+			content := `before
+<<<<<<< HEAD
+our change
+||||||| merged common ancestors
+original
+=======
+their change
+>>>>>>> branch
+after
+`
+			sections := parseConflict(content)
+			require.Len(t, sections, 5)
+			assert.Equal(t, ConflictSection{Type: "normal", Content: "before\n"}, sections[0])
+			assert.Equal(t, ConflictSection{Type: "ours", Content: "our change\n"}, sections[1])
+			assert.Equal(t, ConflictSection{Type: "base", Content: "original\n"}, sections[2])
+			assert.Equal(t, ConflictSection{Type: "theirs", Content: "their change\n"}, sections[3])
+			assert.Equal(t, ConflictSection{Type: "normal", Content: "after\n"}, sections[4])
+
+			resolved, err := applyChoices(sections, []Choice{ChoiceBase})
+			require.NoError(t, err)
+			assert.Equal(t, "before\noriginal\nafter\n", resolved)
+		})
+	}
+}
+
+func TestParseConflictWithMarkerSize(t *testing.T) {
+	content := `<<<<<<<<<<<< HEAD
+ours
+<<<<<<< not a marker at this size
+============
+theirs
+>>>>>>>>>>>> branch
+`
+	sections := parseConflictWithMarkerSize(content, 12)
+	require.Len(t, sections, 2)
+	assert.Equal(t, "ours\n<<<<<<< not a marker at this size\n", sections[0].Content)
+	assert.Equal(t, "theirs\n", sections[1].Content)
+
+	// With the default size the longer markers are plain text and the
+	// seven character line opens a conflict that is never closed
+	sections = parseConflict(content)
+	require.Len(t, sections, 2)
+	assert.Equal(t, ConflictSection{Type: "normal", Content: "<<<<<<<<<<<< HEAD\nours\n"}, sections[0])
+	assert.Equal(t, "malformed", sections[1].Type)
+}
+
+func TestParseConflict_NestedMarkers(t *testing.T) {
+	// Recursive merges write inner conflicts with longer markers
+	content := `<<<<<<< HEAD
+ours
+=======
+<<<<<<<<< Temporary merge branch 1
+inner ours
+=========
+inner theirs
+>>>>>>>>> Temporary merge branch 2
+>>>>>>> branch
+`
+	sections := parseConflict(content)
+	require.Len(t, sections, 2)
+	assert.Equal(t, "ours", sections[0].Type)
+	assert.Equal(t, "theirs", sections[1].Type)
+	assert.Contains(t, sections[1].Content, "inner ours\n=========\ninner theirs\n")
+}
+
+func TestParseConflict_Malformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "unterminated conflict",
+			content: "before\n<<<<<<< HEAD\nours\n=======\ntheirs\n",
+		},
+		{
+			name:    "closing marker without separator",
+			content: "before\n<<<<<<< HEAD\nours\n>>>>>>> branch\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sections := parseConflict(tt.content)
+			require.Len(t, sections, 2)
+			assert.Equal(t, ConflictSection{Type: "normal", Content: "before\n"}, sections[0])
+			assert.Equal(t, "malformed", sections[1].Type)
+
+			// Nothing is lost, the markers are kept for hand editing
+			assert.Equal(t, tt.content, sections[0].Content+sections[1].Content)
+
+			_, err := hunksOf(sections)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("second opening marker", func(t *testing.T) {
+		content := "<<<<<<< HEAD\nstray\n<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> branch\n"
+		sections := parseConflict(content)
+		require.Len(t, sections, 3)
+		assert.Equal(t, ConflictSection{Type: "malformed", Content: "<<<<<<< HEAD\nstray\n"}, sections[0])
+		assert.Equal(t, "ours", sections[1].Type)
+		assert.Equal(t, "theirs", sections[2].Type)
+	})
+}
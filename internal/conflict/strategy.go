@@ -0,0 +1,163 @@
+package conflict
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/javanhut/harbinger/internal/git"
+	"github.com/javanhut/harbinger/pkg/config"
+)
+
+// Strategy resolves a whole conflicted file without asking
+type Strategy string
+
+const (
+	StrategyOurs   Strategy = "ours"
+	StrategyTheirs Strategy = "theirs"
+	// StrategyUnion keeps both sides of every hunk, ours first, like git's
+	// union merge driver
+	StrategyUnion Strategy = "union"
+)
+
+// ParseStrategy validates a strategy name from the command line or config
+func ParseStrategy(name string) (Strategy, error) {
+	switch strategy := Strategy(strings.ToLower(strings.TrimSpace(name))); strategy {
+	case StrategyOurs, StrategyTheirs, StrategyUnion:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown resolution strategy %q (expected ours, theirs or union)", name)
+	}
+}
+
+// Rule resolves conflicted files whose path matches Pattern with Strategy.
+// Patterns use path.Match syntax. A pattern without a slash matches the file
+// name in any directory, one with a slash matches the path from the
+// repository root.
+type Rule struct {
+	Pattern  string
+	Strategy Strategy
+}
+
+// Matches reports whether the rule applies to a repository-relative path
+func (r Rule) Matches(file string) bool {
+	return matchPattern(r.Pattern, file)
+}
+
+// matchPattern matches a repository-relative path against a glob. Patterns
+// without a slash match the file name in any directory.
+func matchPattern(pattern, file string) bool {
+	file = strings.TrimPrefix(path.Clean(strings.ReplaceAll(file, "\\", "/")), "./")
+	if !strings.Contains(pattern, "/") {
+		file = path.Base(file)
+	}
+	matched, err := path.Match(strings.TrimPrefix(pattern, "/"), file)
+	return err == nil && matched
+}
+
+// RulesFromConfig converts the resolve_rules config section into rules,
+// rejecting invalid patterns and unknown strategies
+func RulesFromConfig(entries []config.ResolveRule) ([]Rule, error) {
+	rules := make([]Rule, 0, len(entries))
+	for i, entry := range entries {
+		if entry.Pattern == "" {
+			return nil, fmt.Errorf("resolve rule %d: missing pattern", i+1)
+		}
+		if _, err := path.Match(entry.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("resolve rule %d: invalid pattern %q: %w", i+1, entry.Pattern, err)
+		}
+		strategy, err := ParseStrategy(entry.Strategy)
+		if err != nil {
+			return nil, fmt.Errorf("resolve rule %d: %w", i+1, err)
+		}
+		rules = append(rules, Rule{Pattern: entry.Pattern, Strategy: strategy})
+	}
+	return rules, nil
+}
+
+// ruleFor returns the first of rules matching file
+func ruleFor(rules []Rule, file string) (Rule, bool) {
+	for _, rule := range rules {
+		if rule.Matches(file) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// ApplyRules resolves every conflict matched by a rule and returns the ones
+// left for the user. Predicted conflicts are not in the working tree yet and
+// are always left, as are conflicts whose rule could not be applied.
+func (r *Resolver) ApplyRules(conflicts []git.Conflict) []git.Conflict {
+	return r.applyRules(conflicts, r.rules)
+}
+
+func (r *Resolver) applyRules(conflicts []git.Conflict, rules []Rule) []git.Conflict {
+	var remaining []git.Conflict
+	for _, conflict := range conflicts {
+		rule, ok := ruleFor(rules, conflict.File)
+		if !ok || conflict.Predicted {
+			remaining = append(remaining, conflict)
+			continue
+		}
+
+		if err := r.applyStrategy(conflict, rule.Strategy); err != nil {
+			color.Red("❌ Could not resolve %s with %s (rule %q): %v", conflict.File, rule.Strategy, rule.Pattern, err)
+			remaining = append(remaining, conflict)
+		}
+	}
+	return remaining
+}
+
+// applyStrategy resolves a conflicted file in the working tree and stages it
+func (r *Resolver) applyStrategy(conflict git.Conflict, strategy Strategy) error {
+	switch strategy {
+	case StrategyOurs:
+		if conflict.Type != "" && conflict.Ours == nil {
+			return r.acceptDeletion(conflict.File)
+		}
+		return r.acceptOurs(conflict.File)
+	case StrategyTheirs:
+		if conflict.Type != "" && conflict.Theirs == nil {
+			return r.acceptDeletion(conflict.File)
+		}
+		return r.acceptTheirs(conflict.File)
+	case StrategyUnion:
+		return r.acceptUnion(conflict)
+	default:
+		return fmt.Errorf("unknown resolution strategy %q", strategy)
+	}
+}
+
+// acceptUnion keeps both sides of every hunk, which suits append-only files
+// such as changelogs
+func (r *Resolver) acceptUnion(conflict git.Conflict) error {
+	if conflict.Type != "" && !conflict.HasMarkers() {
+		return fmt.Errorf("union needs conflict markers, not a %s conflict", conflict.Type)
+	}
+
+	sections := r.sections(conflict)
+	hunks, err := hunksOf(sections)
+	if err != nil {
+		return err
+	}
+	if len(hunks) == 0 {
+		return fmt.Errorf("no conflict markers found in %s", conflict.File)
+	}
+
+	choices := make([]Choice, len(hunks))
+	for i := range choices {
+		choices[i] = ChoiceOursThenTheirs
+	}
+	content, err := applyChoices(sections, choices)
+	if err != nil {
+		return err
+	}
+	if err := r.writeResolved(conflict.File, content); err != nil {
+		return err
+	}
+
+	color.Green("✓ Kept both sides of %d hunk(s) in %s\n", len(hunks), conflict.File)
+	return nil
+}
@@ -0,0 +1,198 @@
+package conflict
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/javanhut/harbinger/internal/git"
+	"github.com/javanhut/harbinger/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runGit runs a git command in dir and fails the test on error
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %s: %s", strings.Join(args, " "), output)
+	return strings.TrimSpace(string(output))
+}
+
+// writeFiles writes files into dir and commits them. An empty content
+// deletes the file.
+func writeFiles(t *testing.T, dir, message string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if content == "" {
+			require.NoError(t, os.Remove(filepath.Join(dir, name)))
+			continue
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-q", "-m", message)
+}
+
+// newConflictedRepo creates a repository stopped in a merge where both
+// branches edited the given files. It returns the repository and its
+// unmerged conflicts.
+func newConflictedRepo(t *testing.T, base, ours, theirs map[string]string) (*git.Repository, []git.Conflict) {
+	t.Helper()
+	t.Setenv("GIT_AUTHOR_NAME", "Harbinger Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Harbinger Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q", "-b", "main")
+	writeFiles(t, dir, "base", base)
+	runGit(t, dir, "checkout", "-q", "-b", "feature")
+	writeFiles(t, dir, "theirs", theirs)
+	runGit(t, dir, "checkout", "-q", "main")
+	writeFiles(t, dir, "ours", ours)
+
+	cmd := exec.Command("git", "merge", "feature")
+	cmd.Dir = dir
+	require.Error(t, cmd.Run(), "merge should stop on conflicts")
+
+	repo, err := git.NewRepository(dir)
+	require.NoError(t, err)
+	conflicts, err := repo.GetUnmergedConflicts()
+	require.NoError(t, err)
+	return repo, conflicts
+}
+
+func readFile(t *testing.T, repo *git.Repository, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(repo.Path(), name))
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestParseStrategy(t *testing.T) {
+	for _, name := range []string{"ours", "theirs", "union", " Theirs "} {
+		_, err := ParseStrategy(name)
+		assert.NoError(t, err, name)
+	}
+	_, err := ParseStrategy("base")
+	assert.Error(t, err)
+}
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		file    string
+		want    bool
+	}{
+		{"go.sum", "go.sum", true},
+		{"go.sum", "tools/go.sum", true},
+		{"*.lock", "web/yarn.lock", true},
+		{"docs/*.md", "docs/CHANGELOG.md", true},
+		{"docs/*.md", "CHANGELOG.md", false},
+		{"/CHANGELOG.md", "CHANGELOG.md", true},
+		{"/CHANGELOG.md", "docs/CHANGELOG.md", false},
+		{"*.go", "main.go.orig", false},
+	}
+
+	for _, tt := range tests {
+		rule := Rule{Pattern: tt.pattern, Strategy: StrategyOurs}
+		assert.Equal(t, tt.want, rule.Matches(tt.file), "%s against %s", tt.pattern, tt.file)
+	}
+}
+
+func TestRulesFromConfig(t *testing.T) {
+	rules, err := RulesFromConfig([]config.ResolveRule{
+		{Pattern: "go.sum", Strategy: "theirs"},
+		{Pattern: "CHANGELOG.md", Strategy: "union"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{Pattern: "go.sum", Strategy: StrategyTheirs},
+		{Pattern: "CHANGELOG.md", Strategy: StrategyUnion},
+	}, rules)
+
+	_, err = RulesFromConfig([]config.ResolveRule{{Pattern: "go.sum", Strategy: "newest"}})
+	assert.Error(t, err)
+	_, err = RulesFromConfig([]config.ResolveRule{{Pattern: "[", Strategy: "ours"}})
+	assert.Error(t, err)
+	_, err = RulesFromConfig([]config.ResolveRule{{Strategy: "ours"}})
+	assert.Error(t, err)
+}
+
+func TestApplyRules(t *testing.T) {
+	repo, conflicts := newConflictedRepo(t,
+		map[string]string{
+			"go.sum":       "a v1\n",
+			"CHANGELOG.md": "# Changes\n",
+			"main.go":      "package main\n",
+		},
+		map[string]string{
+			"go.sum":       "a v2\n",
+			"CHANGELOG.md": "# Changes\n- ours\n",
+			"main.go":      "package main // ours\n",
+		},
+		map[string]string{
+			"go.sum":       "a v3\n",
+			"CHANGELOG.md": "# Changes\n- theirs\n",
+			"main.go":      "package main // theirs\n",
+		},
+	)
+	require.Len(t, conflicts, 3)
+
+	resolver := NewResolverWithOptions(repo, Options{Rules: []Rule{
+		{Pattern: "go.sum", Strategy: StrategyTheirs},
+		{Pattern: "CHANGELOG.md", Strategy: StrategyUnion},
+	}})
+	remaining := resolver.ApplyRules(conflicts)
+
+	require.Len(t, remaining, 1)
+	assert.Equal(t, "main.go", remaining[0].File)
+	assert.Equal(t, "a v3\n", readFile(t, repo, "go.sum"))
+	assert.Equal(t, "# Changes\n- ours\n- theirs\n", readFile(t, repo, "CHANGELOG.md"))
+
+	files, err := repo.GetConflictedFiles()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go"}, files)
+}
+
+func TestApplyRules_SkipsPredictedAndUnresolvable(t *testing.T) {
+	repo, conflicts := newConflictedRepo(t,
+		map[string]string{"data.bin": "a\x00b\n"},
+		map[string]string{"data.bin": "a\x00ours\n"},
+		map[string]string{"data.bin": "a\x00theirs\n"},
+	)
+	require.Len(t, conflicts, 1)
+	require.True(t, conflicts[0].IsBinary())
+
+	resolver := NewResolverWithOptions(repo, Options{Rules: []Rule{{Pattern: "*", Strategy: StrategyUnion}}})
+	remaining := resolver.ApplyRules(conflicts)
+	assert.Len(t, remaining, 1, "union cannot merge a binary file")
+
+	predicted := []git.Conflict{{File: "data.bin", Predicted: true}}
+	assert.Equal(t, predicted, resolver.ApplyRules(predicted))
+}
+
+func TestApplyRules_OursKeepsDeletion(t *testing.T) {
+	repo, conflicts := newConflictedRepo(t,
+		map[string]string{"notes.txt": "notes\n", "keep.txt": "keep\n"},
+		map[string]string{"notes.txt": "", "keep.txt": "ours\n"},
+		map[string]string{"notes.txt": "edited\n", "keep.txt": "theirs\n"},
+	)
+	require.Len(t, conflicts, 2)
+
+	resolver := NewResolverWithOptions(repo, Options{Rules: []Rule{{Pattern: "*", Strategy: StrategyOurs}}})
+	assert.Empty(t, resolver.ApplyRules(conflicts))
+	assert.NoFileExists(t, filepath.Join(repo.Path(), "notes.txt"))
+	assert.Equal(t, "ours\n", readFile(t, repo, "keep.txt"))
+
+	files, err := repo.GetConflictedFiles()
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -0,0 +1,42 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ErrNotRunning is returned when no daemon is listening on the control socket
+var ErrNotRunning = errors.New("harbinger daemon is not running")
+
+// Send delivers a request to the daemon and waits for its response. A
+// response that reports a failure is returned as an error.
+func Send(socketPath string, req Request) (*Response, error) {
+	conn, err := net.DialTimeout("unix", socketPath, 2*time.Second)
+	if err != nil {
+		return nil, ErrNotRunning
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Minute))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if !resp.OK {
+		return &resp, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+// IsRunning reports whether a daemon answers on the control socket
+func IsRunning(socketPath string) bool {
+	_, err := Send(socketPath, Request{Command: CommandPing})
+	return err == nil
+}
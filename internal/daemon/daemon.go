@@ -0,0 +1,537 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/javanhut/harbinger/internal/git"
+	"github.com/javanhut/harbinger/internal/monitor"
+	"github.com/javanhut/harbinger/pkg/config"
+)
+
+// Spec describes a repository the daemon should monitor
+type Spec struct {
+	Path         string
+	PollInterval time.Duration
+	RemoteBranch string
+	Remote       string
+	// Worktrees expands the spec to every worktree of the repository
+	Worktrees bool
+	// Submodules also reports submodules that fall behind their upstream
+	Submodules bool
+}
+
+// key identifies a monitor by repository, remote and remote branch, so the
+// same branch can be watched against several remotes
+func (s Spec) key() string {
+	target := s.RemoteBranch
+	if s.Remote != "" {
+		target = s.Remote + "/" + s.RemoteBranch
+	}
+	if target == "" {
+		return s.Path
+	}
+	return s.Path + "@" + target
+}
+
+// MonitorInfo is the externally visible state of a monitor owned by the daemon
+type MonitorInfo struct {
+	Path         string    `json:"path"`
+	PollInterval string    `json:"poll_interval"`
+	RemoteBranch string    `json:"remote_branch,omitempty"`
+	Remote       string    `json:"remote,omitempty"`
+	Submodules   bool      `json:"submodules,omitempty"`
+	RemoteRef    string    `json:"remote_ref,omitempty"`
+	FromConfig   bool      `json:"from_config"`
+	StartedAt    time.Time `json:"started_at"`
+	Branch       string    `json:"branch,omitempty"`
+	Paused       bool      `json:"paused"`
+	Ignored      bool      `json:"ignored,omitempty"`
+	InSync       bool      `json:"in_sync"`
+	RemoteCommit string    `json:"remote_commit,omitempty"`
+	LastCheck    time.Time `json:"last_check"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+// Monitor is the subset of monitor.Monitor used by the daemon
+type Monitor interface {
+	Start() error
+	Stop() error
+	Status() monitor.Status
+	CheckNow()
+	Pause()
+	Resume()
+	SetPollInterval(interval time.Duration) error
+	Reload() error
+}
+
+// Factory creates a monitor for a repository
+type Factory func(path string, options monitor.Options) (Monitor, error)
+
+type entry struct {
+	spec       Spec
+	monitor    Monitor
+	fromConfig bool
+	startedAt  time.Time
+}
+
+// Daemon owns many monitors inside a single long-lived process
+type Daemon struct {
+	mu       sync.Mutex
+	monitors map[string]*entry
+	factory  Factory
+	done     chan struct{}
+	stopOnce sync.Once
+
+	// configManaged is set once the daemon owns the config repositories list
+	configManaged bool
+	// shutDown is set by Shutdown, after which no monitors are added
+	shutDown bool
+	// reconcileMu serializes Reconcile, which releases mu while it starts
+	// monitors
+	reconcileMu sync.Mutex
+}
+
+// New creates a daemon that builds monitors with monitor.New
+func New() *Daemon {
+	return NewWithFactory(func(path string, options monitor.Options) (Monitor, error) {
+		return monitor.New(path, options)
+	})
+}
+
+// NewWithFactory creates a daemon that builds monitors with the given factory
+func NewWithFactory(factory Factory) *Daemon {
+	return &Daemon{
+		monitors: make(map[string]*entry),
+		factory:  factory,
+		done:     make(chan struct{}),
+	}
+}
+
+// Add starts monitoring a repository, or each of its worktrees when
+// spec.Worktrees is set. Adding an already monitored repository is an error.
+func (d *Daemon) Add(spec Spec) error {
+	specs, err := ExpandWorktrees(spec)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, spec := range specs {
+		if err := d.add(spec, false); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (d *Daemon) add(spec Spec, fromConfig bool) error {
+	absPath, err := repoRoot(spec.Path)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for repository: %w", err)
+	}
+	spec.Path = absPath
+
+	d.mu.Lock()
+	_, exists := d.monitors[spec.key()]
+	d.mu.Unlock()
+	if exists {
+		return fmt.Errorf("repository is already monitored: %s", spec.key())
+	}
+
+	// Starting fetches from the remote, so other requests must not wait for it
+	m, err := d.factory(spec.Path, monitor.Options{
+		PollInterval: spec.PollInterval,
+		RemoteBranch: spec.RemoteBranch,
+		Remote:       spec.Remote,
+		Submodules:   spec.Submodules,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create monitor: %w", err)
+	}
+	if err := m.Start(); err != nil {
+		return fmt.Errorf("failed to start monitor: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, exists := d.monitors[spec.key()]; exists || d.shutDown {
+		if err := m.Stop(); err != nil {
+			log.Printf("[%s] Error stopping monitor for %s: %v", time.Now().Format(time.RFC3339), spec.key(), err)
+		}
+		if exists {
+			return fmt.Errorf("repository is already monitored: %s", spec.key())
+		}
+		return fmt.Errorf("daemon is shutting down")
+	}
+	d.monitors[spec.key()] = &entry{
+		spec:       spec,
+		monitor:    m,
+		fromConfig: fromConfig,
+		startedAt:  time.Now(),
+	}
+	log.Printf("[%s] Added monitor for %s", time.Now().Format(time.RFC3339), spec.key())
+	return nil
+}
+
+// Remove stops every monitor for the given repository path. If remoteBranch is
+// not empty, only the monitor tracking that remote branch is stopped.
+func (d *Daemon) Remove(path, remoteBranch string) (int, error) {
+	absPath, err := repoRoot(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get absolute path for repository: %w", err)
+	}
+
+	d.mu.Lock()
+	var removed []*entry
+	for key, e := range d.monitors {
+		if e.spec.Path != absPath {
+			continue
+		}
+		if remoteBranch != "" && e.spec.RemoteBranch != remoteBranch {
+			continue
+		}
+		delete(d.monitors, key)
+		removed = append(removed, e)
+	}
+	d.mu.Unlock()
+	stopEntries(removed)
+
+	if len(removed) == 0 {
+		return 0, fmt.Errorf("repository is not monitored: %s", absPath)
+	}
+	return len(removed), nil
+}
+
+// List returns the monitors owned by the daemon, sorted by path, remote branch
+// and remote
+func (d *Daemon) List() []MonitorInfo {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	infos := make([]MonitorInfo, 0, len(d.monitors))
+	for _, e := range d.monitors {
+		status := e.monitor.Status()
+		infos = append(infos, MonitorInfo{
+			Path:         e.spec.Path,
+			PollInterval: status.PollInterval.String(),
+			RemoteBranch: e.spec.RemoteBranch,
+			Remote:       e.spec.Remote,
+			Submodules:   e.spec.Submodules,
+			RemoteRef:    status.RemoteRef,
+			FromConfig:   e.fromConfig,
+			StartedAt:    e.startedAt,
+			Branch:       status.Branch,
+			Paused:       status.Paused,
+			Ignored:      status.Ignored,
+			InSync:       status.InSync,
+			RemoteCommit: status.RemoteCommit,
+			LastCheck:    status.LastCheck,
+			LastError:    status.LastError,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Path != infos[j].Path {
+			return infos[i].Path < infos[j].Path
+		}
+		if infos[i].RemoteBranch != infos[j].RemoteBranch {
+			return infos[i].RemoteBranch < infos[j].RemoteBranch
+		}
+		return infos[i].Remote < infos[j].Remote
+	})
+	return infos
+}
+
+// Control applies fn to every monitor for the given repository path, or to
+// all monitors when path is empty. It returns the number of monitors affected.
+// fn runs without the daemon lock, so a slow monitor does not block others.
+func (d *Daemon) Control(path, remoteBranch string, fn func(Monitor) error) (int, error) {
+	absPath := ""
+	if path != "" {
+		var err error
+		absPath, err = repoRoot(path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get absolute path for repository: %w", err)
+		}
+	}
+
+	d.mu.Lock()
+	var monitors []Monitor
+	for _, e := range d.monitors {
+		if absPath != "" && e.spec.Path != absPath {
+			continue
+		}
+		if remoteBranch != "" && e.spec.RemoteBranch != remoteBranch {
+			continue
+		}
+		monitors = append(monitors, e.monitor)
+	}
+	d.mu.Unlock()
+
+	affected := 0
+	for _, m := range monitors {
+		if err := fn(m); err != nil {
+			return affected, err
+		}
+		affected++
+	}
+
+	if affected == 0 && absPath != "" {
+		return 0, fmt.Errorf("repository is not monitored: %s", absPath)
+	}
+	return affected, nil
+}
+
+// Reconcile brings the config-managed monitors in line with the repositories
+// listed in cfg. Monitors added at runtime are left untouched.
+func (d *Daemon) Reconcile(cfg *config.Config) []error {
+	d.reconcileMu.Lock()
+	defer d.reconcileMu.Unlock()
+
+	specs, errs := SpecsFromConfig(cfg)
+
+	d.mu.Lock()
+	d.configManaged = true
+	d.mu.Unlock()
+
+	wanted := make(map[string]Spec, len(specs))
+	for _, spec := range specs {
+		wanted[spec.key()] = spec
+	}
+
+	d.mu.Lock()
+	var removed []*entry
+	for key, e := range d.monitors {
+		if !e.fromConfig {
+			// Runtime additions win over config entries for the same repository
+			delete(wanted, key)
+			continue
+		}
+		if spec, ok := wanted[key]; ok && spec == e.spec {
+			delete(wanted, key)
+			continue
+		}
+		delete(d.monitors, key)
+		removed = append(removed, e)
+	}
+	d.mu.Unlock()
+	stopEntries(removed)
+
+	for _, spec := range wanted {
+		if err := d.add(spec, true); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", spec.Path, err))
+		}
+	}
+	return errs
+}
+
+// Reload applies the current config files to every monitor and, once the
+// daemon manages the config repositories list, reconciles it as well
+func (d *Daemon) Reload() []error {
+	var errs []error
+	if _, err := d.Control("", "", func(m Monitor) error {
+		if err := m.Reload(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", m.Status().RepoPath, err))
+		}
+		return nil
+	}); err != nil {
+		errs = append(errs, err)
+	}
+
+	if !d.ConfigManaged() {
+		return errs
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return append(errs, fmt.Errorf("failed to load config: %w", err))
+	}
+	return append(errs, d.Reconcile(cfg)...)
+}
+
+// WatchConfig reconciles the config repositories list whenever the global
+// config file changes, until the daemon is shut down. Monitors watch their
+// own config files.
+func (d *Daemon) WatchConfig() {
+	watcher := config.NewWatcher(config.Layers("", ""))
+	ticker := time.NewTicker(config.WatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+			if !watcher.Changed() || !d.ConfigManaged() {
+				continue
+			}
+			log.Printf("[%s] Config file changed, reconciling repositories", time.Now().Format(time.RFC3339))
+			cfg, err := config.Load()
+			if err != nil {
+				log.Printf("[%s] Keeping the current repositories: %v", time.Now().Format(time.RFC3339), err)
+				continue
+			}
+			for _, err := range d.Reconcile(cfg) {
+				log.Printf("[%s] Warning: %v", time.Now().Format(time.RFC3339), err)
+			}
+		}
+	}
+}
+
+// ConfigManaged reports whether Reconcile has been used to load monitors from the config
+func (d *Daemon) ConfigManaged() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.configManaged
+}
+
+// stopEntries stops monitors that were already taken out of d.monitors.
+// Stopping waits for the monitor loop, pending notifications and running
+// hooks, so the caller must not hold d.mu.
+func stopEntries(entries []*entry) {
+	for _, e := range entries {
+		if err := e.monitor.Stop(); err != nil {
+			log.Printf("[%s] Error stopping monitor for %s: %v", time.Now().Format(time.RFC3339), e.spec.key(), err)
+		}
+		log.Printf("[%s] Removed monitor for %s", time.Now().Format(time.RFC3339), e.spec.key())
+	}
+}
+
+// Shutdown stops all monitors and marks the daemon as done
+func (d *Daemon) Shutdown() {
+	d.stopOnce.Do(func() {
+		d.mu.Lock()
+		d.shutDown = true
+		removed := make([]*entry, 0, len(d.monitors))
+		for key, e := range d.monitors {
+			delete(d.monitors, key)
+			removed = append(removed, e)
+		}
+		d.mu.Unlock()
+		stopEntries(removed)
+		close(d.done)
+	})
+}
+
+// Done is closed once the daemon has been shut down
+func (d *Daemon) Done() <-chan struct{} {
+	return d.done
+}
+
+// SpecsFromConfig converts the repositories section of the config into specs
+func SpecsFromConfig(cfg *config.Config) ([]Spec, []error) {
+	defaultInterval, err := cfg.PollIntervalDuration()
+	if err != nil {
+		defaultInterval = config.DefaultPollInterval
+	}
+
+	var specs []Spec
+	var errs []error
+	for _, repo := range cfg.Repositories {
+		if repo.Path == "" {
+			errs = append(errs, fmt.Errorf("repository entry is missing a path"))
+			continue
+		}
+
+		interval := defaultInterval
+		if repo.PollInterval < 0 {
+			errs = append(errs, fmt.Errorf("%s: invalid poll_interval %q: must be positive", repo.Path, repo.PollInterval))
+			continue
+		}
+		if repo.PollInterval > 0 {
+			interval = time.Duration(repo.PollInterval)
+		}
+
+		// Identify the repository the way add does, so reconciling matches
+		// entries that name a subdirectory or a symlink
+		path, err := repoRoot(expandHome(repo.Path))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", repo.Path, err))
+			continue
+		}
+
+		expanded, err := ExpandWorktrees(Spec{
+			Path:         path,
+			PollInterval: interval,
+			RemoteBranch: repo.RemoteBranch,
+			Remote:       repo.Remote,
+			Worktrees:    repo.Worktrees,
+			Submodules:   repo.Submodules,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", repo.Path, err))
+			continue
+		}
+		for _, spec := range expanded {
+			if spec.Path, err = repoRoot(spec.Path); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", repo.Path, err))
+				continue
+			}
+			specs = append(specs, spec)
+		}
+	}
+	return specs, errs
+}
+
+// ExpandWorktrees returns one spec per worktree of the repository when
+// spec.Worktrees is set, skipping bare and prunable entries. Otherwise it
+// returns the spec unchanged.
+func ExpandWorktrees(spec Spec) ([]Spec, error) {
+	if !spec.Worktrees {
+		return []Spec{spec}, nil
+	}
+
+	repo, err := git.NewRepository(spec.Path)
+	if err != nil {
+		return nil, err
+	}
+	worktrees, err := repo.Worktrees()
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []Spec
+	for _, wt := range worktrees {
+		if wt.Bare || wt.Prunable {
+			continue
+		}
+		worktreeSpec := spec
+		worktreeSpec.Path = wt.Path
+		specs = append(specs, worktreeSpec)
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no usable worktrees found for %s", spec.Path)
+	}
+	return specs, nil
+}
+
+// repoRoot returns the absolute path of the working tree containing path,
+// so a repository is identified the same way from any of its subdirectories
+func repoRoot(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if repo, err := git.NewRepository(absPath); err == nil {
+		return repo.Path(), nil
+	}
+	return absPath, nil
+}
+
+// expandHome replaces a leading "~/" with the user's home directory
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
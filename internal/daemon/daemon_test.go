@@ -0,0 +1,441 @@
+package daemon
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/javanhut/harbinger/internal/monitor"
+	"github.com/javanhut/harbinger/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMonitor struct {
+	// gate, when set, holds Start until it is closed
+	gate chan struct{}
+	// stopGate, when set, holds Stop until it is closed
+	stopGate chan struct{}
+	mu       sync.Mutex
+	started  bool
+	stopped  bool
+	paused   bool
+	checks   int
+	reloads  int
+	interval time.Duration
+}
+
+func (f *fakeMonitor) Status() monitor.Status {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return monitor.Status{Branch: "main", Paused: f.paused, PollInterval: f.interval}
+}
+
+func (f *fakeMonitor) CheckNow() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.checks++
+}
+
+func (f *fakeMonitor) Pause() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paused = true
+}
+
+func (f *fakeMonitor) Resume() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paused = false
+}
+
+func (f *fakeMonitor) SetPollInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("poll interval must be positive")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.interval = interval
+	return nil
+}
+
+func (f *fakeMonitor) Reload() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reloads++
+	return nil
+}
+
+func (f *fakeMonitor) Start() error {
+	if f.gate != nil {
+		<-f.gate
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.started = true
+	return nil
+}
+
+func (f *fakeMonitor) Stop() error {
+	if f.stopGate != nil {
+		<-f.stopGate
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopped = true
+	return nil
+}
+
+type fakeFactory struct {
+	mu       sync.Mutex
+	monitors map[string]*fakeMonitor
+	options  map[string]monitor.Options
+	// gate is given to the monitors created from now on
+	gate chan struct{}
+}
+
+func newFakeFactory() *fakeFactory {
+	return &fakeFactory{
+		monitors: make(map[string]*fakeMonitor),
+		options:  make(map[string]monitor.Options),
+	}
+}
+
+func (f *fakeFactory) create(path string, options monitor.Options) (Monitor, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if filepath.Base(path) == "broken" {
+		return nil, errors.New("not a git repository")
+	}
+	// Like monitor.New, fall back to poll_interval when no interval is given
+	m := &fakeMonitor{interval: options.PollInterval, gate: f.gate}
+	if m.interval <= 0 {
+		m.interval = config.DefaultPollInterval
+	}
+	f.monitors[path+"@"+options.RemoteBranch] = m
+	f.options[path+"@"+options.RemoteBranch] = options
+	return m, nil
+}
+
+func TestDaemon_AddListRemove(t *testing.T) {
+	factory := newFakeFactory()
+	d := NewWithFactory(factory.create)
+
+	require.NoError(t, d.Add(Spec{Path: "/repos/one", PollInterval: time.Minute}))
+	require.NoError(t, d.Add(Spec{Path: "/repos/two"}))
+	require.NoError(t, d.Add(Spec{Path: "/repos/two", RemoteBranch: "develop"}))
+
+	monitors := d.List()
+	require.Len(t, monitors, 3)
+	assert.Equal(t, "/repos/one", monitors[0].Path)
+	assert.Equal(t, "1m0s", monitors[0].PollInterval)
+	assert.Equal(t, "30s", monitors[1].PollInterval, "zero interval should fall back to the default")
+	assert.Zero(t, factory.options["/repos/two@"].PollInterval, "the monitor resolves poll_interval itself so it can follow reloads")
+	assert.Equal(t, "develop", monitors[2].RemoteBranch)
+	assert.True(t, factory.monitors["/repos/one@"].started)
+
+	// Duplicates are rejected
+	assert.Error(t, d.Add(Spec{Path: "/repos/one"}))
+	assert.Error(t, d.Add(Spec{Path: "/repos/two", RemoteBranch: "develop"}))
+
+	// Removing a path stops every monitor for it
+	removed, err := d.Remove("/repos/two", "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	assert.True(t, factory.monitors["/repos/two@"].stopped)
+	assert.True(t, factory.monitors["/repos/two@develop"].stopped)
+	assert.Len(t, d.List(), 1)
+
+	_, err = d.Remove("/repos/missing", "")
+	assert.Error(t, err)
+}
+
+func TestDaemon_AddSameBranchOnTwoRemotes(t *testing.T) {
+	d := NewWithFactory(newFakeFactory().create)
+
+	require.NoError(t, d.Add(Spec{Path: "/repos/one", RemoteBranch: "main", Remote: "origin"}))
+	require.NoError(t, d.Add(Spec{Path: "/repos/one", RemoteBranch: "main", Remote: "upstream"}))
+	assert.Error(t, d.Add(Spec{Path: "/repos/one", RemoteBranch: "main", Remote: "upstream"}))
+
+	monitors := d.List()
+	require.Len(t, monitors, 2)
+	assert.Equal(t, "origin", monitors[0].Remote)
+	assert.Equal(t, "upstream", monitors[1].Remote)
+}
+
+func TestDaemon_AddFactoryError(t *testing.T) {
+	d := NewWithFactory(newFakeFactory().create)
+
+	err := d.Add(Spec{Path: "/repos/broken"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to create monitor")
+	assert.Empty(t, d.List())
+}
+
+func TestDaemon_AddDoesNotBlockOthers(t *testing.T) {
+	factory := newFakeFactory()
+	d := NewWithFactory(factory.create)
+	require.NoError(t, d.Add(Spec{Path: "/repos/one"}))
+
+	// A monitor whose first fetch hangs
+	factory.gate = make(chan struct{})
+	added := make(chan error)
+	go func() { added <- d.Add(Spec{Path: "/repos/slow"}) }()
+
+	listed := make(chan []MonitorInfo)
+	go func() { listed <- d.List() }()
+	select {
+	case monitors := <-listed:
+		assert.Len(t, monitors, 1)
+	case <-time.After(5 * time.Second):
+		t.Fatal("List waited for a monitor to start")
+	}
+	affected, err := d.Control("", "", func(m Monitor) error { m.CheckNow(); return nil })
+	require.NoError(t, err)
+	assert.Equal(t, 1, affected)
+
+	close(factory.gate)
+	require.NoError(t, <-added)
+	assert.Len(t, d.List(), 2)
+}
+
+func TestDaemon_RemoveDoesNotBlockOthers(t *testing.T) {
+	factory := newFakeFactory()
+	d := NewWithFactory(factory.create)
+	require.NoError(t, d.Add(Spec{Path: "/repos/one"}))
+	require.NoError(t, d.Add(Spec{Path: "/repos/slow"}))
+
+	// A monitor whose final notifications take a while to send
+	slow := factory.monitors["/repos/slow@"]
+	slow.stopGate = make(chan struct{})
+	removed := make(chan error)
+	go func() {
+		_, err := d.Remove("/repos/slow", "")
+		removed <- err
+	}()
+
+	listed := make(chan []MonitorInfo)
+	go func() {
+		for {
+			if monitors := d.List(); len(monitors) == 1 {
+				listed <- monitors
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	select {
+	case monitors := <-listed:
+		assert.Equal(t, "/repos/one", monitors[0].Path)
+	case <-time.After(5 * time.Second):
+		t.Fatal("List waited for a monitor to stop")
+	}
+
+	close(slow.stopGate)
+	require.NoError(t, <-removed)
+	assert.True(t, slow.stopped)
+}
+
+func TestDaemon_Control(t *testing.T) {
+	factory := newFakeFactory()
+	d := NewWithFactory(factory.create)
+	require.NoError(t, d.Add(Spec{Path: "/repos/one"}))
+	require.NoError(t, d.Add(Spec{Path: "/repos/two"}))
+
+	pause := func(m Monitor) error {
+		m.Pause()
+		return nil
+	}
+
+	affected, err := d.Control("/repos/one", "", pause)
+	require.NoError(t, err)
+	assert.Equal(t, 1, affected)
+	assert.True(t, factory.monitors["/repos/one@"].paused)
+	assert.False(t, factory.monitors["/repos/two@"].paused)
+
+	// An empty path targets every monitor
+	affected, err = d.Control("", "", pause)
+	require.NoError(t, err)
+	assert.Equal(t, 2, affected)
+
+	monitors := d.List()
+	assert.True(t, monitors[0].Paused)
+	assert.Equal(t, "main", monitors[0].Branch)
+
+	_, err = d.Control("/repos/missing", "", pause)
+	assert.Error(t, err)
+}
+
+func TestDaemon_Reconcile(t *testing.T) {
+	factory := newFakeFactory()
+	d := NewWithFactory(factory.create)
+
+	require.NoError(t, d.Add(Spec{Path: "/repos/runtime"}))
+
+	cfg := &config.Config{
+		PollInterval: config.Duration(45 * time.Second),
+		Repositories: []config.RepositoryConfig{
+			{Path: "/repos/one"},
+			{Path: "/repos/two", PollInterval: config.Duration(2 * time.Minute), RemoteBranch: "main"},
+			{Path: "/repos/bad", PollInterval: config.Duration(-time.Second)},
+		},
+	}
+
+	assert.False(t, d.ConfigManaged())
+	errs := d.Reconcile(cfg)
+	assert.True(t, d.ConfigManaged())
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "invalid poll_interval")
+
+	monitors := d.List()
+	require.Len(t, monitors, 3)
+	assert.Equal(t, "45s", monitors[0].PollInterval)
+	assert.True(t, monitors[0].FromConfig)
+	assert.False(t, monitors[1].FromConfig, "runtime monitor should be kept")
+	assert.Equal(t, "2m0s", monitors[2].PollInterval)
+
+	// Dropping a repository from the config stops only its monitor
+	cfg.Repositories = cfg.Repositories[1:2]
+	assert.Empty(t, d.Reconcile(cfg))
+	assert.True(t, factory.monitors["/repos/one@"].stopped)
+	assert.Len(t, d.List(), 2)
+}
+
+func TestDaemon_ReconcileIsSerialized(t *testing.T) {
+	factory := newFakeFactory()
+	d := NewWithFactory(factory.create)
+
+	// The first reconcile hangs while starting its monitor
+	factory.gate = make(chan struct{})
+	first := make(chan []error)
+	go func() {
+		first <- d.Reconcile(&config.Config{Repositories: []config.RepositoryConfig{{Path: "/repos/one"}}})
+	}()
+	require.Eventually(t, func() bool {
+		factory.mu.Lock()
+		defer factory.mu.Unlock()
+		return factory.monitors["/repos/one@"] != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// A newer config without the repository must not finish before it
+	second := make(chan []error)
+	go func() { second <- d.Reconcile(&config.Config{}) }()
+	select {
+	case <-second:
+		t.Fatal("Reconcile ran alongside another reconcile")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(factory.gate)
+	assert.Empty(t, <-first)
+	assert.Empty(t, <-second)
+	assert.Empty(t, d.List(), "the newest config wins")
+	assert.True(t, factory.monitors["/repos/one@"].stopped)
+}
+
+func TestDaemon_Shutdown(t *testing.T) {
+	factory := newFakeFactory()
+	d := NewWithFactory(factory.create)
+	require.NoError(t, d.Add(Spec{Path: "/repos/one"}))
+
+	d.Shutdown()
+	d.Shutdown() // Must be safe to call twice
+
+	assert.True(t, factory.monitors["/repos/one@"].stopped)
+	assert.Empty(t, d.List())
+	select {
+	case <-d.Done():
+	default:
+		t.Fatal("Done channel should be closed after shutdown")
+	}
+}
+
+func TestSpecsFromConfig_ExpandsHome(t *testing.T) {
+	t.Setenv("HOME", "/home/tester")
+
+	specs, errs := SpecsFromConfig(&config.Config{
+		PollInterval: config.Duration(30 * time.Second),
+		Repositories: []config.RepositoryConfig{{Path: "~/src/project"}, {}},
+	})
+
+	require.Len(t, specs, 1)
+	assert.Equal(t, "/home/tester/src/project", specs[0].Path)
+	assert.Len(t, errs, 1)
+}
+
+// runGit runs git commands in dir with a fixed identity and no user config
+func runGit(t *testing.T, dir string, commands ...[]string) {
+	t.Helper()
+	t.Setenv("GIT_AUTHOR_NAME", "Harbinger Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Harbinger Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+
+	for _, args := range commands {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, "git %v: %s", args, output)
+	}
+}
+
+func TestDaemon_ReconcileMatchesSubdirectories(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "sub"), 0755))
+	runGit(t, repo, []string{"init", "-q", "-b", "main"})
+	require.NoError(t, os.Symlink(repo, filepath.Join(root, "link")))
+	repoPath, err := repoRoot(repo)
+	require.NoError(t, err)
+
+	factory := newFakeFactory()
+	d := NewWithFactory(factory.create)
+	cfg := &config.Config{Repositories: []config.RepositoryConfig{{Path: filepath.Join(repo, "sub")}}}
+	require.Empty(t, d.Reconcile(cfg))
+	require.Empty(t, d.Reconcile(cfg))
+	assert.False(t, factory.monitors[repoPath+"@"].stopped, "the entry matches its running monitor")
+
+	cfg.Repositories[0].Path = filepath.Join(root, "link")
+	require.Empty(t, d.Reconcile(cfg))
+	assert.False(t, factory.monitors[repoPath+"@"].stopped)
+	assert.Len(t, d.List(), 1)
+}
+
+func TestExpandWorktrees(t *testing.T) {
+	root := t.TempDir()
+	primary := filepath.Join(root, "main")
+	require.NoError(t, os.MkdirAll(primary, 0755))
+	runGit(t, primary,
+		[]string{"init", "-q", "-b", "main"},
+		[]string{"commit", "-q", "--allow-empty", "-m", "initial"},
+		[]string{"worktree", "add", "-q", "-b", "feature", filepath.Join(root, "feature")},
+		[]string{"worktree", "add", "-q", "-b", "gone", filepath.Join(root, "gone")},
+	)
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "gone")))
+
+	spec := Spec{Path: primary, RemoteBranch: "develop", Submodules: true}
+	specs, err := ExpandWorktrees(spec)
+	require.NoError(t, err)
+	assert.Equal(t, []Spec{spec}, specs, "worktrees are only expanded on request")
+
+	spec.Worktrees = true
+	specs, err = ExpandWorktrees(spec)
+	require.NoError(t, err)
+	require.Len(t, specs, 2, "prunable worktrees are skipped")
+	assert.Equal(t, "main", filepath.Base(specs[0].Path))
+	assert.Equal(t, "feature", filepath.Base(specs[1].Path))
+	assert.Equal(t, "develop", specs[1].RemoteBranch)
+	assert.True(t, specs[1].Submodules)
+
+	factory := newFakeFactory()
+	d := NewWithFactory(factory.create)
+	require.NoError(t, d.Add(spec))
+	assert.Len(t, d.List(), 2)
+	assert.True(t, factory.options[specs[1].Path+"@develop"].Submodules)
+}
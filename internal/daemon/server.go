@@ -0,0 +1,215 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Commands understood by the daemon control socket
+const (
+	CommandPing     = "ping"
+	CommandList     = "list"
+	CommandAdd      = "add"
+	CommandRemove   = "remove"
+	CommandReload   = "reload"
+	CommandShutdown = "shutdown"
+	CommandStatus   = "status"
+	CommandCheck    = "check"
+	CommandPause    = "pause"
+	CommandResume   = "resume"
+	CommandInterval = "interval"
+)
+
+// Request is a single JSON request sent over the control socket
+type Request struct {
+	Command      string `json:"command"`
+	Path         string `json:"path,omitempty"`
+	PollInterval string `json:"poll_interval,omitempty"`
+	RemoteBranch string `json:"remote_branch,omitempty"`
+	Remote       string `json:"remote,omitempty"`
+	Worktrees    bool   `json:"worktrees,omitempty"`
+	Submodules   bool   `json:"submodules,omitempty"`
+}
+
+// Response is the JSON reply to a Request
+type Response struct {
+	OK       bool          `json:"ok"`
+	Error    string        `json:"error,omitempty"`
+	PID      int           `json:"pid,omitempty"`
+	Removed  int           `json:"removed,omitempty"`
+	Affected int           `json:"affected,omitempty"`
+	Monitors []MonitorInfo `json:"monitors,omitempty"`
+}
+
+// DefaultSocketPath returns the control socket path in the user's home directory
+func DefaultSocketPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "harbinger.sock")
+	}
+	return filepath.Join(home, ".harbinger.sock")
+}
+
+// Listen opens the control socket, replacing a stale socket file left behind
+// by a daemon that did not shut down cleanly.
+func Listen(socketPath string) (net.Listener, error) {
+	if IsRunning(socketPath) {
+		return nil, fmt.Errorf("a harbinger daemon is already running on %s", socketPath)
+	}
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	return listener, nil
+}
+
+// Serve answers control requests until the listener is closed or the daemon
+// is shut down.
+func (d *Daemon) Serve(listener net.Listener) error {
+	go func() {
+		<-d.done
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-d.done:
+				return nil
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+		go d.handleConn(conn)
+	}
+}
+
+func (d *Daemon) handleConn(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Minute))
+
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		json.NewEncoder(conn).Encode(Response{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+
+	resp := d.handle(req)
+	resp.PID = os.Getpid()
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		log.Printf("[%s] Failed to write control response: %v", time.Now().Format(time.RFC3339), err)
+	}
+
+	if req.Command == CommandShutdown && resp.OK {
+		go d.Shutdown()
+	}
+}
+
+func (d *Daemon) handle(req Request) Response {
+	switch req.Command {
+	case CommandPing:
+		return Response{OK: true}
+	case CommandList, CommandStatus:
+		return Response{OK: true, Monitors: d.List()}
+	case CommandCheck:
+		return d.control(req, func(m Monitor) error {
+			m.CheckNow()
+			return nil
+		})
+	case CommandPause:
+		return d.control(req, func(m Monitor) error {
+			m.Pause()
+			return nil
+		})
+	case CommandResume:
+		return d.control(req, func(m Monitor) error {
+			m.Resume()
+			return nil
+		})
+	case CommandInterval:
+		interval, err := time.ParseDuration(req.PollInterval)
+		if err != nil {
+			return Response{Error: fmt.Sprintf("invalid poll interval: %v", err)}
+		}
+		return d.control(req, func(m Monitor) error {
+			return m.SetPollInterval(interval)
+		})
+	case CommandAdd:
+		if req.Path == "" {
+			return Response{Error: "path is required"}
+		}
+		spec := Spec{
+			Path:         req.Path,
+			RemoteBranch: req.RemoteBranch,
+			Remote:       req.Remote,
+			Worktrees:    req.Worktrees,
+			Submodules:   req.Submodules,
+		}
+		if req.PollInterval != "" {
+			interval, err := time.ParseDuration(req.PollInterval)
+			if err != nil {
+				return Response{Error: fmt.Sprintf("invalid poll interval: %v", err)}
+			}
+			spec.PollInterval = interval
+		}
+		if err := d.Add(spec); err != nil {
+			return Response{Error: err.Error()}
+		}
+		return Response{OK: true, Monitors: d.List()}
+	case CommandRemove:
+		if req.Path == "" {
+			return Response{Error: "path is required"}
+		}
+		removed, err := d.Remove(req.Path, req.RemoteBranch)
+		if err != nil {
+			return Response{Error: err.Error()}
+		}
+		return Response{OK: true, Removed: removed}
+	case CommandReload:
+		if req.Path != "" {
+			return d.control(req, func(m Monitor) error {
+				return m.Reload()
+			})
+		}
+		if errs := d.Reload(); len(errs) > 0 {
+			messages := make([]string, len(errs))
+			for i, err := range errs {
+				messages[i] = err.Error()
+			}
+			return Response{Error: strings.Join(messages, "; "), Monitors: d.List()}
+		}
+		return Response{OK: true, Monitors: d.List()}
+	case CommandShutdown:
+		return Response{OK: true}
+	default:
+		return Response{Error: fmt.Sprintf("unknown command: %q", req.Command)}
+	}
+}
+
+// control applies fn to the monitors selected by the request
+func (d *Daemon) control(req Request, fn func(Monitor) error) Response {
+	affected, err := d.Control(req.Path, req.RemoteBranch, fn)
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+	return Response{OK: true, Affected: affected, Monitors: d.List()}
+}
@@ -0,0 +1,137 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startTestServer(t *testing.T) (*Daemon, *fakeFactory, string) {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "harbinger.sock")
+	listener, err := Listen(socketPath)
+	require.NoError(t, err)
+
+	factory := newFakeFactory()
+	d := NewWithFactory(factory.create)
+	go d.Serve(listener)
+	t.Cleanup(d.Shutdown)
+
+	return d, factory, socketPath
+}
+
+func TestServer_RoundTrip(t *testing.T) {
+	_, factory, socketPath := startTestServer(t)
+
+	assert.True(t, IsRunning(socketPath))
+
+	resp, err := Send(socketPath, Request{Command: CommandAdd, Path: "/repos/one", PollInterval: "10s"})
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), resp.PID)
+	require.Len(t, resp.Monitors, 1)
+	assert.Equal(t, 10*time.Second, factory.options["/repos/one@"].PollInterval)
+
+	resp, err = Send(socketPath, Request{Command: CommandList})
+	require.NoError(t, err)
+	assert.Len(t, resp.Monitors, 1)
+
+	resp, err = Send(socketPath, Request{Command: CommandRemove, Path: "/repos/one"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, resp.Removed)
+}
+
+func TestServer_ControlCommands(t *testing.T) {
+	_, factory, socketPath := startTestServer(t)
+
+	_, err := Send(socketPath, Request{Command: CommandAdd, Path: "/repos/one"})
+	require.NoError(t, err)
+	fake := factory.monitors["/repos/one@"]
+
+	resp, err := Send(socketPath, Request{Command: CommandPause, Path: "/repos/one"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, resp.Affected)
+	assert.True(t, resp.Monitors[0].Paused)
+
+	_, err = Send(socketPath, Request{Command: CommandResume})
+	require.NoError(t, err)
+	assert.False(t, fake.paused)
+
+	_, err = Send(socketPath, Request{Command: CommandCheck, Path: "/repos/one"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, fake.checks)
+
+	resp, err = Send(socketPath, Request{Command: CommandInterval, PollInterval: "2m"})
+	require.NoError(t, err)
+	assert.Equal(t, "2m0s", resp.Monitors[0].PollInterval)
+
+	_, err = Send(socketPath, Request{Command: CommandInterval, PollInterval: "-1s"})
+	assert.Error(t, err)
+
+	resp, err = Send(socketPath, Request{Command: CommandStatus})
+	require.NoError(t, err)
+	assert.Len(t, resp.Monitors, 1)
+
+	_, err = Send(socketPath, Request{Command: CommandReload})
+	require.NoError(t, err)
+	assert.Equal(t, 1, fake.reloads, "reload applies the config files to every monitor")
+
+	resp, err = Send(socketPath, Request{Command: CommandReload, Path: "/repos/one"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, resp.Affected)
+	assert.Equal(t, 2, fake.reloads)
+}
+
+func TestServer_Errors(t *testing.T) {
+	_, _, socketPath := startTestServer(t)
+
+	_, err := Send(socketPath, Request{Command: "bogus"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown command")
+
+	_, err = Send(socketPath, Request{Command: CommandAdd})
+	assert.Error(t, err)
+
+	_, err = Send(socketPath, Request{Command: CommandAdd, Path: "/repos/one", PollInterval: "often"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid poll interval")
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	d, _, socketPath := startTestServer(t)
+
+	_, err := Send(socketPath, Request{Command: CommandShutdown})
+	require.NoError(t, err)
+
+	select {
+	case <-d.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("daemon did not shut down")
+	}
+}
+
+func TestListen_RejectsRunningDaemon(t *testing.T) {
+	_, _, socketPath := startTestServer(t)
+
+	_, err := Listen(socketPath)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already running")
+}
+
+func TestListen_ReplacesStaleSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "harbinger.sock")
+	require.NoError(t, os.WriteFile(socketPath, nil, 0600))
+
+	listener, err := Listen(socketPath)
+	require.NoError(t, err)
+	listener.Close()
+}
+
+func TestSend_NotRunning(t *testing.T) {
+	_, err := Send(filepath.Join(t.TempDir(), "missing.sock"), Request{Command: CommandPing})
+	assert.ErrorIs(t, err, ErrNotRunning)
+}
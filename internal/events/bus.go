@@ -0,0 +1,90 @@
+package events
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Handler consumes published events
+type Handler func(Event)
+
+// Bus delivers every published event to its subscribers, in the order they
+// subscribed. Handlers run on the publishing goroutine, so slow work such as
+// network calls belongs in a goroutine of their own.
+type Bus struct {
+	mu          sync.Mutex
+	subscribers []*subscriber
+}
+
+type subscriber struct {
+	handler Handler
+}
+
+// Subscribe adds a handler and returns a function that removes it again
+func (b *Bus) Subscribe(handler Handler) func() {
+	sub := &subscriber{handler: handler}
+
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, sub)
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, s := range b.subscribers {
+			if s == sub {
+				b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish hands an event to every subscriber
+func (b *Bus) Publish(event Event) {
+	b.mu.Lock()
+	subscribers := append([]*subscriber(nil), b.subscribers...)
+	b.mu.Unlock()
+
+	for _, sub := range subscribers {
+		sub.handler(event)
+	}
+}
+
+// Log writes an event to the log, stamped with the time it happened
+func Log(event Event) {
+	timestamp := event.Metadata().Time.Format(time.RFC3339)
+	for _, line := range strings.Split(event.String(), "\n") {
+		log.Printf("[%s] %s", timestamp, line)
+	}
+}
+
+// Recorder keeps every event it handles, for tests
+type Recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *Recorder) Handle(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// Events returns the events handled so far
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// Kinds returns the kinds of the events handled so far, in order
+func (r *Recorder) Kinds() []string {
+	var kinds []string
+	for _, event := range r.Events() {
+		kinds = append(kinds, event.Kind())
+	}
+	return kinds
+}
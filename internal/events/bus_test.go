@@ -0,0 +1,62 @@
+package events
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBus_PublishAndUnsubscribe(t *testing.T) {
+	var bus Bus
+	var order []string
+	bus.Subscribe(func(event Event) { order = append(order, "first "+event.Kind()) })
+	unsubscribe := bus.Subscribe(func(event Event) { order = append(order, "second "+event.Kind()) })
+
+	bus.Publish(BecameInSync{Meta: Meta{Branch: "main"}})
+	unsubscribe()
+	unsubscribe() // Removing twice is harmless
+	bus.Publish(BehindRemote{Meta: Meta{Branch: "main"}, Commits: 2})
+
+	assert.Equal(t, []string{"first in_sync", "second in_sync", "first behind_remote"}, order)
+}
+
+func TestRecorder(t *testing.T) {
+	var bus Bus
+	recorder := &Recorder{}
+	bus.Subscribe(recorder.Handle)
+
+	bus.Publish(FetchFailed{Err: errors.New("offline")})
+	bus.Publish(BranchSwitched{Meta: Meta{Branch: "feature"}, From: "main"})
+
+	assert.Equal(t, []string{KindFetchFailed, KindBranchSwitched}, recorder.Kinds())
+	assert.Equal(t, "main", recorder.Events()[1].(BranchSwitched).From)
+}
+
+func TestLog(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	flags := log.Flags()
+	log.SetFlags(0)
+	defer log.SetFlags(flags)
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	Log(ConflictsPredicted{
+		Meta:      Meta{Branch: "main", Time: at},
+		RemoteRef: "origin/main",
+		Files:     []string{"go.sum (both modified)", "README.md (both modified)"},
+	})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, []string{
+		"[2024-05-01T12:00:00Z] Found 2 conflicting file(s) with origin/main",
+		"[2024-05-01T12:00:00Z]   go.sum (both modified)",
+		"[2024-05-01T12:00:00Z]   README.md (both modified)",
+	}, lines)
+}
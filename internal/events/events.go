@@ -0,0 +1,248 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Event kinds, as reported by Kind. They are named like the notification
+// types, so hooks and notifier filters use the same names.
+const (
+	KindRemoteAdvanced     = "remote_change"
+	KindBranchSwitched     = "branch_switched"
+	KindBecameInSync       = "in_sync"
+	KindBehindRemote       = "behind_remote"
+	KindConflictsPredicted = "conflicts"
+	KindAutoSyncSucceeded  = "auto_pull"
+	KindAutoSyncFailed     = "auto_sync_failed"
+	KindFetchFailed        = "fetch_failed"
+	KindSubmoduleDrift     = "submodule_drift"
+	KindStashNotRestored   = "stash_not_restored"
+)
+
+// Kinds returns every event kind
+func Kinds() []string {
+	return []string{
+		KindRemoteAdvanced, KindBranchSwitched, KindBecameInSync, KindBehindRemote, KindConflictsPredicted,
+		KindAutoSyncSucceeded, KindAutoSyncFailed, KindFetchFailed, KindSubmoduleDrift, KindStashNotRestored,
+	}
+}
+
+// Event is something a monitor observed about its repository. String
+// describes it for the log, one line per entry.
+type Event interface {
+	Kind() string
+	Metadata() Meta
+	String() string
+}
+
+// Meta is carried by every event
+type Meta struct {
+	Repository string    `json:"repository"`
+	Branch     string    `json:"branch"`
+	Time       time.Time `json:"time"`
+}
+
+func (m Meta) Metadata() Meta {
+	return m
+}
+
+// RemoteAdvanced is published when the remote-tracking ref moves to a new commit
+type RemoteAdvanced struct {
+	Meta
+	RemoteRef string `json:"remote_ref"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+}
+
+func (e RemoteAdvanced) Kind() string { return KindRemoteAdvanced }
+
+func (e RemoteAdvanced) String() string {
+	return fmt.Sprintf("Remote %s advanced: %s -> %s", e.RemoteRef, short(e.From), short(e.To))
+}
+
+// BranchSwitched is published when another branch is checked out; Branch is
+// the new one
+type BranchSwitched struct {
+	Meta
+	From string `json:"from"`
+}
+
+func (e BranchSwitched) Kind() string { return KindBranchSwitched }
+
+func (e BranchSwitched) String() string {
+	return fmt.Sprintf("Branch switch detected: '%s' -> '%s'", e.From, e.Branch)
+}
+
+// BecameInSync is published when the branch catches up with the remote
+// after being out of sync
+type BecameInSync struct {
+	Meta
+	RemoteRef string `json:"remote_ref"`
+}
+
+func (e BecameInSync) Kind() string { return KindBecameInSync }
+
+func (e BecameInSync) String() string {
+	return fmt.Sprintf("Branch '%s' is now in sync with %s", e.Branch, e.RemoteRef)
+}
+
+// BehindRemote is published by every check that finds the branch behind
+type BehindRemote struct {
+	Meta
+	RemoteRef string `json:"remote_ref"`
+	Commit    string `json:"commit"` // The remote commit
+	Commits   int    `json:"commits"`
+}
+
+func (e BehindRemote) Kind() string { return KindBehindRemote }
+
+func (e BehindRemote) String() string {
+	return fmt.Sprintf("Branch is %d commit(s) behind %s", e.Commits, e.RemoteRef)
+}
+
+// ConflictsPredicted is published when syncing with the remote would
+// conflict; Files summarizes each conflicting file
+type ConflictsPredicted struct {
+	Meta
+	RemoteRef string   `json:"remote_ref"`
+	Files     []string `json:"files"`
+}
+
+func (e ConflictsPredicted) Kind() string { return KindConflictsPredicted }
+
+func (e ConflictsPredicted) String() string {
+	lines := []string{fmt.Sprintf("Found %d conflicting file(s) with %s", len(e.Files), e.RemoteRef)}
+	for _, file := range e.Files {
+		lines = append(lines, "  "+file)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// AutoSyncSucceeded is published when auto_sync or auto_resolve brought the
+// remote commits into the branch
+type AutoSyncSucceeded struct {
+	Meta
+	RemoteRef string `json:"remote_ref"`
+	Strategy  string `json:"strategy"`
+	Commits   int    `json:"commits"`
+}
+
+func (e AutoSyncSucceeded) Kind() string { return KindAutoSyncSucceeded }
+
+func (e AutoSyncSucceeded) String() string {
+	return fmt.Sprintf("Successfully synced %d commit(s) from %s (%s)", e.Commits, e.RemoteRef, e.Strategy)
+}
+
+// AutoSyncFailed is published when auto_sync or auto_resolve could not
+// sync, including when uncommitted changes or conflicts prevented it
+type AutoSyncFailed struct {
+	Meta
+	RemoteRef string `json:"remote_ref"`
+	Option    string `json:"option"` // The option that attempted the sync: "auto_sync" or "auto_resolve"
+	Err       error  `json:"-"`
+}
+
+func (e AutoSyncFailed) Kind() string { return KindAutoSyncFailed }
+
+func (e AutoSyncFailed) String() string {
+	if e.Option == "auto_resolve" {
+		return fmt.Sprintf("Auto-resolve failed: %v", e.Err)
+	}
+	return fmt.Sprintf("Auto-sync failed: %v", e.Err)
+}
+
+// FetchFailed is published when a check could not fetch from the remote
+type FetchFailed struct {
+	Meta
+	Err error `json:"-"`
+}
+
+func (e FetchFailed) Kind() string { return KindFetchFailed }
+
+func (e FetchFailed) String() string {
+	return fmt.Sprintf("Error: Failed to fetch remote changes: %v", e.Err)
+}
+
+// SubmoduleDrift is published when the number of commits a submodule's
+// upstream is ahead of the recorded commit changes
+type SubmoduleDrift struct {
+	Meta
+	Submodule string `json:"submodule"`
+	Upstream  string `json:"upstream"`
+	Commits   int    `json:"commits"`
+}
+
+func (e SubmoduleDrift) Kind() string { return KindSubmoduleDrift }
+
+func (e SubmoduleDrift) String() string {
+	return fmt.Sprintf("Submodule %s is %d commit(s) behind %s", e.Submodule, e.Commits, e.Upstream)
+}
+
+// StashNotRestored is published when changes auto-stashed around a sync no
+// longer apply. They are kept in the stash entry StashRef. Applied reports
+// whether git applied them with conflicts, rather than giving up before
+// touching the working tree.
+type StashNotRestored struct {
+	Meta
+	StashRef string `json:"stash_ref"`
+	OID      string `json:"oid"`
+	Applied  bool   `json:"applied"`
+	Err      error  `json:"-"`
+}
+
+func (e StashNotRestored) Kind() string { return KindStashNotRestored }
+
+func (e StashNotRestored) String() string {
+	lines := []string{fmt.Sprintf("Could not restore stashed changes: %v", e.Err)}
+	if e.Applied {
+		return strings.Join(append(lines,
+			fmt.Sprintf("Your changes were applied with conflicts and are still in %s (%s). To recover:", e.StashRef, short(e.OID)),
+			fmt.Sprintf("  1. Resolve the conflicted files in %s (harbinger resolve, or edit and git add them)", e.Repository),
+			fmt.Sprintf("  2. Once your changes are back, drop the entry: git stash drop %s", e.StashRef),
+			"  Or start over: git reset --hard discards the conflicted changes; remove the untracked files the stash",
+			fmt.Sprintf("  re-created, then run git stash apply %s", e.OID),
+		), "\n")
+	}
+	return strings.Join(append(lines,
+		fmt.Sprintf("Nothing was applied; your changes are safe in %s (%s). To recover:", e.StashRef, short(e.OID)),
+		fmt.Sprintf("  1. Commit or stash whatever blocks them in %s, then run: git stash apply %s", e.Repository, e.OID),
+		fmt.Sprintf("  2. Resolve any conflicts, then drop the entry: git stash drop %s", e.StashRef),
+	), "\n")
+}
+
+// Data returns the fields of an event by their JSON names, with its kind
+// as "type" and an error as its message under "error"
+func Data(event Event) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	data := map[string]interface{}{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+
+	data["type"] = event.Kind()
+	v := reflect.ValueOf(event)
+	for i := 0; i < v.NumField(); i++ {
+		if err, ok := v.Field(i).Interface().(error); ok && err != nil {
+			data["error"] = err.Error()
+		}
+	}
+	return data, nil
+}
+
+// short abbreviates a commit hash for the log
+func short(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
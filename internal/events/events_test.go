@@ -0,0 +1,52 @@
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestData(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	data, err := Data(AutoSyncFailed{
+		Meta:      Meta{Repository: "/src/api", Branch: "main", Time: at},
+		RemoteRef: "origin/main",
+		Option:    "auto_sync",
+		Err:       errors.New("uncommitted changes prevent auto-pull"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"type":       KindAutoSyncFailed,
+		"repository": "/src/api",
+		"branch":     "main",
+		"time":       "2024-05-01T12:00:00Z",
+		"remote_ref": "origin/main",
+		"option":     "auto_sync",
+		"error":      "uncommitted changes prevent auto-pull",
+	}, data)
+
+	data, err = Data(BehindRemote{Commits: 3})
+	require.NoError(t, err)
+	assert.Equal(t, "3", data["commits"].(interface{ String() string }).String())
+	assert.NotContains(t, data, "error")
+}
+
+func TestStashNotRestored_String(t *testing.T) {
+	event := StashNotRestored{
+		Meta:     Meta{Repository: "/src/api"},
+		StashRef: "stash@{0}",
+		OID:      "0123456789abcdef",
+		Err:      errors.New("conflict"),
+	}
+	assert.Contains(t, event.String(), "Nothing was applied; your changes are safe in stash@{0} (01234567)")
+	assert.Contains(t, event.String(), "git stash apply 0123456789abcdef")
+	assert.NotContains(t, event.String(), "Resolve the conflicted files")
+
+	event.Applied = true
+	assert.Contains(t, event.String(), "applied with conflicts")
+	assert.Contains(t, event.String(), "Resolve the conflicted files in /src/api")
+	assert.NotContains(t, event.String(), "git clean")
+}
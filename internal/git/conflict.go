@@ -0,0 +1,338 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Conflict types reported by git merge-tree. Git may report other types,
+// which are kept verbatim in Conflict.Type.
+const (
+	ConflictContents      = "contents"
+	ConflictBinary        = "binary"
+	ConflictAddAdd        = "add/add"
+	ConflictModifyDelete  = "modify/delete"
+	ConflictRenameDelete  = "rename/delete"
+	ConflictRenameRename  = "rename/rename"
+	ConflictFileDirectory = "file/directory"
+	ConflictDistinctModes = "distinct modes"
+)
+
+// SubmoduleMode is the git file mode of a submodule (gitlink) entry
+const SubmoduleMode = "160000"
+
+// ConflictStage is one version of a conflicted path: the merge base, ours or theirs
+type ConflictStage struct {
+	Mode string
+	OID  string
+	Path string
+}
+
+type Conflict struct {
+	File    string
+	Content string
+
+	// Type is git's conflict type, such as "contents" or "modify/delete"
+	Type string
+	// Message is git's description of the conflict
+	Message string
+	// Paths lists every path involved, e.g. both sides of a rename
+	Paths []string
+
+	// Base, Ours and Theirs are nil when the path does not exist on that side
+	Base   *ConflictStage
+	Ours   *ConflictStage
+	Theirs *ConflictStage
+
+	// Tree is the merge result tree of a predicted conflict
+	Tree string
+	// Predicted is set for conflicts found before merging, which exist only
+	// in Content and not in the working tree or index
+	Predicted bool
+}
+
+// IsSubmodule reports whether the conflict involves a submodule
+func (c Conflict) IsSubmodule() bool {
+	if strings.HasPrefix(c.Type, "submodule") {
+		return true
+	}
+	for _, stage := range []*ConflictStage{c.Base, c.Ours, c.Theirs} {
+		if stage != nil && stage.Mode == SubmoduleMode {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBinary reports whether git could not merge the file's contents because it is binary
+func (c Conflict) IsBinary() bool {
+	return c.Type == ConflictBinary
+}
+
+// HasMarkers reports whether resolving the conflict means editing conflict
+// markers, as opposed to choosing between versions of a deleted, renamed,
+// binary or submodule path
+func (c Conflict) HasMarkers() bool {
+	return (c.Type == ConflictContents || c.Type == ConflictAddAdd) && !c.IsSubmodule()
+}
+
+// Summary describes the conflict in a single line, e.g. "a.txt (modify/delete)"
+func (c Conflict) Summary() string {
+	if c.Type == "" {
+		return c.File
+	}
+	return fmt.Sprintf("%s (%s)", c.File, c.Type)
+}
+
+// parseConflictsFromMergeTree parses the output of
+// "git merge-tree --write-tree -z". The output is the result tree, one
+// "<mode> <oid> <stage>\t<path>" entry per conflicted stage, an empty field,
+// and then informational messages, each made of a path count, the paths, a
+// type and the message itself. All fields are NUL terminated.
+func parseConflictsFromMergeTree(output string) ([]Conflict, error) {
+	fields := strings.Split(output, "\x00")
+	tree := fields[0]
+	if tree == "" {
+		return nil, fmt.Errorf("unexpected merge-tree output: missing result tree")
+	}
+
+	var conflicts []Conflict
+	index := make(map[string]int)
+	conflictFor := func(path string) *Conflict {
+		i, ok := index[path]
+		if !ok {
+			i = len(conflicts)
+			index[path] = i
+			conflicts = append(conflicts, Conflict{File: path, Tree: tree})
+		}
+		return &conflicts[i]
+	}
+
+	i := 1
+	for ; i < len(fields) && fields[i] != ""; i++ {
+		if err := addStageEntry(fields[i], conflictFor); err != nil {
+			return nil, err
+		}
+	}
+	// Skip the empty field that ends the conflicted file list
+	i++
+
+	for i < len(fields) {
+		if fields[i] == "" {
+			i++
+			continue
+		}
+
+		count, err := strconv.Atoi(fields[i])
+		if err != nil || count < 1 || i+count+2 >= len(fields) {
+			return nil, fmt.Errorf("unexpected merge-tree message header: %q", fields[i])
+		}
+		paths := fields[i+1 : i+1+count]
+		messageType := fields[i+1+count]
+		message := strings.TrimSpace(fields[i+2+count])
+		i += count + 3
+
+		conflictType, ok := parseConflictType(messageType)
+		if !ok {
+			// Informational messages such as "Auto-merging"
+			continue
+		}
+
+		conflict := conflictFor(paths[0])
+		if conflict.Type == "" {
+			conflict.Type = conflictType
+			conflict.Message = message
+			conflict.Paths = append([]string(nil), paths...)
+		}
+	}
+
+	for i := range conflicts {
+		conflict := &conflicts[i]
+		if conflict.Type == "" {
+			conflict.Type = inferConflictType(conflict)
+		}
+		if len(conflict.Paths) == 0 {
+			conflict.Paths = []string{conflict.File}
+		}
+		conflict.Content = conflict.Message
+		if conflict.Content == "" {
+			conflict.Content = fmt.Sprintf("CONFLICT (%s): %s", conflict.Type, conflict.File)
+		}
+	}
+
+	return conflicts, nil
+}
+
+// loadConflictPreviews marks conflicts as predicted and replaces the content
+// of text conflicts with the would-be merged file from the merge result tree,
+// conflict markers included. Other conflicts keep git's message.
+func (r *Repository) loadConflictPreviews(conflicts []Conflict) {
+	for i := range conflicts {
+		conflict := &conflicts[i]
+		conflict.Predicted = true
+		if conflict.Tree == "" || !conflict.HasMarkers() {
+			continue
+		}
+
+		content, err := r.ShowFile(conflict.Tree, conflict.File)
+		if err != nil || strings.IndexByte(content, 0) >= 0 {
+			// Unreadable or binary, keep the message
+			continue
+		}
+		conflict.Content = content
+	}
+}
+
+// ShowFile returns the contents of path in a tree or commit without touching
+// the working tree or index
+func (r *Repository) ShowFile(treeish, path string) (string, error) {
+	if err := validateBranchName(treeish); err != nil {
+		return "", fmt.Errorf("invalid tree: %w", err)
+	}
+
+	cmd := exec.Command("git", "cat-file", "blob", treeish+":"+path)
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from %s: %w", path, treeish, err)
+	}
+	return string(output), nil
+}
+
+// ConflictMarkerSize returns the length of the conflict markers git writes
+// into file, from its conflict-marker-size attribute
+func (r *Repository) ConflictMarkerSize(file string) int {
+	const defaultSize = 7
+
+	cmd := exec.Command("git", "check-attr", "-z", "conflict-marker-size", "--", file)
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return defaultSize
+	}
+
+	// Output is "<path> NUL conflict-marker-size NUL <value> NUL"
+	fields := strings.Split(string(output), "\x00")
+	if len(fields) < 3 {
+		return defaultSize
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil || size <= 0 {
+		return defaultSize
+	}
+	return size
+}
+
+// addStageEntry parses a "<mode> <oid> <stage>\t<path>" index entry, as
+// printed by merge-tree and ls-files, into the conflict for its path
+func addStageEntry(entry string, conflictFor func(path string) *Conflict) error {
+	meta, path, ok := strings.Cut(entry, "\t")
+	parts := strings.Fields(meta)
+	if !ok || len(parts) != 3 {
+		return fmt.Errorf("unexpected conflict entry: %q", entry)
+	}
+
+	stage := &ConflictStage{Mode: parts[0], OID: parts[1], Path: path}
+	conflict := conflictFor(path)
+	switch parts[2] {
+	case "1":
+		conflict.Base = stage
+	case "2":
+		conflict.Ours = stage
+	case "3":
+		conflict.Theirs = stage
+	default:
+		return fmt.Errorf("unexpected stage %q for %s", parts[2], path)
+	}
+	return nil
+}
+
+// GetUnmergedConflicts returns the conflicts of an in-progress merge from
+// the index, with the working tree file as their content
+func (r *Repository) GetUnmergedConflicts() ([]Conflict, error) {
+	cmd := exec.Command("git", "ls-files", "--unmerged", "-z")
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unmerged files: %w", err)
+	}
+
+	var conflicts []Conflict
+	index := make(map[string]int)
+	conflictFor := func(path string) *Conflict {
+		i, ok := index[path]
+		if !ok {
+			i = len(conflicts)
+			index[path] = i
+			conflicts = append(conflicts, Conflict{File: path, Paths: []string{path}})
+		}
+		return &conflicts[i]
+	}
+
+	for _, entry := range strings.Split(string(output), "\x00") {
+		if entry == "" {
+			continue
+		}
+		if err := addStageEntry(entry, conflictFor); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range conflicts {
+		conflict := &conflicts[i]
+		conflict.Type = inferConflictType(conflict)
+		if conflict.IsSubmodule() {
+			conflict.Type = "submodule"
+		}
+
+		content, err := os.ReadFile(filepath.Join(r.path, conflict.File))
+		switch {
+		case err != nil:
+			conflict.Content = fmt.Sprintf("CONFLICT (%s): %s", conflict.Type, conflict.File)
+		case bytes.IndexByte(content, 0) >= 0:
+			conflict.Type = ConflictBinary
+			conflict.Content = fmt.Sprintf("CONFLICT (%s): %s", conflict.Type, conflict.File)
+		default:
+			conflict.Content = string(content)
+		}
+		conflict.Message = conflict.Content
+		if conflict.HasMarkers() {
+			conflict.Message = fmt.Sprintf("CONFLICT (%s): %s", conflict.Type, conflict.File)
+		}
+	}
+
+	return conflicts, nil
+}
+
+// parseConflictType extracts the type from a merge-tree message type such as
+// "CONFLICT (modify/delete)". It returns false for non-conflict messages.
+func parseConflictType(messageType string) (string, bool) {
+	if !strings.HasPrefix(messageType, "CONFLICT") {
+		return "", false
+	}
+
+	conflictType := strings.TrimSpace(strings.TrimPrefix(messageType, "CONFLICT"))
+	conflictType = strings.TrimSuffix(strings.TrimPrefix(conflictType, "("), ")")
+	if conflictType == "" {
+		conflictType = ConflictContents
+	}
+	return conflictType, true
+}
+
+// inferConflictType guesses the type of a conflict from the stages present
+// when git did not describe it
+func inferConflictType(conflict *Conflict) string {
+	switch {
+	case conflict.Ours == nil || conflict.Theirs == nil:
+		return ConflictModifyDelete
+	case conflict.Base == nil:
+		return ConflictAddAdd
+	default:
+		return ConflictContents
+	}
+}
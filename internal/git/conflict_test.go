@@ -0,0 +1,244 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mergeTreeOutput joins fields into NUL terminated merge-tree -z output
+func mergeTreeOutput(fields ...string) string {
+	return strings.Join(fields, "\x00") + "\x00"
+}
+
+func TestParseConflictsFromMergeTree(t *testing.T) {
+	tests := []struct {
+		name          string
+		output        string
+		expectedFiles []string
+		expectedTypes []string
+	}{
+		{
+			name:   "no conflicts",
+			output: mergeTreeOutput("4a88d67b"),
+		},
+		{
+			name: "one conflict",
+			output: mergeTreeOutput("4a88d67b",
+				"100644 aaaa 1\tfile1.txt",
+				"100644 bbbb 2\tfile1.txt",
+				"100644 cccc 3\tfile1.txt",
+				"",
+				"1", "file1.txt", "Auto-merging", "Auto-merging file1.txt\n",
+				"1", "file1.txt", "CONFLICT (contents)", "CONFLICT (content): Merge conflict in file1.txt\n"),
+			expectedFiles: []string{"file1.txt"},
+			expectedTypes: []string{ConflictContents},
+		},
+		{
+			name: "multiple conflicts",
+			output: mergeTreeOutput("4a88d67b",
+				"100644 aaaa 1\tfile1.txt",
+				"100644 bbbb 2\tfile1.txt",
+				"100644 cccc 3\tfile1.txt",
+				"100644 dddd 2\tfile2.js",
+				"100644 eeee 3\tfile2.js",
+				"100644 ffff 1\tfile3.go",
+				"100644 ffff 2\tfile3.go",
+				"",
+				"1", "file1.txt", "CONFLICT (contents)", "CONFLICT (content): Merge conflict in file1.txt\n",
+				"1", "file2.js", "CONFLICT (contents)", "CONFLICT (add/add): Merge conflict in file2.js\n",
+				"1", "file3.go", "CONFLICT (modify/delete)", "CONFLICT (modify/delete): file3.go deleted in theirs and modified in HEAD.\n"),
+			expectedFiles: []string{"file1.txt", "file2.js", "file3.go"},
+			expectedTypes: []string{ConflictContents, ConflictContents, ConflictModifyDelete},
+		},
+		{
+			name: "no message infers the type from the stages",
+			output: mergeTreeOutput("4a88d67b",
+				"100644 bbbb 2\tnew.txt",
+				"100644 cccc 3\tnew.txt",
+				""),
+			expectedFiles: []string{"new.txt"},
+			expectedTypes: []string{ConflictAddAdd},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflicts, err := parseConflictsFromMergeTree(tt.output)
+			require.NoError(t, err)
+			require.Len(t, conflicts, len(tt.expectedFiles))
+
+			for i, conflict := range conflicts {
+				assert.Equal(t, tt.expectedFiles[i], conflict.File)
+				assert.Equal(t, tt.expectedTypes[i], conflict.Type)
+				assert.Equal(t, "4a88d67b", conflict.Tree)
+				assert.NotEmpty(t, conflict.Content)
+			}
+		})
+	}
+}
+
+func TestParseConflictsFromMergeTree_RenameDelete(t *testing.T) {
+	output := mergeTreeOutput("4a88d67b",
+		"100644 aaaa 1\tsrc/new name.go",
+		"100644 aaaa 3\tsrc/new name.go",
+		"",
+		"2", "src/new name.go", "src/old name.go", "CONFLICT (rename/delete)",
+		"CONFLICT (rename/delete): src/old name.go renamed to src/new name.go in theirs, but deleted in HEAD.\n")
+
+	conflicts, err := parseConflictsFromMergeTree(output)
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+
+	conflict := conflicts[0]
+	assert.Equal(t, "src/new name.go", conflict.File)
+	assert.Equal(t, ConflictRenameDelete, conflict.Type)
+	assert.Equal(t, []string{"src/new name.go", "src/old name.go"}, conflict.Paths)
+	assert.Contains(t, conflict.Message, "renamed to src/new name.go")
+	require.NotNil(t, conflict.Base)
+	assert.Nil(t, conflict.Ours)
+	require.NotNil(t, conflict.Theirs)
+	assert.Equal(t, "aaaa", conflict.Theirs.OID)
+	assert.Equal(t, "100644", conflict.Theirs.Mode)
+	assert.False(t, conflict.HasMarkers())
+}
+
+func TestParseConflictsFromMergeTree_Submodule(t *testing.T) {
+	output := mergeTreeOutput("4a88d67b",
+		"160000 aaaa 1\tvendor/lib",
+		"160000 bbbb 2\tvendor/lib",
+		"160000 cccc 3\tvendor/lib",
+		"",
+		"1", "vendor/lib", "CONFLICT (submodule lacked merge base)", "Failed to merge submodule vendor/lib\n")
+
+	conflicts, err := parseConflictsFromMergeTree(output)
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "submodule lacked merge base", conflicts[0].Type)
+	assert.True(t, conflicts[0].IsSubmodule())
+	assert.False(t, conflicts[0].HasMarkers())
+}
+
+func TestParseConflictsFromMergeTree_Malformed(t *testing.T) {
+	_, err := parseConflictsFromMergeTree("")
+	assert.Error(t, err)
+
+	_, err = parseConflictsFromMergeTree(mergeTreeOutput("4a88d67b", "not an entry", ""))
+	assert.Error(t, err)
+
+	_, err = parseConflictsFromMergeTree(mergeTreeOutput("4a88d67b", "", "3", "a.txt"))
+	assert.Error(t, err)
+}
+
+func TestCheckForConflicts_StructuredTypes(t *testing.T) {
+	remote, clone := newClonedRepo(t)
+	commitFile(t, remote, "notes.txt", "keep me\n", "add notes")
+	runGit(t, clone, "pull", "-q")
+
+	// Theirs edits a file with a space in its name and deletes notes.txt
+	commitFile(t, remote, "shared.txt", "line 1\nremote change\nline 3\n", "remote edit")
+	runGit(t, remote, "rm", "-q", "notes.txt")
+	runGit(t, remote, "commit", "-q", "-m", "remove notes")
+	require.NoError(t, os.Rename(filepath.Join(clone, "shared.txt"), filepath.Join(clone, "shared file.txt")))
+	runGit(t, clone, "add", "-A")
+	runGit(t, clone, "commit", "-q", "-m", "rename shared")
+	commitFile(t, clone, "shared file.txt", "line 1\nlocal change\nline 3\n", "local edit")
+	commitFile(t, clone, "notes.txt", "changed\n", "edit notes")
+
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+	require.NoError(t, repo.Fetch())
+
+	conflicts, err := repo.CheckForConflicts("origin/main")
+	require.NoError(t, err)
+
+	types := make(map[string]string)
+	for _, conflict := range conflicts {
+		types[conflict.File] = conflict.Type
+	}
+	assert.Equal(t, map[string]string{
+		"notes.txt":       ConflictModifyDelete,
+		"shared file.txt": ConflictContents,
+	}, types)
+}
+
+func TestCheckForConflicts_PreviewHunks(t *testing.T) {
+	remote, clone := newClonedRepo(t)
+	commitFile(t, remote, "shared.txt", "line 1\nremote change\nline 3\n", "remote edit")
+	commitFile(t, clone, "shared.txt", "line 1\nlocal change\nline 3\n", "local edit")
+
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+	require.NoError(t, repo.Fetch())
+
+	conflicts, err := repo.CheckForConflicts("origin/main")
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+
+	conflict := conflicts[0]
+	assert.True(t, conflict.Predicted)
+	assert.Contains(t, conflict.Content, "<<<<<<< main")
+	assert.Contains(t, conflict.Content, "local change")
+	assert.Contains(t, conflict.Content, "remote change")
+	assert.Contains(t, conflict.Content, ">>>>>>> origin/main")
+	// Previews include the base version when no conflict style is configured
+	assert.Contains(t, conflict.Content, "|||||||")
+	assert.Contains(t, conflict.Content, "line 2")
+
+	// The preview must not touch the working tree or index
+	content, err := os.ReadFile(filepath.Join(clone, "shared.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "line 1\nlocal change\nline 3\n", string(content))
+	assert.Empty(t, runGit(t, clone, "status", "--porcelain"))
+}
+
+func TestConflictMarkerSize(t *testing.T) {
+	_, clone := newClonedRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(clone, ".gitattributes"), []byte("*.md conflict-marker-size=12\n"), 0644))
+
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+
+	assert.Equal(t, 12, repo.ConflictMarkerSize("docs/readme.md"))
+	assert.Equal(t, 7, repo.ConflictMarkerSize("shared.txt"))
+}
+
+func TestGetUnmergedConflicts(t *testing.T) {
+	remote, clone := newClonedRepo(t)
+	commitFile(t, remote, "notes.txt", "keep me\n", "add notes")
+	runGit(t, clone, "pull", "-q")
+
+	commitFile(t, remote, "shared.txt", "line 1\nremote change\nline 3\n", "remote edit")
+	runGit(t, remote, "rm", "-q", "notes.txt")
+	runGit(t, remote, "commit", "-q", "-m", "remove notes")
+	commitFile(t, clone, "shared.txt", "line 1\nlocal change\nline 3\n", "local edit")
+	commitFile(t, clone, "notes.txt", "changed\n", "edit notes")
+
+	runGit(t, clone, "fetch", "-q")
+	cmd := exec.Command("git", "merge", "origin/main")
+	cmd.Dir = clone
+	require.Error(t, cmd.Run(), "merge should stop on conflicts")
+
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+	conflicts, err := repo.GetUnmergedConflicts()
+	require.NoError(t, err)
+	require.Len(t, conflicts, 2)
+
+	assert.Equal(t, "notes.txt", conflicts[0].File)
+	assert.Equal(t, ConflictModifyDelete, conflicts[0].Type)
+	assert.NotNil(t, conflicts[0].Ours)
+	assert.Nil(t, conflicts[0].Theirs)
+	assert.False(t, conflicts[0].HasMarkers())
+
+	assert.Equal(t, "shared.txt", conflicts[1].File)
+	assert.Equal(t, ConflictContents, conflicts[1].Type)
+	assert.True(t, conflicts[1].HasMarkers())
+	assert.Contains(t, conflicts[1].Content, "<<<<<<<")
+	assert.False(t, conflicts[1].Predicted)
+}
@@ -0,0 +1,162 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Operation describes an in-progress merge, rebase, cherry-pick or revert
+type Operation struct {
+	// Kind is one of the Operation* constants
+	Kind string
+	// Commit is the commit being merged, replayed, picked or reverted
+	Commit  string
+	Subject string
+
+	// Step and Total are the rebase progress, zero when unknown
+	Step  int
+	Total int
+	// Branch and Onto describe a rebase: the branch being rebased and the
+	// commit it is rebased onto
+	Branch string
+	Onto   string
+}
+
+// Describe summarizes the operation, e.g.
+// "rebasing main onto 1a2b3c4d (step 2/5), replaying 5e6f7a8b Fix typo"
+func (o *Operation) Describe() string {
+	var b strings.Builder
+	switch o.Kind {
+	case OperationRebase:
+		b.WriteString("rebasing")
+		if o.Branch != "" {
+			b.WriteString(" " + o.Branch)
+		}
+		if o.Onto != "" {
+			b.WriteString(" onto " + shortCommit(o.Onto))
+		}
+		if o.Total > 0 {
+			fmt.Fprintf(&b, " (step %d/%d)", o.Step, o.Total)
+		}
+		b.WriteString(", replaying")
+	case OperationCherryPick:
+		b.WriteString("cherry-picking")
+	case OperationRevert:
+		b.WriteString("reverting")
+	case OperationMerge:
+		b.WriteString("merging")
+	default:
+		b.WriteString(o.Kind)
+	}
+
+	if o.Commit != "" {
+		b.WriteString(" " + shortCommit(o.Commit))
+		if o.Subject != "" {
+			b.WriteString(" " + o.Subject)
+		}
+	}
+	return b.String()
+}
+
+// CanSkip reports whether the current commit of the operation can be skipped
+func (o *Operation) CanSkip() bool {
+	return o.Kind == OperationRebase || o.Kind == OperationCherryPick || o.Kind == OperationRevert
+}
+
+// CurrentOperation returns the in-progress operation with the commit it is
+// stopped at, or nil if there is none
+func (r *Repository) CurrentOperation() (*Operation, error) {
+	kind, err := r.InProgressOperation()
+	if err != nil || kind == "" {
+		return nil, err
+	}
+
+	op := &Operation{Kind: kind}
+	switch kind {
+	case OperationRebase:
+		dir := "rebase-merge"
+		if _, err := r.readGitFile(dir + "/head-name"); err != nil {
+			dir = "rebase-apply"
+		}
+		op.Commit, _ = r.readGitFile("REBASE_HEAD")
+		op.Onto, _ = r.readGitFile(dir + "/onto")
+		if head, err := r.readGitFile(dir + "/head-name"); err == nil {
+			op.Branch = strings.TrimPrefix(head, "refs/heads/")
+		}
+		stepFile, totalFile := "msgnum", "end"
+		if dir == "rebase-apply" {
+			stepFile, totalFile = "next", "last"
+		}
+		if step, err := r.readGitFile(dir + "/" + stepFile); err == nil {
+			op.Step, _ = strconv.Atoi(step)
+		}
+		if total, err := r.readGitFile(dir + "/" + totalFile); err == nil {
+			op.Total, _ = strconv.Atoi(total)
+		}
+	case OperationCherryPick:
+		op.Commit, _ = r.readGitFile("CHERRY_PICK_HEAD")
+	case OperationRevert:
+		op.Commit, _ = r.readGitFile("REVERT_HEAD")
+	case OperationMerge:
+		// MERGE_HEAD lists every merged commit, one per line
+		heads, _ := r.readGitFile("MERGE_HEAD")
+		op.Commit, _, _ = strings.Cut(heads, "\n")
+	}
+
+	if op.Commit != "" {
+		op.Subject, _ = r.git("log", "-1", "--format=%s", op.Commit)
+	}
+	return op, nil
+}
+
+// ContinueOperation commits the resolved conflicts and carries on with the
+// operation, keeping the prepared commit message
+func (r *Repository) ContinueOperation(op *Operation) error {
+	return r.runOperation(op, "--continue")
+}
+
+// SkipOperation drops the commit the operation is stopped at and carries on
+func (r *Repository) SkipOperation(op *Operation) error {
+	if !op.CanSkip() {
+		return fmt.Errorf("a %s cannot skip commits", op.Kind)
+	}
+	return r.runOperation(op, "--skip")
+}
+
+// AbortOperation cancels the operation and restores the state before it began
+func (r *Repository) AbortOperation(op *Operation) error {
+	return r.runOperation(op, "--abort")
+}
+
+func (r *Repository) runOperation(op *Operation, action string) error {
+	cmd := exec.Command("git", op.Kind, action)
+	cmd.Dir = r.path
+	// Accept the prepared commit message instead of opening an editor
+	cmd.Env = append(os.Environ(), "GIT_EDITOR=true")
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s %s failed: %w - %s", op.Kind, action, err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
+
+// readGitFile reads a file inside the git directory, trimming whitespace
+func (r *Repository) readGitFile(name string) (string, error) {
+	path, err := r.gitPath(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,107 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gitFails runs a git command that is expected to stop on conflicts
+func gitFails(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	require.Error(t, cmd.Run(), "git %v should have stopped", args)
+}
+
+func TestCurrentOperation_None(t *testing.T) {
+	_, clone := newClonedRepo(t)
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+
+	op, err := repo.CurrentOperation()
+	require.NoError(t, err)
+	assert.Nil(t, op)
+}
+
+func TestCurrentOperation_Rebase(t *testing.T) {
+	remote, clone := newClonedRepo(t)
+	commitFile(t, remote, "shared.txt", "line 1\nremote\nline 3\n", "remote edit")
+	commitFile(t, clone, "local.txt", "local\n", "local file")
+	commitFile(t, clone, "shared.txt", "line 1\nlocal\nline 3\n", "local edit")
+	runGit(t, clone, "fetch", "-q")
+	gitFails(t, clone, "rebase", "origin/main")
+
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+	op, err := repo.CurrentOperation()
+	require.NoError(t, err)
+	require.NotNil(t, op)
+
+	assert.Equal(t, OperationRebase, op.Kind)
+	assert.Equal(t, runGit(t, clone, "rev-parse", "main"), op.Commit)
+	assert.Equal(t, "local edit", op.Subject)
+	assert.Equal(t, "main", op.Branch)
+	assert.Equal(t, runGit(t, clone, "rev-parse", "origin/main"), op.Onto)
+	assert.Equal(t, 2, op.Step)
+	assert.Equal(t, 2, op.Total)
+	assert.Contains(t, op.Describe(), "rebasing main onto")
+	assert.Contains(t, op.Describe(), "(step 2/2), replaying")
+
+	// Resolve and continue
+	require.NoError(t, os.WriteFile(filepath.Join(clone, "shared.txt"), []byte("line 1\nboth\nline 3\n"), 0644))
+	runGit(t, clone, "add", "shared.txt")
+	require.NoError(t, repo.ContinueOperation(op))
+
+	op, err = repo.CurrentOperation()
+	require.NoError(t, err)
+	assert.Nil(t, op)
+	assert.Equal(t, "2", runGit(t, clone, "rev-list", "--count", "origin/main..HEAD"))
+}
+
+func TestCurrentOperation_CherryPickSkipAndRevertAbort(t *testing.T) {
+	_, clone := newClonedRepo(t)
+	runGit(t, clone, "checkout", "-q", "-b", "topic")
+	commitFile(t, clone, "shared.txt", "line 1\ntopic\nline 3\n", "topic edit")
+	runGit(t, clone, "checkout", "-q", "main")
+	commitFile(t, clone, "shared.txt", "line 1\nmain\nline 3\n", "main edit")
+
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+	head := runGit(t, clone, "rev-parse", "HEAD")
+
+	gitFails(t, clone, "cherry-pick", "topic")
+	op, err := repo.CurrentOperation()
+	require.NoError(t, err)
+	require.NotNil(t, op)
+	assert.Equal(t, OperationCherryPick, op.Kind)
+	assert.Equal(t, runGit(t, clone, "rev-parse", "topic"), op.Commit)
+	assert.True(t, op.CanSkip())
+	require.NoError(t, repo.SkipOperation(op))
+	assert.Equal(t, head, runGit(t, clone, "rev-parse", "HEAD"))
+
+	commitFile(t, clone, "shared.txt", "line 1\nmain again\nline 3\n", "main edit 2")
+	gitFails(t, clone, "revert", "--no-edit", "HEAD~1")
+	op, err = repo.CurrentOperation()
+	require.NoError(t, err)
+	require.NotNil(t, op)
+	assert.Equal(t, OperationRevert, op.Kind)
+	assert.Equal(t, "main edit", op.Subject)
+	require.NoError(t, repo.AbortOperation(op))
+
+	op, err = repo.CurrentOperation()
+	require.NoError(t, err)
+	assert.Nil(t, op)
+}
+
+func TestSkipOperation_Merge(t *testing.T) {
+	_, clone := newClonedRepo(t)
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+
+	assert.Error(t, repo.SkipOperation(&Operation{Kind: OperationMerge}))
+}
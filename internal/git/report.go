@@ -0,0 +1,94 @@
+package git
+
+import "fmt"
+
+// SyncReport summarizes how a repository's current branch relates to its remote
+type SyncReport struct {
+	Path               string   `json:"path"`
+	Branch             string   `json:"branch"`
+	Upstream           string   `json:"upstream,omitempty"`
+	Ahead              int      `json:"ahead"`
+	Behind             int      `json:"behind"`
+	UncommittedChanges bool     `json:"uncommitted_changes"`
+	InProgress         string   `json:"in_progress,omitempty"`
+	PredictedConflicts []string `json:"predicted_conflicts"`
+	Errors             []string `json:"errors,omitempty"`
+}
+
+// InSync reports whether the branch is neither ahead of nor behind its remote
+func (s *SyncReport) InSync() bool {
+	return s.Ahead == 0 && s.Behind == 0
+}
+
+// SyncReport builds a report for the current branch from the local view of
+// its upstream. Call Fetch first for up-to-date results. Problems with
+// individual checks are recorded in Errors rather than failing the report.
+func (r *Repository) SyncReport() (*SyncReport, error) {
+	branch, err := r.GetCurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SyncReport{
+		Path:               r.path,
+		Branch:             branch,
+		PredictedConflicts: []string{},
+	}
+	addError := func(format string, args ...interface{}) {
+		report.Errors = append(report.Errors, fmt.Sprintf(format, args...))
+	}
+
+	if hasChanges, err := r.HasUncommittedChanges(); err != nil {
+		addError("uncommitted changes: %v", err)
+	} else {
+		report.UncommittedChanges = hasChanges
+	}
+
+	if operation, err := r.InProgressOperation(); err != nil {
+		addError("in-progress operation: %v", err)
+	} else {
+		report.InProgress = operation
+	}
+
+	// Nothing to compare against on a detached HEAD
+	if branch == "HEAD" {
+		return report, nil
+	}
+
+	upstream, err := r.RemoteRef(branch)
+	if err != nil {
+		addError("upstream: %v", err)
+		return report, nil
+	}
+
+	// Without a remote branch there is nothing to be ahead of or behind
+	if !r.RefExists(upstream) {
+		return report, nil
+	}
+	report.Upstream = upstream
+
+	if _, ahead, err := r.IsAheadOfRemote(branch); err != nil {
+		addError("%v", err)
+	} else {
+		report.Ahead = ahead
+	}
+
+	if _, behind, err := r.IsBehindRemote(branch); err != nil {
+		addError("%v", err)
+	} else {
+		report.Behind = behind
+	}
+
+	// Conflicts are only possible when the remote has commits we don't
+	if report.Behind > 0 {
+		conflicts, err := r.CheckForConflicts(upstream)
+		if err != nil {
+			addError("conflict check: %v", err)
+		}
+		for _, conflict := range conflicts {
+			report.PredictedConflicts = append(report.PredictedConflicts, conflict.File)
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,125 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runGit runs a git command in dir and fails the test on error
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %s: %s", strings.Join(args, " "), output)
+	return strings.TrimSpace(string(output))
+}
+
+// commitFile writes a file and commits it
+func commitFile(t *testing.T, dir, name, content, message string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	runGit(t, dir, "add", name)
+	runGit(t, dir, "commit", "-q", "-m", message)
+}
+
+// newClonedRepo creates a remote repository with one commit on main and a
+// clone of it. It returns the remote's working copy and the clone.
+func newClonedRepo(t *testing.T) (string, string) {
+	t.Helper()
+	t.Setenv("GIT_AUTHOR_NAME", "Harbinger Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Harbinger Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote")
+	clone := filepath.Join(root, "clone")
+
+	require.NoError(t, os.MkdirAll(remote, 0755))
+	runGit(t, remote, "init", "-q", "-b", "main")
+	runGit(t, remote, "config", "receive.denyCurrentBranch", "ignore")
+	commitFile(t, remote, "shared.txt", "line 1\nline 2\nline 3\n", "initial")
+	runGit(t, root, "clone", "-q", remote, clone)
+
+	return remote, clone
+}
+
+func TestSyncReport_InSync(t *testing.T) {
+	_, clone := newClonedRepo(t)
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+
+	report, err := repo.SyncReport()
+	require.NoError(t, err)
+
+	assert.Equal(t, "main", report.Branch)
+	assert.Equal(t, "origin/main", report.Upstream)
+	assert.True(t, report.InSync())
+	assert.False(t, report.UncommittedChanges)
+	assert.Empty(t, report.InProgress)
+	assert.Empty(t, report.PredictedConflicts)
+	assert.Empty(t, report.Errors)
+}
+
+func TestSyncReport_AheadBehindWithConflicts(t *testing.T) {
+	remote, clone := newClonedRepo(t)
+
+	commitFile(t, remote, "shared.txt", "line 1\nremote change\nline 3\n", "remote edit")
+	commitFile(t, clone, "shared.txt", "line 1\nlocal change\nline 3\n", "local edit")
+	require.NoError(t, os.WriteFile(filepath.Join(clone, "untracked.txt"), []byte("wip"), 0644))
+
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+	require.NoError(t, repo.Fetch())
+
+	report, err := repo.SyncReport()
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, report.Ahead)
+	assert.Equal(t, 1, report.Behind)
+	assert.False(t, report.InSync())
+	assert.True(t, report.UncommittedChanges)
+	assert.Equal(t, []string{"shared.txt"}, report.PredictedConflicts)
+}
+
+func TestSyncReport_MergeInProgress(t *testing.T) {
+	remote, clone := newClonedRepo(t)
+
+	commitFile(t, remote, "shared.txt", "line 1\nremote change\nline 3\n", "remote edit")
+	commitFile(t, clone, "shared.txt", "line 1\nlocal change\nline 3\n", "local edit")
+
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+	require.NoError(t, repo.Fetch())
+
+	// The merge stops with a conflict and leaves MERGE_HEAD behind
+	cmd := exec.Command("git", "merge", "origin/main")
+	cmd.Dir = clone
+	assert.Error(t, cmd.Run())
+
+	operation, err := repo.InProgressOperation()
+	require.NoError(t, err)
+	assert.Equal(t, OperationMerge, operation)
+}
+
+func TestGetUpstream_NoUpstream(t *testing.T) {
+	_, clone := newClonedRepo(t)
+	runGit(t, clone, "checkout", "-q", "-b", "local-only")
+
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+
+	upstream, err := repo.GetUpstream("local-only")
+	require.NoError(t, err)
+	assert.Empty(t, upstream)
+}
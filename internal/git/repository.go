@@ -2,6 +2,7 @@ package git
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -12,13 +13,28 @@ import (
 )
 
 type Repository struct {
-	path string
+	// path is the top of the working tree
+	path      string
+	gitDir    string
+	commonDir string
 }
 
 func (r *Repository) Path() string {
 	return r.path
 }
 
+// GitDir returns the repository's git directory. In a linked worktree or a
+// submodule this is not <path>/.git.
+func (r *Repository) GitDir() string {
+	return r.gitDir
+}
+
+// CommonDir returns the git directory shared by all worktrees of the
+// repository, which holds refs, config and objects
+func (r *Repository) CommonDir() string {
+	return r.commonDir
+}
+
 func NewRepository(path string) (*Repository, error) {
 	// Validate input path
 	if path == "" {
@@ -37,14 +53,47 @@ func NewRepository(path string) (*Repository, error) {
 		return nil, fmt.Errorf("path does not exist: %s", absPath)
 	}
 
-	// Verify it's a git repository
-	cmd := exec.Command("git", "rev-parse", "--git-dir")
+	// Verify it's a git repository and find the top of its working tree,
+	// which may be above absPath. --path-format=absolute needs git 2.31, so
+	// the git directories are made absolute here instead.
+	cmd := exec.Command("git", "rev-parse", "--is-bare-repository", "--git-dir", "--git-common-dir")
 	cmd.Dir = absPath
-	if err := cmd.Run(); err != nil {
+	output, err := cmd.Output()
+	if err != nil {
 		return nil, fmt.Errorf("not a git repository: %w", err)
 	}
+	fields := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("unexpected rev-parse output: %q", output)
+	}
+	if fields[0] == "true" {
+		return nil, fmt.Errorf("cannot monitor a bare repository: %s", absPath)
+	}
 
-	return &Repository{path: absPath}, nil
+	cmd = exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = absPath
+	toplevel, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("not inside a working tree: %s", absPath)
+	}
+
+	return &Repository{
+		path:      strings.TrimSpace(string(toplevel)),
+		gitDir:    absolutePath(absPath, fields[1]),
+		commonDir: absolutePath(absPath, fields[2]),
+	}, nil
+}
+
+// absolutePath resolves a path git printed relative to dir, following
+// symlinks like --show-toplevel does
+func absolutePath(dir, path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
 }
 
 // validateBranchName validates that a branch name is safe to use in git commands
@@ -91,19 +140,39 @@ func (r *Repository) Fetch() error {
 }
 
 func (r *Repository) GetRemoteCommit(branch string) (string, error) {
-	if err := validateBranchName(branch); err != nil {
-		return "", fmt.Errorf("invalid branch name: %w", err)
+	ref, err := r.RemoteRef(branch)
+	if err != nil {
+		return "", err
+	}
+
+	commit, err := r.GetRefCommit(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to get remote commit: %w", err)
 	}
+	return commit, nil
+}
 
-	cmd := exec.Command("git", "rev-parse", fmt.Sprintf("origin/%s", branch))
+// GetRefCommit returns the commit a ref such as "upstream/main" points to
+func (r *Repository) GetRefCommit(ref string) (string, error) {
+	if err := validateBranchName(ref); err != nil {
+		return "", fmt.Errorf("invalid ref: %w", err)
+	}
+
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", ref+"^{commit}")
 	cmd.Dir = r.path
 	output, err := cmd.Output()
 	if err != nil {
-		return "", fmt.Errorf("failed to get remote commit: %w", err)
+		return "", fmt.Errorf("unknown revision %s: %w", ref, err)
 	}
 	return strings.TrimSpace(string(output)), nil
 }
 
+// RefExists reports whether ref resolves to a commit
+func (r *Repository) RefExists(ref string) bool {
+	_, err := r.GetRefCommit(ref)
+	return err == nil
+}
+
 func (r *Repository) GetLocalCommit(branch string) (string, error) {
 	if err := validateBranchName(branch); err != nil {
 		return "", fmt.Errorf("invalid branch name: %w", err)
@@ -132,8 +201,9 @@ func (r *Repository) CheckForConflicts(targetBranch string) ([]Conflict, error)
 		return nil, fmt.Errorf("failed to get current branch: %w", err)
 	}
 
-	// Try using git merge-tree (non-destructive)
-	cmd := exec.Command("git", "merge-tree", "--write-tree", "--name-only", currentBranch, targetBranch)
+	// Try using git merge-tree (non-destructive). It exits with 1 when the
+	// merge has conflicts and prints them in its machine-readable -z format.
+	cmd := exec.Command("git", r.mergeTreeArgs(currentBranch, targetBranch)...)
 	cmd.Dir = r.path
 
 	var stdout, stderr bytes.Buffer
@@ -148,22 +218,32 @@ func (r *Repository) CheckForConflicts(targetBranch string) ([]Conflict, error)
 			return r.checkConflictsWithDiff(targetBranch)
 		}
 
-		// Check for conflicts in the output
-		if strings.Contains(stdout.String(), "CONFLICT") {
-			return r.parseConflictsFromMergeTree(stdout.String())
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			conflicts, err := parseConflictsFromMergeTree(stdout.String())
+			if err != nil {
+				return nil, err
+			}
+			r.loadConflictPreviews(conflicts)
+			return conflicts, nil
 		}
 
-		return nil, fmt.Errorf("merge-tree failed: %w", err)
-	}
-
-	// Check output for conflicts
-	if strings.Contains(stdout.String(), "CONFLICT") {
-		return r.parseConflictsFromMergeTree(stdout.String())
+		return nil, fmt.Errorf("merge-tree failed: %w - %s", err, strings.TrimSpace(stderr.String()))
 	}
 
 	return nil, nil
 }
 
+// mergeTreeArgs builds a merge-tree command for two commits. Previews
+// include the base version unless a conflict style is configured.
+func (r *Repository) mergeTreeArgs(ours, theirs string) []string {
+	var args []string
+	if r.configValue("merge.conflictStyle") == "" {
+		args = append(args, "-c", "merge.conflictStyle=diff3")
+	}
+	return append(args, "merge-tree", "--write-tree", "-z", ours, theirs)
+}
+
 // checkConflictsWithDiff uses a diff-based approach for older git versions
 func (r *Repository) checkConflictsWithDiff(targetBranch string) ([]Conflict, error) {
 	// Get the merge base
@@ -225,8 +305,11 @@ func (r *Repository) checkConflictsWithDiff(targetBranch string) ([]Conflict, er
 		if !bytes.Equal(ourContent, theirContent) &&
 			(!bytes.Equal(ourContent, baseContent) && !bytes.Equal(theirContent, baseContent)) {
 			conflicts = append(conflicts, Conflict{
-				File:    file,
-				Content: fmt.Sprintf("Potential conflict in %s\n", file),
+				File:      file,
+				Content:   fmt.Sprintf("Potential conflict in %s\n", file),
+				Type:      ConflictContents,
+				Paths:     []string{file},
+				Predicted: true,
 			})
 		}
 	}
@@ -234,31 +317,6 @@ func (r *Repository) checkConflictsWithDiff(targetBranch string) ([]Conflict, er
 	return conflicts, nil
 }
 
-// parseConflictsFromMergeTree parses conflicts from git merge-tree output
-func (r *Repository) parseConflictsFromMergeTree(output string) ([]Conflict, error) {
-	var conflicts []Conflict
-	lines := strings.Split(output, "\n")
-
-	for _, line := range lines {
-		if strings.Contains(line, "CONFLICT") {
-			// Extract filename from conflict message
-			parts := strings.Fields(line)
-			for i, part := range parts {
-				if part == "in" && i+1 < len(parts) {
-					filename := parts[i+1]
-					conflicts = append(conflicts, Conflict{
-						File:    filename,
-						Content: line,
-					})
-					break
-				}
-			}
-		}
-	}
-
-	return conflicts, nil
-}
-
 func (r *Repository) getConflictedFiles() ([]Conflict, error) {
 	cmd := exec.Command("git", "diff", "--name-only", "--diff-filter=U")
 	cmd.Dir = r.path
@@ -317,11 +375,6 @@ func (r *Repository) GetConflictedFiles() ([]string, error) {
 	return files, nil
 }
 
-type Conflict struct {
-	File    string
-	Content string
-}
-
 // IsInSync checks if the local branch is in sync with the remote
 func (r *Repository) IsInSync(branch string) (bool, error) {
 	if err := validateBranchName(branch); err != nil {
@@ -338,15 +391,17 @@ func (r *Repository) IsInSync(branch string) (bool, error) {
 		return false, err
 	}
 
-	remoteCommit, err := r.GetRemoteCommit(branch)
+	ref, err := r.RemoteRef(branch)
 	if err != nil {
-		// If remote branch doesn't exist, we're in sync (nothing to sync with)
-		if strings.Contains(err.Error(), "unknown revision") {
-			return true, nil
-		}
 		return false, err
 	}
 
+	// If remote branch doesn't exist, we're in sync (nothing to sync with)
+	remoteCommit, err := r.GetRefCommit(ref)
+	if err != nil {
+		return true, nil
+	}
+
 	return localCommit == remoteCommit, nil
 }
 
@@ -356,24 +411,20 @@ func (r *Repository) IsBehindRemote(branch string) (bool, int, error) {
 		return false, 0, fmt.Errorf("invalid branch name: %w", err)
 	}
 
-	// Check how many commits we're behind
-	cmd := exec.Command("git", "rev-list", "--count", fmt.Sprintf("%s..origin/%s", branch, branch))
-	cmd.Dir = r.path
-	output, err := cmd.Output()
+	ref, err := r.RemoteRef(branch)
 	if err != nil {
-		// If the command fails, it might be because the remote branch doesn't exist
-		if strings.Contains(err.Error(), "unknown revision") {
-			return false, 0, nil
-		}
-		return false, 0, fmt.Errorf("failed to check if behind remote: %w", err)
+		return false, 0, err
 	}
 
-	count := 0
-	countStr := strings.TrimSpace(string(output))
-	if countStr != "" {
-		count, _ = strconv.Atoi(countStr)
+	// If the remote branch doesn't exist there is nothing to be behind
+	if !r.RefExists(ref) {
+		return false, 0, nil
 	}
 
+	count, err := r.CountCommits(branch, ref)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to check if behind remote: %w", err)
+	}
 	return count > 0, count, nil
 }
 
@@ -383,16 +434,37 @@ func (r *Repository) IsAheadOfRemote(branch string) (bool, int, error) {
 		return false, 0, fmt.Errorf("invalid branch name: %w", err)
 	}
 
-	// Check how many commits we're ahead
-	cmd := exec.Command("git", "rev-list", "--count", fmt.Sprintf("origin/%s..%s", branch, branch))
+	ref, err := r.RemoteRef(branch)
+	if err != nil {
+		return false, 0, err
+	}
+
+	// If the remote branch doesn't exist there is nothing to be ahead of
+	if !r.RefExists(ref) {
+		return false, 0, nil
+	}
+
+	count, err := r.CountCommits(ref, branch)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to check if ahead of remote: %w", err)
+	}
+	return count > 0, count, nil
+}
+
+// CountCommits returns the number of commits reachable from to but not from from
+func (r *Repository) CountCommits(from, to string) (int, error) {
+	if err := validateBranchName(from); err != nil {
+		return 0, fmt.Errorf("invalid ref: %w", err)
+	}
+	if err := validateBranchName(to); err != nil {
+		return 0, fmt.Errorf("invalid ref: %w", err)
+	}
+
+	cmd := exec.Command("git", "rev-list", "--count", fmt.Sprintf("%s..%s", from, to))
 	cmd.Dir = r.path
 	output, err := cmd.Output()
 	if err != nil {
-		// If the command fails, it might be because the remote branch doesn't exist
-		if strings.Contains(err.Error(), "unknown revision") {
-			return false, 0, nil
-		}
-		return false, 0, fmt.Errorf("failed to check if ahead of remote: %w", err)
+		return 0, fmt.Errorf("failed to count commits in %s..%s: %w", from, to, err)
 	}
 
 	count := 0
@@ -400,8 +472,7 @@ func (r *Repository) IsAheadOfRemote(branch string) (bool, int, error) {
 	if countStr != "" {
 		count, _ = strconv.Atoi(countStr)
 	}
-
-	return count > 0, count, nil
+	return count, nil
 }
 
 // HasUncommittedChanges checks if there are uncommitted changes
@@ -446,6 +517,19 @@ func (r *Repository) MergeFromRemote(remoteBranch string) error {
 		return fmt.Errorf("invalid remote branch name: %w", err)
 	}
 
+	ref, err := r.RemoteRef(remoteBranch)
+	if err != nil {
+		return err
+	}
+	return r.MergeRef(ref)
+}
+
+// MergeRef merges a ref such as "upstream/main" into the current branch
+func (r *Repository) MergeRef(ref string) error {
+	if err := validateBranchName(ref); err != nil {
+		return fmt.Errorf("invalid ref: %w", err)
+	}
+
 	// Check if we have uncommitted changes
 	hasChanges, err := r.HasUncommittedChanges()
 	if err != nil {
@@ -455,15 +539,14 @@ func (r *Repository) MergeFromRemote(remoteBranch string) error {
 		return fmt.Errorf("cannot merge: uncommitted changes in working directory")
 	}
 
-	// Merge from the remote branch
-	cmd := exec.Command("git", "merge", fmt.Sprintf("origin/%s", remoteBranch))
+	cmd := exec.Command("git", "merge", ref)
 	cmd.Dir = r.path
 
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to merge from origin/%s: %w - %s", remoteBranch, err, stderr.String())
+		return fmt.Errorf("failed to merge from %s: %w - %s", ref, err, stderr.String())
 	}
 
 	return nil
@@ -483,3 +566,142 @@ func (r *Repository) GetRemoteName(branch string) (string, error) {
 	}
 	return strings.TrimSpace(string(output)), nil
 }
+
+// configValue returns a git config value, or an empty string if it is not set
+func (r *Repository) configValue(key string) string {
+	cmd := exec.Command("git", "config", "--get", key)
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// GetUpstream returns the short name of the branch's upstream tracking ref
+// (for example "origin/main"), or an empty string if none is configured
+func (r *Repository) GetUpstream(branch string) (string, error) {
+	if err := validateBranchName(branch); err != nil {
+		return "", fmt.Errorf("invalid branch name: %w", err)
+	}
+
+	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "--symbolic-full-name", branch+"@{upstream}")
+	cmd.Dir = r.path
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		// Branches without tracking config, or that only exist on a remote,
+		// have no upstream
+		if strings.Contains(stderr.String(), "no upstream") || strings.Contains(stderr.String(), "no such branch") {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get upstream: %w - %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// RemoteRef returns the remote-tracking ref that branch is compared against.
+// This is the branch's upstream when one is configured, which may live on any
+// remote and have a different name from the local branch. Otherwise it is
+// <remote>/<branch> using branch.<name>.remote, or origin.
+func (r *Repository) RemoteRef(branch string) (string, error) {
+	return r.RemoteRefOn("", branch)
+}
+
+// RemoteRefOn is like RemoteRef but looks the branch up on the given remote
+// when remote is not empty. The upstream branch name from branch.<name>.merge
+// is kept, so branches tracking a differently named branch still resolve.
+func (r *Repository) RemoteRefOn(remote, branch string) (string, error) {
+	if err := validateBranchName(branch); err != nil {
+		return "", fmt.Errorf("invalid branch name: %w", err)
+	}
+
+	if remote == "" {
+		upstream, err := r.GetUpstream(branch)
+		if err != nil {
+			return "", err
+		}
+		if upstream != "" {
+			return upstream, nil
+		}
+
+		remote, err = r.GetRemoteName(branch)
+		if err != nil {
+			return "", err
+		}
+	} else if err := validateBranchName(remote); err != nil {
+		return "", fmt.Errorf("invalid remote name: %w", err)
+	}
+
+	return remote + "/" + r.upstreamBranchName(branch), nil
+}
+
+// upstreamBranchName returns the name of the branch that branch tracks on its
+// remote, which defaults to the local name
+func (r *Repository) upstreamBranchName(branch string) string {
+	cmd := exec.Command("git", "config", fmt.Sprintf("branch.%s.merge", branch))
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return branch
+	}
+
+	merge := strings.TrimPrefix(strings.TrimSpace(string(output)), "refs/heads/")
+	if merge == "" {
+		return branch
+	}
+	return merge
+}
+
+// Operations reported by InProgressOperation
+const (
+	OperationMerge      = "merge"
+	OperationRebase     = "rebase"
+	OperationCherryPick = "cherry-pick"
+	OperationRevert     = "revert"
+)
+
+// InProgressOperation reports which multi-step operation (merge, rebase,
+// cherry-pick or revert) is currently underway, or an empty string if none is
+func (r *Repository) InProgressOperation() (string, error) {
+	markers := []struct {
+		path      string
+		operation string
+	}{
+		{"rebase-merge", OperationRebase},
+		{"rebase-apply", OperationRebase},
+		{"CHERRY_PICK_HEAD", OperationCherryPick},
+		{"REVERT_HEAD", OperationRevert},
+		{"MERGE_HEAD", OperationMerge},
+	}
+
+	for _, marker := range markers {
+		path, err := r.gitPath(marker.path)
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(path); err == nil {
+			return marker.operation, nil
+		}
+	}
+	return "", nil
+}
+
+// gitPath resolves a path inside the git directory, honoring worktrees
+func (r *Repository) gitPath(name string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--git-path", name)
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve git path %s: %w", name, err)
+	}
+
+	path := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.path, path)
+	}
+	return path, nil
+}
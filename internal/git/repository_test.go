@@ -157,10 +157,10 @@ func TestNewRepository_ValidGitRepo(t *testing.T) {
 	repo, err := NewRepository(".")
 	require.NoError(t, err)
 	assert.NotNil(t, repo)
-	assert.NotEmpty(t, repo.Path)
+	assert.NotEmpty(t, repo.Path())
 
 	// Verify the path is absolute
-	assert.True(t, filepath.IsAbs(repo.Path))
+	assert.True(t, filepath.IsAbs(repo.Path()))
 }
 
 func TestGetCurrentBranch_ValidRepo(t *testing.T) {
@@ -245,52 +245,6 @@ func TestCheckForConflicts_InvalidBranch(t *testing.T) {
 	assert.Contains(t, err.Error(), "invalid target branch name")
 }
 
-func TestParseConflictsFromMergeTree(t *testing.T) {
-	repo := &Repository{Path: "."} // Just for testing the method
-
-	tests := []struct {
-		name          string
-		output        string
-		expectedCount int
-		expectedFiles []string
-	}{
-		{
-			name:          "no conflicts",
-			output:        "clean merge",
-			expectedCount: 0,
-		},
-		{
-			name:          "one conflict",
-			output:        "CONFLICT (content): Merge conflict in file1.txt",
-			expectedCount: 1,
-			expectedFiles: []string{"file1.txt"},
-		},
-		{
-			name: "multiple conflicts",
-			output: `CONFLICT (content): Merge conflict in file1.txt
-CONFLICT (content): Merge conflict in file2.js
-regular output line
-CONFLICT (add/add): Merge conflict in file3.go`,
-			expectedCount: 3,
-			expectedFiles: []string{"file1.txt", "file2.js", "file3.go"},
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			conflicts, err := repo.parseConflictsFromMergeTree(tt.output)
-			assert.NoError(t, err)
-			assert.Len(t, conflicts, tt.expectedCount)
-
-			for i, expectedFile := range tt.expectedFiles {
-				if i < len(conflicts) {
-					assert.Equal(t, expectedFile, conflicts[i].File)
-				}
-			}
-		})
-	}
-}
-
 func TestHasUncommittedChanges_CleanRepo(t *testing.T) {
 	repo, err := NewRepository(".")
 	require.NoError(t, err)
@@ -312,3 +266,72 @@ func TestConflictStruct(t *testing.T) {
 	assert.Equal(t, "test.txt", conflict.File)
 	assert.Equal(t, "test content", conflict.Content)
 }
+
+// newForkRepo returns a clone whose main branch tracks "trunk" on a remote
+// named "upstream", with no origin remote at all
+func newForkRepo(t *testing.T) (string, string) {
+	t.Helper()
+	remote, clone := newClonedRepo(t)
+	runGit(t, remote, "branch", "trunk")
+	runGit(t, clone, "remote", "rename", "origin", "upstream")
+	runGit(t, clone, "fetch", "-q", "upstream")
+	runGit(t, clone, "branch", "-q", "--set-upstream-to", "upstream/trunk", "main")
+	return remote, clone
+}
+
+func TestRemoteRef_UpstreamWithDifferentName(t *testing.T) {
+	_, clone := newForkRepo(t)
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+
+	ref, err := repo.RemoteRef("main")
+	require.NoError(t, err)
+	assert.Equal(t, "upstream/trunk", ref)
+}
+
+func TestRemoteRefOn_ExplicitRemote(t *testing.T) {
+	_, clone := newForkRepo(t)
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+
+	// The upstream branch name is kept when looking on another remote
+	ref, err := repo.RemoteRefOn("fork", "main")
+	require.NoError(t, err)
+	assert.Equal(t, "fork/trunk", ref)
+
+	// Branches without tracking config fall back to their own name
+	ref, err = repo.RemoteRefOn("fork", "feature")
+	require.NoError(t, err)
+	assert.Equal(t, "fork/feature", ref)
+
+	_, err = repo.RemoteRefOn("bad;remote", "main")
+	assert.Error(t, err)
+}
+
+func TestIsBehindRemote_NonOriginUpstream(t *testing.T) {
+	remote, clone := newForkRepo(t)
+	runGit(t, remote, "checkout", "-q", "trunk")
+	commitFile(t, remote, "trunk.txt", "trunk\n", "trunk change")
+
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+	require.NoError(t, repo.Fetch())
+
+	behind, count, err := repo.IsBehindRemote("main")
+	require.NoError(t, err)
+	assert.True(t, behind)
+	assert.Equal(t, 1, count)
+
+	ahead, _, err := repo.IsAheadOfRemote("main")
+	require.NoError(t, err)
+	assert.False(t, ahead)
+
+	inSync, err := repo.IsInSync("main")
+	require.NoError(t, err)
+	assert.False(t, inSync)
+
+	require.NoError(t, repo.MergeFromRemote("main"))
+	inSync, err = repo.IsInSync("main")
+	require.NoError(t, err)
+	assert.True(t, inSync)
+}
@@ -0,0 +1,96 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Stash is a stash entry created by harbinger. It is identified by its
+// commit, not its stash@{n} position, so it is found again even if other
+// entries are pushed on top of it and never confused with someone else's.
+type Stash struct {
+	OID     string
+	Message string
+}
+
+// StashPush stashes tracked and untracked changes and returns the new entry
+func (r *Repository) StashPush(message string) (*Stash, error) {
+	before, _ := r.git("rev-parse", "--verify", "--quiet", "refs/stash")
+
+	cmd := exec.Command("git", "stash", "push", "--include-untracked", "-m", message)
+	cmd.Dir = r.path
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to stash changes: %w - %s", err, stderr.String())
+	}
+
+	after, err := r.git("rev-parse", "--verify", "--quiet", "refs/stash")
+	if err != nil || after == before {
+		return nil, fmt.Errorf("no changes were stashed")
+	}
+	return &Stash{OID: after, Message: message}, nil
+}
+
+// StashRef returns the stash@{n} name of a stash entry, or an empty string if
+// it is no longer in the stash list
+func (r *Repository) StashRef(stash *Stash) (string, error) {
+	output, err := r.git("stash", "list", "--format=%H")
+	if err != nil {
+		return "", fmt.Errorf("failed to list stashes: %w", err)
+	}
+	for i, oid := range strings.Fields(output) {
+		if oid == stash.OID {
+			return "stash@{" + strconv.Itoa(i) + "}", nil
+		}
+	}
+	return "", nil
+}
+
+// StashRestore re-applies a stash entry, staged changes included where
+// possible, and drops it. If applying fails the entry is kept.
+func (r *Repository) StashRestore(stash *Stash) error {
+	err := r.stashApply(stash, true)
+	if err != nil {
+		// --index gives up before touching anything when the staged changes
+		// do not apply, so try again restoring only the working tree
+		if dirty, statusErr := r.HasUncommittedChanges(); statusErr == nil && !dirty {
+			err = r.stashApply(stash, false)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	ref, err := r.StashRef(stash)
+	if err != nil || ref == "" {
+		return err
+	}
+	if _, err := r.git("stash", "drop", "--quiet", ref); err != nil {
+		return fmt.Errorf("restored changes but failed to drop %s: %w", ref, err)
+	}
+	return nil
+}
+
+func (r *Repository) stashApply(stash *Stash, index bool) error {
+	args := []string{"stash", "apply", "--quiet"}
+	if index {
+		args = append(args, "--index")
+	}
+	cmd := exec.Command("git", append(args, stash.OID)...)
+	cmd.Dir = r.path
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to apply stash %s: %w - %s", shortCommit(stash.OID), err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStashPushRestore(t *testing.T) {
+	_, clone := newClonedRepo(t)
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(clone, "shared.txt"), []byte("line 1\nstaged\nline 3\n"), 0644))
+	runGit(t, clone, "add", "shared.txt")
+	require.NoError(t, os.WriteFile(filepath.Join(clone, "new.txt"), []byte("untracked\n"), 0644))
+
+	stash, err := repo.StashPush("harbinger test")
+	require.NoError(t, err)
+	assert.Empty(t, runGit(t, clone, "status", "--porcelain"))
+
+	// Someone else's stash on top must not get in the way
+	require.NoError(t, os.WriteFile(filepath.Join(clone, "other.txt"), []byte("other\n"), 0644))
+	runGit(t, clone, "stash", "push", "-q", "--include-untracked", "-m", "not ours")
+
+	ref, err := repo.StashRef(stash)
+	require.NoError(t, err)
+	assert.Equal(t, "stash@{1}", ref)
+
+	require.NoError(t, repo.StashRestore(stash))
+	assert.Equal(t, "M  shared.txt\n?? new.txt", runGit(t, clone, "status", "--porcelain"))
+
+	ref, err = repo.StashRef(stash)
+	require.NoError(t, err)
+	assert.Empty(t, ref, "restored stash should be dropped")
+	assert.Contains(t, runGit(t, clone, "stash", "list"), "not ours")
+}
+
+func TestStashPush_NothingToStash(t *testing.T) {
+	_, clone := newClonedRepo(t)
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+
+	_, err = repo.StashPush("harbinger test")
+	assert.Error(t, err)
+}
+
+func TestStashRestore_ConflictKeepsEntry(t *testing.T) {
+	_, clone := newClonedRepo(t)
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(clone, "shared.txt"), []byte("line 1\nmine\nline 3\n"), 0644))
+	stash, err := repo.StashPush("harbinger test")
+	require.NoError(t, err)
+
+	commitFile(t, clone, "shared.txt", "line 1\ntheirs\nline 3\n", "conflicting commit")
+
+	assert.Error(t, repo.StashRestore(stash))
+	ref, err := repo.StashRef(stash)
+	require.NoError(t, err)
+	assert.Equal(t, "stash@{0}", ref)
+}
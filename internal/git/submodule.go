@@ -0,0 +1,88 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Submodule is a submodule declared in .gitmodules
+type Submodule struct {
+	Name string
+	Path string
+	// Branch is the upstream branch to track, from submodule.<name>.branch
+	Branch string
+	// Recorded is the commit the superproject's HEAD points the submodule at
+	Recorded string
+}
+
+// SubmoduleDrift is how far a submodule's recorded commit is behind its upstream
+type SubmoduleDrift struct {
+	Submodule Submodule
+	Upstream  string
+	Behind    int
+}
+
+// Submodules lists the submodules declared in the repository's .gitmodules
+func (r *Repository) Submodules() ([]Submodule, error) {
+	cmd := exec.Command("git", "config", "--file", ".gitmodules", "-z", "--get-regexp", `^submodule\..*\.path$`)
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			// No .gitmodules or no submodules in it
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read .gitmodules: %w", err)
+	}
+
+	var submodules []Submodule
+	for _, entry := range strings.Split(string(output), "\x00") {
+		key, path, ok := strings.Cut(entry, "\n")
+		if !ok {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "submodule."), ".path")
+
+		sub := Submodule{Name: name, Path: path}
+		sub.Branch, _ = r.git("config", "--file", ".gitmodules", "submodule."+name+".branch")
+		// The gitlink recorded in HEAD's tree
+		sub.Recorded, _ = r.git("rev-parse", "--verify", "--quiet", "HEAD:"+path)
+		submodules = append(submodules, sub)
+	}
+	return submodules, nil
+}
+
+// CheckSubmoduleDrift fetches a checked out submodule and counts the commits
+// its upstream branch has that the superproject does not record yet. The
+// upstream is the configured branch on origin, or origin's default branch.
+func (r *Repository) CheckSubmoduleDrift(sub Submodule) (*SubmoduleDrift, error) {
+	if sub.Recorded == "" {
+		return nil, fmt.Errorf("submodule %s is not recorded in HEAD", sub.Name)
+	}
+
+	subRepo, err := NewRepository(filepath.Join(r.path, sub.Path))
+	if err != nil || subRepo.Path() != filepath.Join(r.path, sub.Path) {
+		return nil, fmt.Errorf("submodule %s is not checked out", sub.Name)
+	}
+
+	if _, err := subRepo.git("fetch", "--quiet", "origin"); err != nil {
+		return nil, fmt.Errorf("failed to fetch submodule %s: %w", sub.Name, err)
+	}
+
+	upstream := "origin/" + sub.Branch
+	if sub.Branch == "" || sub.Branch == "." {
+		head, err := subRepo.git("symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD")
+		if err != nil {
+			return nil, fmt.Errorf("submodule %s has no branch configured and origin/HEAD is unknown", sub.Name)
+		}
+		upstream = head
+	}
+
+	behind, err := subRepo.CountCommits(sub.Recorded, upstream)
+	if err != nil {
+		return nil, fmt.Errorf("submodule %s: %w", sub.Name, err)
+	}
+	return &SubmoduleDrift{Submodule: sub, Upstream: upstream, Behind: behind}, nil
+}
@@ -0,0 +1,88 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSuperproject creates a library repository and a superproject with the
+// library added as a submodule at lib. It returns the library and the
+// superproject.
+func newSuperproject(t *testing.T) (string, string) {
+	t.Helper()
+	library, super := newClonedRepo(t)
+
+	runGit(t, super, "-c", "protocol.file.allow=always", "submodule", "add", "-q", "-b", "main", library, "lib")
+	runGit(t, super, "commit", "-q", "-m", "add lib")
+	return library, super
+}
+
+func TestSubmodules(t *testing.T) {
+	_, super := newSuperproject(t)
+
+	repo, err := NewRepository(super)
+	require.NoError(t, err)
+	submodules, err := repo.Submodules()
+	require.NoError(t, err)
+	require.Len(t, submodules, 1)
+	assert.Equal(t, "lib", submodules[0].Name)
+	assert.Equal(t, "lib", submodules[0].Path)
+	assert.Equal(t, "main", submodules[0].Branch)
+	assert.Equal(t, runGit(t, filepath.Join(super, "lib"), "rev-parse", "HEAD"), submodules[0].Recorded)
+
+	// The submodule's .git is a file pointing into the superproject
+	libRepo, err := NewRepository(filepath.Join(super, "lib"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(repo.GitDir(), "modules", "lib"), libRepo.GitDir())
+}
+
+func TestSubmodules_None(t *testing.T) {
+	_, clone := newClonedRepo(t)
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+
+	submodules, err := repo.Submodules()
+	require.NoError(t, err)
+	assert.Empty(t, submodules)
+}
+
+func TestCheckSubmoduleDrift(t *testing.T) {
+	library, super := newSuperproject(t)
+	repo, err := NewRepository(super)
+	require.NoError(t, err)
+	submodules, err := repo.Submodules()
+	require.NoError(t, err)
+	require.Len(t, submodules, 1)
+
+	drift, err := repo.CheckSubmoduleDrift(submodules[0])
+	require.NoError(t, err)
+	assert.Equal(t, 0, drift.Behind)
+	assert.Equal(t, "origin/main", drift.Upstream)
+
+	commitFile(t, library, "new.txt", "one\n", "library 1")
+	commitFile(t, library, "new.txt", "two\n", "library 2")
+
+	drift, err = repo.CheckSubmoduleDrift(submodules[0])
+	require.NoError(t, err)
+	assert.Equal(t, 2, drift.Behind)
+}
+
+func TestCheckSubmoduleDrift_NotCheckedOut(t *testing.T) {
+	_, super := newSuperproject(t)
+	repo, err := NewRepository(super)
+	require.NoError(t, err)
+	submodules, err := repo.Submodules()
+	require.NoError(t, err)
+
+	runGit(t, super, "submodule", "deinit", "-q", "--force", "lib")
+	entries, err := os.ReadDir(filepath.Join(super, "lib"))
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	_, err = repo.CheckSubmoduleDrift(submodules[0])
+	assert.ErrorContains(t, err, "not checked out")
+}
@@ -0,0 +1,182 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Strategies for bringing a branch up to date with its remote
+const (
+	SyncMerge  = "merge"
+	SyncRebase = "rebase"
+	SyncFFOnly = "ff-only"
+)
+
+// ParseSyncStrategy validates a sync_strategy value, defaulting to merge
+func ParseSyncStrategy(strategy string) (string, error) {
+	switch strategy {
+	case "":
+		return SyncMerge, nil
+	case SyncMerge, SyncRebase, SyncFFOnly:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown sync strategy %q (expected merge, rebase or ff-only)", strategy)
+	}
+}
+
+// FastForward moves the current branch to ref, failing if it has diverged
+func (r *Repository) FastForward(ref string) error {
+	if err := validateBranchName(ref); err != nil {
+		return fmt.Errorf("invalid ref: %w", err)
+	}
+
+	hasChanges, err := r.HasUncommittedChanges()
+	if err != nil {
+		return err
+	}
+	if hasChanges {
+		return fmt.Errorf("cannot fast-forward: uncommitted changes in working directory")
+	}
+
+	cmd := exec.Command("git", "merge", "--ff-only", ref)
+	cmd.Dir = r.path
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to fast-forward to %s: %w - %s", ref, err, stderr.String())
+	}
+
+	return nil
+}
+
+// Rebase replays the current branch's commits on top of onto. If the rebase
+// stops, it is aborted so the branch is left as it was.
+func (r *Repository) Rebase(onto string) error {
+	if err := validateBranchName(onto); err != nil {
+		return fmt.Errorf("invalid ref: %w", err)
+	}
+
+	hasChanges, err := r.HasUncommittedChanges()
+	if err != nil {
+		return err
+	}
+	if hasChanges {
+		return fmt.Errorf("cannot rebase: uncommitted changes in working directory")
+	}
+
+	cmd := exec.Command("git", "rebase", onto)
+	cmd.Dir = r.path
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		abort := exec.Command("git", "rebase", "--abort")
+		abort.Dir = r.path
+		if abortErr := abort.Run(); abortErr != nil {
+			return fmt.Errorf("failed to rebase onto %s: %w - %s (abort also failed: %v)", onto, err, stderr.String(), abortErr)
+		}
+		return fmt.Errorf("failed to rebase onto %s, rebase aborted: %w - %s", onto, err, stderr.String())
+	}
+
+	return nil
+}
+
+// RebasePreflight predicts whether rebasing the current branch onto onto
+// applies cleanly, without touching the working tree, index or refs. Each
+// commit rebase would replay is merged in turn with merge-tree onto the
+// previous result, using its parent as the merge base. It returns the
+// conflicts of the first commit that would stop the rebase.
+func (r *Repository) RebasePreflight(onto string) ([]Conflict, error) {
+	if err := validateBranchName(onto); err != nil {
+		return nil, fmt.Errorf("invalid ref: %w", err)
+	}
+
+	// The same commits rebase picks: no merges, and none already upstream
+	output, err := r.git("rev-list", "--reverse", "--no-merges", "--right-only", "--cherry-pick", onto+"...HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits to rebase: %w", err)
+	}
+	commits := strings.Fields(output)
+
+	tree, err := r.git("rev-parse", onto+"^{tree}")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", onto, err)
+	}
+
+	for _, commit := range commits {
+		parent, err := r.git("rev-parse", "--verify", "--quiet", commit+"^")
+		if err != nil {
+			return nil, fmt.Errorf("cannot preflight rebase of root commit %s", shortCommit(commit))
+		}
+
+		// A throwaway commit holding the rebased tree so far, with the
+		// replayed commit's parent as its parent. Merging the replayed commit
+		// into it uses that parent as the base, exactly like a cherry-pick.
+		current, err := r.syntheticCommit(tree, parent)
+		if err != nil {
+			return nil, err
+		}
+
+		cmd := exec.Command("git", r.mergeTreeArgs(current, commit)...)
+		cmd.Dir = r.path
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		result, err := cmd.Output()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			conflicts, err := parseConflictsFromMergeTree(string(result))
+			if err != nil {
+				return nil, err
+			}
+			r.loadConflictPreviews(conflicts)
+			for i := range conflicts {
+				conflicts[i].Message = fmt.Sprintf("%s (replaying %s)", conflicts[i].Message, shortCommit(commit))
+			}
+			return conflicts, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to simulate replaying %s: %w - %s", shortCommit(commit), err, stderr.String())
+		}
+
+		tree = strings.SplitN(string(result), "\x00", 2)[0]
+	}
+
+	return nil, nil
+}
+
+// syntheticCommit writes a commit object for tree with the given parent
+// without updating any ref. Unreferenced, it is pruned by git gc.
+func (r *Repository) syntheticCommit(tree, parent string) (string, error) {
+	cmd := exec.Command("git", "commit-tree", tree, "-p", parent, "-m", "harbinger rebase preflight")
+	cmd.Dir = r.path
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=harbinger", "GIT_AUTHOR_EMAIL=harbinger@localhost",
+		"GIT_COMMITTER_NAME=harbinger", "GIT_COMMITTER_EMAIL=harbinger@localhost",
+	)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to create preflight commit: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// git runs a git command in the repository and returns its trimmed output
+func (r *Repository) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	return strings.TrimSpace(string(output)), err
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
@@ -0,0 +1,111 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSyncStrategy(t *testing.T) {
+	strategy, err := ParseSyncStrategy("")
+	require.NoError(t, err)
+	assert.Equal(t, SyncMerge, strategy)
+
+	for _, name := range []string{SyncMerge, SyncRebase, SyncFFOnly} {
+		strategy, err := ParseSyncStrategy(name)
+		require.NoError(t, err)
+		assert.Equal(t, name, strategy)
+	}
+
+	_, err = ParseSyncStrategy("squash")
+	assert.Error(t, err)
+}
+
+func TestRebasePreflight_Clean(t *testing.T) {
+	remote, clone := newClonedRepo(t)
+	commitFile(t, remote, "remote.txt", "remote\n", "remote file")
+	commitFile(t, clone, "local.txt", "one\n", "local 1")
+	commitFile(t, clone, "local.txt", "two\n", "local 2")
+
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+	require.NoError(t, repo.Fetch())
+	before := runGit(t, clone, "rev-parse", "HEAD")
+
+	conflicts, err := repo.RebasePreflight("origin/main")
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+	assert.Equal(t, before, runGit(t, clone, "rev-parse", "HEAD"), "preflight must not move HEAD")
+
+	require.NoError(t, repo.Rebase("origin/main"))
+	assert.Equal(t, "2", runGit(t, clone, "rev-list", "--count", "origin/main..HEAD"))
+	assert.Equal(t, "0", runGit(t, clone, "rev-list", "--count", "--merges", "HEAD"))
+}
+
+func TestRebasePreflight_ConflictOnlyWhenReplayed(t *testing.T) {
+	remote, clone := newClonedRepo(t)
+	commitFile(t, remote, "shared.txt", "line 1\nremote change\nline 3\n", "remote edit")
+
+	// The second commit undoes the first, so a merge is clean but replaying
+	// the first commit on top of the remote conflicts
+	commitFile(t, clone, "shared.txt", "line 1\nlocal change\nline 3\n", "local edit")
+	commitFile(t, clone, "shared.txt", "line 1\nline 2\nline 3\n", "revert local edit")
+
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+	require.NoError(t, repo.Fetch())
+
+	mergeConflicts, err := repo.CheckForConflicts("origin/main")
+	require.NoError(t, err)
+	assert.Empty(t, mergeConflicts)
+
+	conflicts, err := repo.RebasePreflight("origin/main")
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "shared.txt", conflicts[0].File)
+	assert.Equal(t, ConflictContents, conflicts[0].Type)
+	assert.True(t, conflicts[0].Predicted)
+	assert.Contains(t, conflicts[0].Content, "local change")
+	assert.Contains(t, conflicts[0].Content, "remote change")
+
+	status := runGit(t, clone, "status", "--porcelain")
+	assert.Empty(t, status, "preflight must not touch the working tree")
+
+	assert.Error(t, repo.Rebase("origin/main"), "the real rebase stops where the preflight said")
+}
+
+func TestRebase_AbortsOnConflict(t *testing.T) {
+	remote, clone := newClonedRepo(t)
+	commitFile(t, remote, "shared.txt", "line 1\nremote change\nline 3\n", "remote edit")
+	commitFile(t, clone, "shared.txt", "line 1\nlocal change\nline 3\n", "local edit")
+
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+	require.NoError(t, repo.Fetch())
+	before := runGit(t, clone, "rev-parse", "HEAD")
+
+	assert.Error(t, repo.Rebase("origin/main"))
+	assert.Equal(t, before, runGit(t, clone, "rev-parse", "HEAD"))
+
+	operation, err := repo.InProgressOperation()
+	require.NoError(t, err)
+	assert.Empty(t, operation, "failed rebase should be aborted")
+}
+
+func TestFastForward(t *testing.T) {
+	remote, clone := newClonedRepo(t)
+	commitFile(t, remote, "remote.txt", "remote\n", "remote file")
+
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+	require.NoError(t, repo.Fetch())
+
+	require.NoError(t, repo.FastForward("origin/main"))
+	assert.Equal(t, runGit(t, clone, "rev-parse", "origin/main"), runGit(t, clone, "rev-parse", "HEAD"))
+
+	commitFile(t, remote, "remote.txt", "remote 2\n", "remote edit")
+	commitFile(t, clone, "local.txt", "local\n", "local file")
+	require.NoError(t, repo.Fetch())
+	assert.Error(t, repo.FastForward("origin/main"), "diverged branches cannot fast-forward")
+}
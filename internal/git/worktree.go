@@ -0,0 +1,63 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Worktree is one working tree of a repository, from "git worktree list"
+type Worktree struct {
+	Path     string
+	Head     string
+	Branch   string // Empty when HEAD is detached
+	Bare     bool
+	Prunable bool // The worktree's directory is gone
+}
+
+// Worktrees lists the main working tree and every linked worktree of the repository
+func (r *Repository) Worktrees() ([]Worktree, error) {
+	cmd := exec.Command("git", "worktree", "list", "--porcelain", "-z")
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	return parseWorktrees(string(output)), nil
+}
+
+// parseWorktrees parses "git worktree list --porcelain -z" output: one
+// NUL-terminated "key value" field per attribute, with an empty field after
+// each worktree
+func parseWorktrees(output string) []Worktree {
+	var worktrees []Worktree
+	var current *Worktree
+	for _, field := range strings.Split(output, "\x00") {
+		if field == "" {
+			current = nil
+			continue
+		}
+
+		key, value, _ := strings.Cut(field, " ")
+		if key == "worktree" {
+			worktrees = append(worktrees, Worktree{Path: value})
+			current = &worktrees[len(worktrees)-1]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		switch key {
+		case "HEAD":
+			current.Head = value
+		case "branch":
+			current.Branch = strings.TrimPrefix(value, "refs/heads/")
+		case "bare":
+			current.Bare = true
+		case "prunable":
+			current.Prunable = true
+		}
+	}
+	return worktrees
+}
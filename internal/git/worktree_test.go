@@ -0,0 +1,70 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWorktrees(t *testing.T) {
+	output := "worktree /src/app\x00HEAD 1111111111111111111111111111111111111111\x00branch refs/heads/main\x00\x00" +
+		"worktree /src/app-fix\x00HEAD 2222222222222222222222222222222222222222\x00detached\x00\x00" +
+		"worktree /tmp/gone\x00HEAD 3333333333333333333333333333333333333333\x00branch refs/heads/old\x00prunable gitdir file points to non-existent location\x00\x00"
+
+	worktrees := parseWorktrees(output)
+	require.Len(t, worktrees, 3)
+
+	assert.Equal(t, Worktree{Path: "/src/app", Head: "1111111111111111111111111111111111111111", Branch: "main"}, worktrees[0])
+	assert.Equal(t, "/src/app-fix", worktrees[1].Path)
+	assert.Empty(t, worktrees[1].Branch)
+	assert.True(t, worktrees[2].Prunable)
+
+	bare := parseWorktrees("worktree /src/app.git\x00bare\x00\x00")
+	require.Len(t, bare, 1)
+	assert.True(t, bare[0].Bare)
+}
+
+func TestWorktrees_LinkedWorktree(t *testing.T) {
+	_, clone := newClonedRepo(t)
+	linked := filepath.Join(filepath.Dir(clone), "linked")
+	runGit(t, clone, "worktree", "add", "-q", "-b", "feature", linked)
+
+	repo, err := NewRepository(clone)
+	require.NoError(t, err)
+	worktrees, err := repo.Worktrees()
+	require.NoError(t, err)
+	require.Len(t, worktrees, 2)
+	assert.Equal(t, "main", worktrees[0].Branch)
+	assert.Equal(t, "feature", worktrees[1].Branch)
+
+	// .git is a file in a linked worktree, so its git dir is elsewhere
+	info, err := os.Stat(filepath.Join(linked, ".git"))
+	require.NoError(t, err)
+	assert.False(t, info.IsDir())
+
+	linkedRepo, err := NewRepository(linked)
+	require.NoError(t, err)
+	assert.Equal(t, worktrees[1].Path, linkedRepo.Path())
+	assert.NotEqual(t, linkedRepo.CommonDir(), linkedRepo.GitDir())
+	assert.Equal(t, repo.CommonDir(), linkedRepo.CommonDir())
+
+	branch, err := linkedRepo.GetCurrentBranch()
+	require.NoError(t, err)
+	assert.Equal(t, "feature", branch)
+}
+
+func TestNewRepository_Subdirectory(t *testing.T) {
+	_, clone := newClonedRepo(t)
+	commitFile(t, clone, "nested/dir/file.txt", "content\n", "nested file")
+
+	top, err := NewRepository(clone)
+	require.NoError(t, err)
+	repo, err := NewRepository(filepath.Join(clone, "nested", "dir"))
+	require.NoError(t, err)
+	assert.Equal(t, top.Path(), repo.Path())
+	assert.Equal(t, filepath.Join(repo.Path(), ".git"), repo.GitDir())
+	assert.Equal(t, repo.GitDir(), repo.CommonDir())
+}
@@ -0,0 +1,226 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/javanhut/harbinger/internal/events"
+	"github.com/javanhut/harbinger/pkg/config"
+)
+
+const (
+	// DefaultTimeout stops hook commands when hook_timeout is not set
+	DefaultTimeout = time.Minute
+	// DefaultConcurrency is how many hooks run at once when hook_concurrency
+	// is not set
+	DefaultConcurrency = 4
+	// maxQueued is how many hooks may wait for a running one to finish;
+	// further ones are dropped, so a slow hook cannot pile up every poll
+	maxQueued = 32
+	// maxOutputLines is how much of a hook's output is logged
+	maxOutputLines = 20
+)
+
+// Options configures a Runner
+type Options struct {
+	// Commands are the shell commands to run, by event kind
+	Commands    map[string][]string
+	Timeout     time.Duration
+	Concurrency int
+}
+
+// OptionsFromConfig checks the hooks, hook_timeout and hook_concurrency config
+func OptionsFromConfig(cfg *config.Config) (Options, error) {
+	known := map[string]bool{}
+	for _, kind := range events.Kinds() {
+		known[kind] = true
+	}
+	for kind := range cfg.Hooks {
+		if !known[kind] {
+			return Options{}, fmt.Errorf("hooks: unknown event %q", kind)
+		}
+	}
+	return Options{
+		Commands:    cfg.Hooks,
+		Timeout:     time.Duration(cfg.HookTimeout),
+		Concurrency: cfg.HookConcurrency,
+	}, nil
+}
+
+// Runner runs the hook commands for the events it handles. Each command runs
+// through the shell in the event's repository, with the event as JSON on
+// stdin and its fields in HARBINGER_EVENT_* environment variables.
+type Runner struct {
+	mu       sync.Mutex
+	commands map[string][]string
+	timeout  time.Duration
+	slots    chan struct{}
+	// queued counts the hooks started or waiting to start
+	queued  int
+	running sync.WaitGroup
+}
+
+// NewRunner creates a runner; a zero timeout or concurrency uses the default
+func NewRunner(options Options) *Runner {
+	r := &Runner{}
+	r.Configure(options)
+	return r
+}
+
+// Configure replaces the commands and limits, e.g. after the config was
+// reloaded. Hooks already running are not affected.
+func (r *Runner) Configure(options Options) {
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = DefaultConcurrency
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = options.Commands
+	r.timeout = options.Timeout
+	if r.slots == nil || cap(r.slots) != options.Concurrency {
+		r.slots = make(chan struct{}, options.Concurrency)
+	}
+}
+
+// Handle starts the hooks for an event in the background. Hooks over the
+// concurrency limit wait for a running one to finish, and are dropped when
+// too many are waiting already.
+func (r *Runner) Handle(event events.Event) {
+	r.mu.Lock()
+	commands, timeout, slots := r.commands[event.Kind()], r.timeout, r.slots
+	r.mu.Unlock()
+	if len(commands) == 0 {
+		return
+	}
+
+	data, err := events.Data(event)
+	if err != nil {
+		log.Printf("[%s] Warning: cannot run %s hooks: %v", time.Now().Format(time.RFC3339), event.Kind(), err)
+		return
+	}
+	for _, command := range commands {
+		if !r.enqueue(slots) {
+			log.Printf("[%s] Warning: dropped hook %q for %s: %d hook(s) already waiting", time.Now().Format(time.RFC3339), command, event.Kind(), maxQueued)
+			continue
+		}
+		r.running.Add(1)
+		go func(command string) {
+			defer r.running.Done()
+			defer r.dequeue()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			r.run(command, timeout, event, data)
+		}(command)
+	}
+}
+
+// enqueue reserves room for a hook, unless maxQueued hooks already wait for
+// one of the slots
+func (r *Runner) enqueue(slots chan struct{}) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.queued >= cap(slots)+maxQueued {
+		return false
+	}
+	r.queued++
+	return true
+}
+
+func (r *Runner) dequeue() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queued--
+}
+
+// Wait blocks until every hook started so far has finished
+func (r *Runner) Wait() {
+	r.running.Wait()
+}
+
+func (r *Runner) run(command string, timeout time.Duration, event events.Event, data map[string]interface{}) {
+	input, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("[%s] Warning: cannot run %s hook %q: %v", time.Now().Format(time.RFC3339), event.Kind(), command, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := shellCommand(ctx, command)
+	cmd.Dir = event.Metadata().Repository
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(), environment(data)...)
+	// Don't wait on children of the shell that keep its output open
+	cmd.WaitDelay = time.Second
+
+	started := time.Now()
+	output, err := cmd.CombinedOutput()
+	elapsed := time.Since(started).Round(time.Millisecond)
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		log.Printf("[%s] Hook %q for %s timed out after %s", time.Now().Format(time.RFC3339), command, event.Kind(), timeout)
+	case err != nil:
+		log.Printf("[%s] Hook %q for %s failed after %s: %v", time.Now().Format(time.RFC3339), command, event.Kind(), elapsed, err)
+	default:
+		log.Printf("[%s] Hook %q for %s finished in %s", time.Now().Format(time.RFC3339), command, event.Kind(), elapsed)
+	}
+	logOutput(output)
+}
+
+// shellCommand runs command through the platform's shell
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// environment returns HARBINGER_EVENT_<FIELD> variables for the event data.
+// Lists are comma-separated.
+func environment(data map[string]interface{}) []string {
+	var env []string
+	for key, value := range data {
+		if list, ok := value.([]interface{}); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = fmt.Sprint(item)
+			}
+			value = strings.Join(items, ",")
+		}
+		env = append(env, fmt.Sprintf("HARBINGER_EVENT_%s=%v", strings.ToUpper(key), value))
+	}
+	sort.Strings(env)
+	return env
+}
+
+// logOutput logs the last lines a hook wrote
+func logOutput(output []byte) {
+	text := strings.TrimRight(string(output), "\n")
+	if text == "" {
+		return
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) > maxOutputLines {
+		log.Printf("[%s]   ... %d earlier line(s) omitted", time.Now().Format(time.RFC3339), len(lines)-maxOutputLines)
+		lines = lines[len(lines)-maxOutputLines:]
+	}
+	for _, line := range lines {
+		log.Printf("[%s]   %s", time.Now().Format(time.RFC3339), line)
+	}
+}
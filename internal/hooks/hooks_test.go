@@ -0,0 +1,148 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/javanhut/harbinger/internal/events"
+	"github.com/javanhut/harbinger/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func skipWithoutShell(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the hooks in these tests are sh scripts")
+	}
+}
+
+// captureLog collects the log output of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func TestOptionsFromConfig(t *testing.T) {
+	options, err := OptionsFromConfig(&config.Config{
+		Hooks:           map[string][]string{events.KindAutoSyncSucceeded: {"make test"}},
+		HookTimeout:     config.Duration(time.Minute),
+		HookConcurrency: 2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"make test"}, options.Commands[events.KindAutoSyncSucceeded])
+	assert.Equal(t, 2, options.Concurrency)
+
+	_, err = OptionsFromConfig(&config.Config{Hooks: map[string][]string{"merged": {"make test"}}})
+	assert.ErrorContains(t, err, `unknown event "merged"`)
+}
+
+func TestKinds_MatchConfigSchema(t *testing.T) {
+	schema, err := config.Schema()
+	require.NoError(t, err)
+	for _, kind := range events.Kinds() {
+		assert.Contains(t, string(schema), `"`+kind+`"`, "hooks should accept %s", kind)
+	}
+}
+
+func TestRunner_PassesEventData(t *testing.T) {
+	skipWithoutShell(t)
+	repo := t.TempDir()
+	logged := captureLog(t)
+
+	runner := NewRunner(Options{Commands: map[string][]string{
+		events.KindConflictsPredicted: {`echo "$HARBINGER_EVENT_TYPE $HARBINGER_EVENT_BRANCH $HARBINGER_EVENT_FILES" > env.txt; cat > event.json; echo done`},
+	}})
+	runner.Handle(events.BehindRemote{Meta: events.Meta{Repository: repo}})
+	runner.Handle(events.ConflictsPredicted{
+		Meta:      events.Meta{Repository: repo, Branch: "main", Time: time.Now()},
+		RemoteRef: "origin/main",
+		Files:     []string{"a.go", "b.go"},
+	})
+	runner.Wait()
+
+	env, err := os.ReadFile(filepath.Join(repo, "env.txt"))
+	require.NoError(t, err, "the hook runs in the repository")
+	assert.Equal(t, "conflicts main a.go,b.go\n", string(env))
+
+	input, err := os.ReadFile(filepath.Join(repo, "event.json"))
+	require.NoError(t, err)
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal(input, &data))
+	assert.Equal(t, "origin/main", data["remote_ref"])
+	assert.Equal(t, []interface{}{"a.go", "b.go"}, data["files"])
+
+	assert.Contains(t, logged.String(), "for conflicts finished in")
+	assert.Contains(t, logged.String(), "  done")
+}
+
+func TestRunner_LogsFailuresAndTimeouts(t *testing.T) {
+	skipWithoutShell(t)
+	repo := t.TempDir()
+	logged := captureLog(t)
+
+	runner := NewRunner(Options{
+		Commands: map[string][]string{events.KindFetchFailed: {"echo broken >&2; exit 3", "sleep 5"}},
+		Timeout:  200 * time.Millisecond,
+	})
+	started := time.Now()
+	runner.Handle(events.FetchFailed{Meta: events.Meta{Repository: repo}, Err: errors.New("offline")})
+	runner.Wait()
+
+	assert.Less(t, time.Since(started), 4*time.Second)
+	assert.Contains(t, logged.String(), `Hook "echo broken >&2; exit 3" for fetch_failed failed after`)
+	assert.Contains(t, logged.String(), "exit status 3")
+	assert.Contains(t, logged.String(), "  broken")
+	assert.Contains(t, logged.String(), `Hook "sleep 5" for fetch_failed timed out after 200ms`)
+}
+
+func TestRunner_Concurrency(t *testing.T) {
+	skipWithoutShell(t)
+	repo := t.TempDir()
+	captureLog(t)
+
+	hook := "echo start >> order.txt; sleep 0.2; echo end >> order.txt"
+	runner := NewRunner(Options{
+		Commands:    map[string][]string{events.KindBecameInSync: {hook, hook, hook}},
+		Concurrency: 1,
+	})
+	runner.Handle(events.BecameInSync{Meta: events.Meta{Repository: repo}})
+	runner.Wait()
+
+	order, err := os.ReadFile(filepath.Join(repo, "order.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("start\nend\n", 3), string(order), "one hook at a time")
+}
+
+func TestRunner_DropsWhenQueueIsFull(t *testing.T) {
+	skipWithoutShell(t)
+	repo := t.TempDir()
+	logged := captureLog(t)
+
+	// Each hook waits until the test lets them finish
+	runner := NewRunner(Options{
+		Commands:    map[string][]string{events.KindBehindRemote: {"while [ ! -f done ]; do sleep 0.01; done; echo ran >> runs.txt"}},
+		Concurrency: 1,
+	})
+	for i := 0; i < maxQueued+5; i++ {
+		runner.Handle(events.BehindRemote{Meta: events.Meta{Repository: repo}})
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "done"), nil, 0644))
+	runner.Wait()
+
+	runs, err := os.ReadFile(filepath.Join(repo, "runs.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, maxQueued+1, strings.Count(string(runs), "ran"), "one running and maxQueued waiting")
+	assert.Equal(t, 4, strings.Count(logged.String(), "dropped hook"))
+}
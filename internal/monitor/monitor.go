@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/javanhut/harbinger/internal/conflict"
+	"github.com/javanhut/harbinger/internal/events"
 	"github.com/javanhut/harbinger/internal/git"
+	"github.com/javanhut/harbinger/internal/hooks"
 	"github.com/javanhut/harbinger/internal/notify"
 	"github.com/javanhut/harbinger/pkg/config"
 )
@@ -16,12 +18,34 @@ import (
 type Options struct {
 	PollInterval time.Duration
 	RemoteBranch string // Optional: specific remote branch to monitor
+	Remote       string // Optional: remote to compare against instead of the branch's upstream remote
+	Submodules   bool   // Also report submodules whose upstream has moved past the recorded commit
+	// Sinks receive notifications alongside the configured ones, e.g. a
+	// notify.Recorder in tests
+	Sinks []notify.Sink
+}
+
+// Status is a point-in-time snapshot of a monitor, used by the control socket
+type Status struct {
+	RepoPath     string
+	Branch       string
+	TargetBranch string
+	RemoteRef    string
+	PollInterval time.Duration
+	Paused       bool
+	Ignored      bool // The current branch matches ignore_branches
+	InSync       bool
+	RemoteCommit string
+	LastCheck    time.Time
+	LastError    string
 }
 
 type Monitor struct {
 	repo             *git.Repository
 	options          Options
 	notifier         *notify.Notifier
+	bus              *events.Bus
+	hooks            *hooks.Runner
 	config           *config.Config
 	ctx              context.Context
 	cancel           context.CancelFunc
@@ -29,7 +53,23 @@ type Monitor struct {
 	lastRemoteCommit string
 	lastSyncStatus   bool // Track if we were in sync last time
 	currentBranch    string
+	branchIgnored    bool   // The current branch matches ignore_branches, so checks are skipped
 	targetBranch     string // The remote branch we're monitoring
+	remoteRef        string // The remote-tracking ref the current branch is compared against
+	// Last reported number of commits each submodule is behind, by path
+	submoduleDrift map[string]int
+	// Detects edits to the config files so they are applied without a restart
+	watcher *config.Watcher
+
+	// Runtime control state, guarded by mu
+	mu         sync.Mutex
+	paused     bool
+	status     Status
+	checkNow   chan struct{}
+	intervalCh chan time.Duration
+	configCh   chan *config.Config
+	// The interval was not given explicitly, so it follows poll_interval
+	intervalFromConfig bool
 }
 
 func New(repoPath string, options Options) (*Monitor, error) {
@@ -38,12 +78,39 @@ func New(repoPath string, options Options) (*Monitor, error) {
 		return nil, fmt.Errorf("failed to initialize repository: %w", err)
 	}
 
-	cfg, err := config.Load()
+	// Watch before loading so an edit made in between is not missed
+	watcher := config.NewWatcher(config.Layers(repo.Path(), repo.CommonDir()))
+	cfg, err := loadConfig(repo)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load config: %w", err)
+		return nil, err
+	}
+
+	// An explicit interval (from a flag or the daemon) wins over poll_interval
+	intervalFromConfig := options.PollInterval <= 0
+	if intervalFromConfig {
+		interval, err := cfg.PollIntervalDuration()
+		if err != nil {
+			return nil, fmt.Errorf("invalid config: %w", err)
+		}
+		options.PollInterval = interval
 	}
 
-	notifier := notify.New()
+	// loadConfig has already checked the notifiers
+	notifyOptions, _ := notify.OptionsFromConfig(cfg)
+	notifyOptions.Repository = repo.Path()
+	notifyOptions.Sinks = append(notifyOptions.Sinks, options.Sinks...)
+	notifier := notify.NewWithOptions(notifyOptions)
+
+	// loadConfig has already checked the hooks too
+	hookOptions, _ := hooks.OptionsFromConfig(cfg)
+	runner := hooks.NewRunner(hookOptions)
+
+	// Events are logged, turned into notifications and run the hooks;
+	// others can Subscribe
+	bus := &events.Bus{}
+	bus.Subscribe(events.Log)
+	bus.Subscribe(notifier.Handle)
+	bus.Subscribe(runner.Handle)
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -51,13 +118,44 @@ func New(repoPath string, options Options) (*Monitor, error) {
 		repo:         repo,
 		options:      options,
 		notifier:     notifier,
+		bus:          bus,
+		hooks:        runner,
 		config:       cfg,
 		ctx:          ctx,
 		cancel:       cancel,
 		targetBranch: options.RemoteBranch,
+		status:       Status{RepoPath: repo.Path(), TargetBranch: options.RemoteBranch},
+		checkNow:     make(chan struct{}, 1),
+		intervalCh:   make(chan time.Duration, 1),
+		configCh:     make(chan *config.Config, 1),
+		watcher:      watcher,
+
+		intervalFromConfig: intervalFromConfig,
 	}, nil
 }
 
+// loadConfig reads the repository's config files, layered over the global
+// one, and checks that they are valid
+func loadConfig(repo *git.Repository) (*config.Config, error) {
+	cfg, err := config.LoadRepository(repo.Path(), repo.CommonDir())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if _, err := git.ParseSyncStrategy(cfg.SyncStrategy); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if _, err := notify.OptionsFromConfig(cfg); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if _, err := hooks.OptionsFromConfig(cfg); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
 func (m *Monitor) Start() error {
 	// Get initial state
 	branch, err := m.repo.GetCurrentBranch()
@@ -73,48 +171,45 @@ func (m *Monitor) Start() error {
 	}
 	log.Printf("[%s] Poll interval: %s", time.Now().Format(time.RFC3339), m.options.PollInterval)
 
+	if m.config.IsBranchIgnored(branch) {
+		m.branchIgnored = true
+		log.Printf("[%s] Branch '%s' matches ignore_branches, skipping checks until another branch is checked out", time.Now().Format(time.RFC3339), branch)
+		m.recordCheck(nil)
+
+		m.wg.Add(1)
+		go m.monitorLoop()
+		return nil
+	}
+
 	if err := m.repo.Fetch(); err != nil {
 		return fmt.Errorf("failed to fetch remote: %w", err)
 	}
 
-	// Determine which branch to compare against
-	compareBranch := branch
-	if m.targetBranch != "" {
-		compareBranch = m.targetBranch
+	remoteRef, err := m.resolveRemoteRef(branch)
+	if err != nil {
+		return fmt.Errorf("failed to resolve remote branch: %w", err)
 	}
+	m.remoteRef = remoteRef
 
-	remoteCommit, err := m.repo.GetRemoteCommit(compareBranch)
+	remoteCommit, err := m.repo.GetRefCommit(remoteRef)
 	if err != nil {
 		log.Printf("[%s] Warning: failed to get remote commit (branch might not have upstream): %v", time.Now().Format(time.RFC3339), err)
 	} else {
 		m.lastRemoteCommit = remoteCommit
-		log.Printf("[%s] Remote HEAD (%s): %s", time.Now().Format(time.RFC3339), compareBranch, remoteCommit[:8])
-	}
+		log.Printf("[%s] Remote HEAD (%s): %s", time.Now().Format(time.RFC3339), remoteRef, remoteCommit[:8])
 
-	// Check initial sync status
-	if m.targetBranch != "" {
-		// Get local commit for comparison
+		// Check initial sync status
 		localCommit, _ := m.repo.GetLocalCommit(branch)
 		m.lastSyncStatus = localCommit == remoteCommit
 		if m.lastSyncStatus {
-			log.Printf("[%s] Status: In sync with remote branch %s", time.Now().Format(time.RFC3339), compareBranch)
-		} else {
-			log.Printf("[%s] Status: Not in sync with remote branch %s", time.Now().Format(time.RFC3339), compareBranch)
-		}
-	} else {
-		inSync, err := m.repo.IsInSync(branch)
-		if err != nil {
-			log.Printf("[%s] Warning: unable to check initial sync status: %v", time.Now().Format(time.RFC3339), err)
+			log.Printf("[%s] Status: In sync with %s", time.Now().Format(time.RFC3339), remoteRef)
 		} else {
-			m.lastSyncStatus = inSync
-			if inSync {
-				log.Printf("[%s] Status: In sync with remote", time.Now().Format(time.RFC3339))
-			} else {
-				log.Printf("[%s] Status: Not in sync with remote", time.Now().Format(time.RFC3339))
-			}
+			log.Printf("[%s] Status: Not in sync with %s", time.Now().Format(time.RFC3339), remoteRef)
 		}
 	}
 
+	m.recordCheck(nil)
+
 	m.wg.Add(1)
 	go m.monitorLoop()
 
@@ -124,36 +219,222 @@ func (m *Monitor) Start() error {
 func (m *Monitor) Stop() error {
 	m.cancel()
 	m.wg.Wait()
+	// Send the notifications a digest or quiet hours were still holding back
+	m.notifier.Flush()
+	m.hooks.Wait()
 	return nil
 }
 
+// Status returns a snapshot of the monitor's most recent check
+func (m *Monitor) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := m.status
+	status.PollInterval = m.options.PollInterval
+	status.Paused = m.paused
+	return status
+}
+
+// Subscribe registers a handler for the events the monitor publishes and
+// returns a function that unsubscribes it. Handlers run on the monitor loop.
+func (m *Monitor) Subscribe(handler events.Handler) func() {
+	return m.bus.Subscribe(handler)
+}
+
+// meta returns the metadata for an event about the branch, happening now
+func (m *Monitor) meta(branch string) events.Meta {
+	return events.Meta{Repository: m.repo.Path(), Branch: branch, Time: time.Now()}
+}
+
+// CheckNow requests an immediate check, even while the monitor is paused
+func (m *Monitor) CheckNow() {
+	select {
+	case m.checkNow <- struct{}{}:
+	default:
+		// A check is already pending
+	}
+}
+
+// Pause suspends periodic checks until Resume is called
+func (m *Monitor) Pause() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.paused = true
+	log.Printf("[%s] Monitoring paused", time.Now().Format(time.RFC3339))
+}
+
+// Resume restarts periodic checks after Pause
+func (m *Monitor) Resume() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.paused = false
+	log.Printf("[%s] Monitoring resumed", time.Now().Format(time.RFC3339))
+}
+
+// SetPollInterval changes how often the monitor checks for remote changes.
+// The interval is kept when poll_interval is changed afterwards.
+func (m *Monitor) SetPollInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("poll interval must be positive, got %s", interval)
+	}
+
+	m.mu.Lock()
+	m.intervalFromConfig = false
+	m.mu.Unlock()
+
+	m.setPollInterval(interval)
+	return nil
+}
+
+// Reload re-reads the config files and switches to them before the next
+// check. An invalid config is reported and the current one is kept.
+func (m *Monitor) Reload() error {
+	cfg, err := loadConfig(m.repo)
+	if err != nil {
+		log.Printf("[%s] Keeping the current configuration: %v", time.Now().Format(time.RFC3339), err)
+		return err
+	}
+
+	// Holding m.mu makes the drain and send one step, so the send never
+	// waits for the loop, which may already have exited
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ctx.Err() != nil {
+		return fmt.Errorf("monitor is stopped")
+	}
+	// Replace any pending config that the loop has not picked up yet
+	select {
+	case <-m.configCh:
+	default:
+	}
+	m.configCh <- cfg
+	return nil
+}
+
+func (m *Monitor) setPollInterval(interval time.Duration) {
+	// Holding m.mu makes the drain and send one step, so the send never
+	// waits, even when called from the loop itself
+	m.mu.Lock()
+	m.options.PollInterval = interval
+	// Replace any pending interval change that the loop has not picked up yet
+	select {
+	case <-m.intervalCh:
+	default:
+	}
+	m.intervalCh <- interval
+	m.mu.Unlock()
+
+	log.Printf("[%s] Poll interval changed to %s", time.Now().Format(time.RFC3339), interval)
+}
+
+// applyConfig switches to a reloaded config, logging every changed key. It
+// runs on the monitor loop, so a check never sees a mix of old and new settings.
+func (m *Monitor) applyConfig(cfg *config.Config) {
+	changes := config.Changes(m.config, cfg)
+	m.config = cfg
+	if len(changes) == 0 {
+		log.Printf("[%s] Configuration reloaded, nothing changed", time.Now().Format(time.RFC3339))
+		return
+	}
+	for _, change := range changes {
+		log.Printf("[%s] Configuration changed: %s", time.Now().Format(time.RFC3339), change)
+	}
+
+	notifyOptions, _ := notify.OptionsFromConfig(cfg)
+	notifyOptions.Sinks = append(notifyOptions.Sinks, m.options.Sinks...)
+	m.notifier.Configure(notifyOptions)
+	hookOptions, _ := hooks.OptionsFromConfig(cfg)
+	m.hooks.Configure(hookOptions)
+
+	m.mu.Lock()
+	followConfig := m.intervalFromConfig
+	current := m.options.PollInterval
+	m.mu.Unlock()
+	if interval, err := cfg.PollIntervalDuration(); followConfig && err == nil && interval != current {
+		m.setPollInterval(interval)
+	}
+}
+
+// reloadIfChanged reloads the config when one of its files was edited
+func (m *Monitor) reloadIfChanged() {
+	if !m.watcher.Changed() {
+		return
+	}
+	log.Printf("[%s] Config file changed, reloading", time.Now().Format(time.RFC3339))
+	cfg, err := loadConfig(m.repo)
+	if err != nil {
+		log.Printf("[%s] Keeping the current configuration: %v", time.Now().Format(time.RFC3339), err)
+		return
+	}
+	m.applyConfig(cfg)
+}
+
+func (m *Monitor) isPaused() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.paused
+}
+
+// recordCheck stores the outcome of a check so Status can report it
+func (m *Monitor) recordCheck(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.status.Branch = m.currentBranch
+	m.status.Ignored = m.branchIgnored
+	m.status.RemoteRef = m.remoteRef
+	m.status.InSync = m.lastSyncStatus
+	m.status.RemoteCommit = m.lastRemoteCommit
+	m.status.LastCheck = time.Now()
+	m.status.LastError = ""
+	if err != nil {
+		m.status.LastError = err.Error()
+	}
+}
+
 func (m *Monitor) monitorLoop() {
 	defer m.wg.Done()
 
-	ticker := time.NewTicker(m.options.PollInterval)
+	m.mu.Lock()
+	interval := m.options.PollInterval
+	m.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
+	watchTicker := time.NewTicker(config.WatchInterval)
+	defer watchTicker.Stop()
 
 	for {
 		select {
 		case <-m.ctx.Done():
 			return
+		case interval := <-m.intervalCh:
+			ticker.Reset(interval)
+		case cfg := <-m.configCh:
+			m.applyConfig(cfg)
+		case <-watchTicker.C:
+			m.reloadIfChanged()
+		case <-m.checkNow:
+			m.runCheck()
 		case <-ticker.C:
-			if err := m.checkForChanges(); err != nil {
-				log.Printf("Error checking for changes: %v", err)
+			if m.isPaused() {
+				continue
 			}
+			m.runCheck()
 		}
 	}
 }
 
-func (m *Monitor) checkForChanges() error {
-	log.Printf("[%s] Checking for changes...", time.Now().Format(time.RFC3339))
-	
-	// Fetch latest changes
-	if err := m.repo.Fetch(); err != nil {
-		log.Printf("[%s] Error: Failed to fetch remote changes: %v", time.Now().Format(time.RFC3339), err)
-		return fmt.Errorf("failed to fetch: %w", err)
+func (m *Monitor) runCheck() {
+	err := m.checkForChanges()
+	if err != nil {
+		log.Printf("Error checking for changes: %v", err)
 	}
+	m.recordCheck(err)
+}
 
+func (m *Monitor) checkForChanges() error {
 	branch, err := m.repo.GetCurrentBranch()
 	if err != nil {
 		return fmt.Errorf("failed to get current branch: %w", err)
@@ -161,17 +442,38 @@ func (m *Monitor) checkForChanges() error {
 
 	// Check if we've switched branches
 	if m.currentBranch != "" && m.currentBranch != branch {
-		log.Printf("[%s] Branch switch detected: '%s' -> '%s'", time.Now().Format(time.RFC3339), m.currentBranch, branch)
+		m.bus.Publish(events.BranchSwitched{Meta: m.meta(branch), From: m.currentBranch})
 		m.lastRemoteCommit = "" // Reset tracking
 		m.lastSyncStatus = false
 	}
 	m.currentBranch = branch
 
-	// Determine which branch to compare against
-	compareBranch := branch
+	if m.config.IsBranchIgnored(branch) {
+		if !m.branchIgnored {
+			log.Printf("[%s] Branch '%s' matches ignore_branches, skipping checks", time.Now().Format(time.RFC3339), branch)
+		}
+		m.branchIgnored = true
+		m.remoteRef = ""
+		return nil
+	}
+	m.branchIgnored = false
+
+	log.Printf("[%s] Checking for changes...", time.Now().Format(time.RFC3339))
+
+	// Fetch latest changes
+	if err := m.repo.Fetch(); err != nil {
+		m.bus.Publish(events.FetchFailed{Meta: m.meta(branch), Err: err})
+		return fmt.Errorf("failed to fetch: %w", err)
+	}
+
+	// Determine which remote branch to compare against
+	remoteRef, err := m.resolveRemoteRef(branch)
+	if err != nil {
+		return fmt.Errorf("failed to resolve remote branch: %w", err)
+	}
+	m.remoteRef = remoteRef
 	if m.targetBranch != "" {
-		compareBranch = m.targetBranch
-		log.Printf("[%s] Comparing current branch '%s' against remote branch '%s'", time.Now().Format(time.RFC3339), branch, compareBranch)
+		log.Printf("[%s] Comparing current branch '%s' against remote branch '%s'", time.Now().Format(time.RFC3339), branch, remoteRef)
 	}
 
 	// Get current commits
@@ -182,27 +484,19 @@ func (m *Monitor) checkForChanges() error {
 		log.Printf("[%s] Local HEAD: %s", time.Now().Format(time.RFC3339), localCommit[:8])
 	}
 
-	remoteCommit, err := m.repo.GetRemoteCommit(compareBranch)
+	remoteCommit, err := m.repo.GetRefCommit(remoteRef)
 	if err != nil {
+		// Branch might not have upstream
 		log.Printf("[%s] Warning: unable to get remote commit: %v", time.Now().Format(time.RFC3339), err)
-	} else {
-		log.Printf("[%s] Remote HEAD (%s): %s", time.Now().Format(time.RFC3339), compareBranch, remoteCommit[:8])
+		return nil
 	}
-
-	// Check sync status - when monitoring a different branch, we check if local matches that remote branch
-	var inSync bool
-	if m.targetBranch != "" {
-		// Compare local branch against specified remote branch
-		inSync = localCommit == remoteCommit
-	} else {
-		// Normal sync check against same branch
-		inSync, err = m.repo.IsInSync(branch)
-		if err != nil {
-			// Branch might not have upstream
-			log.Printf("[%s] Warning: unable to check sync status: %v", time.Now().Format(time.RFC3339), err)
-			return nil
-		}
+	if m.lastRemoteCommit != "" && m.lastRemoteCommit != remoteCommit {
+		m.bus.Publish(events.RemoteAdvanced{Meta: m.meta(branch), RemoteRef: remoteRef, From: m.lastRemoteCommit, To: remoteCommit})
 	}
+	m.lastRemoteCommit = remoteCommit
+	log.Printf("[%s] Remote HEAD (%s): %s", time.Now().Format(time.RFC3339), remoteRef, remoteCommit[:8])
+
+	inSync := localCommit == remoteCommit
 
 	// Log sync status
 	if inSync {
@@ -211,42 +505,34 @@ func (m *Monitor) checkForChanges() error {
 		log.Printf("[%s] Status: Not in sync with remote", time.Now().Format(time.RFC3339))
 	}
 
-	// If we just became in sync, notify with green checkmark
-	if inSync && !m.lastSyncStatus {
-		log.Printf("[%s] Branch is now in sync! Sending notification.", time.Now().Format(time.RFC3339))
-		m.notifier.NotifyInSync(branch)
-	}
-
 	// Auto-resolve when out of sync (if enabled)
 	if !inSync && m.config.AutoResolve {
-		log.Printf("[%s] Auto-resolve is enabled, attempting to sync with %s...", time.Now().Format(time.RFC3339), compareBranch)
-		if err := m.attemptAutoResolve(branch, compareBranch); err != nil {
-			log.Printf("[%s] Auto-resolve failed: %v", time.Now().Format(time.RFC3339), err)
+		log.Printf("[%s] Auto-resolve is enabled, attempting to sync with %s...", time.Now().Format(time.RFC3339), remoteRef)
+		if err := m.attemptAutoResolve(branch, remoteRef); err != nil {
+			m.bus.Publish(events.AutoSyncFailed{Meta: m.meta(branch), RemoteRef: remoteRef, Option: "auto_resolve", Err: err})
 		}
 		// Re-check sync status after auto-resolve attempt
-		if m.targetBranch != "" {
-			localCommit, _ := m.repo.GetLocalCommit(branch)
-			remoteCommit, _ := m.repo.GetRemoteCommit(compareBranch)
-			inSync = localCommit == remoteCommit
-		} else {
-			inSync, _ = m.repo.IsInSync(branch)
-		}
+		localCommit, _ := m.repo.GetLocalCommit(branch)
+		inSync = localCommit == remoteCommit
+	}
+
+	if inSync && !m.lastSyncStatus {
+		m.bus.Publish(events.BecameInSync{Meta: m.meta(branch), RemoteRef: remoteRef})
 	}
 
 	// Check if we're behind remote (only when monitoring same branch)
 	if m.targetBranch == "" {
-		isBehind, behindCount, err := m.repo.IsBehindRemote(branch)
+		behindCount, err := m.repo.CountCommits(branch, remoteRef)
 		if err != nil {
 			log.Printf("[%s] Warning: unable to check if behind remote: %v", time.Now().Format(time.RFC3339), err)
-		} else if isBehind {
-			log.Printf("[%s] Branch is %d commit(s) behind remote", time.Now().Format(time.RFC3339), behindCount)
-			m.notifier.NotifyBehindRemote(branch, behindCount)
+		} else if behindCount > 0 {
+			m.bus.Publish(events.BehindRemote{Meta: m.meta(branch), RemoteRef: remoteRef, Commit: remoteCommit, Commits: behindCount})
 
 			// Auto-sync if enabled and no uncommitted changes  
 			if m.config.AutoSync || m.config.AutoPull { // Support deprecated AutoPull for backward compatibility
 				log.Printf("[%s] Auto-sync is enabled, attempting to pull changes...", time.Now().Format(time.RFC3339))
-				if err := m.attemptAutoPull(branch, behindCount); err != nil {
-					log.Printf("[%s] Auto-sync failed: %v", time.Now().Format(time.RFC3339), err)
+				if err := m.attemptAutoPull(branch, remoteRef, behindCount); err != nil {
+					m.bus.Publish(events.AutoSyncFailed{Meta: m.meta(branch), RemoteRef: remoteRef, Option: "auto_sync", Err: err})
 				}
 			}
 		}
@@ -255,97 +541,237 @@ func (m *Monitor) checkForChanges() error {
 	// Check for conflicts if we're not in sync
 	if !inSync {
 		log.Printf("[%s] Checking for potential conflicts...", time.Now().Format(time.RFC3339))
-		conflicts, err := m.repo.CheckForConflicts(fmt.Sprintf("origin/%s", compareBranch))
+		conflicts, err := m.repo.CheckForConflicts(remoteRef)
 		if err != nil {
 			log.Printf("[%s] Error checking for conflicts: %v", time.Now().Format(time.RFC3339), err)
 		} else if len(conflicts) > 0 {
-			log.Printf("[%s] Found %d conflicting file(s) with %s", time.Now().Format(time.RFC3339), len(conflicts), compareBranch)
-			m.handleConflicts(conflicts)
+			m.handleConflicts(branch, remoteRef, conflicts)
 		} else {
-			log.Printf("[%s] No conflicts detected with %s", time.Now().Format(time.RFC3339), compareBranch)
+			log.Printf("[%s] No conflicts detected with %s", time.Now().Format(time.RFC3339), remoteRef)
 		}
 	}
 
 	m.lastSyncStatus = inSync
+
+	if m.options.Submodules {
+		m.checkSubmodules()
+	}
 	return nil
 }
 
-func (m *Monitor) attemptAutoPull(branch string, commitCount int) error {
+// checkSubmodules fetches each checked out submodule and notifies when the
+// number of commits it is behind its upstream changes
+func (m *Monitor) checkSubmodules() {
+	submodules, err := m.repo.Submodules()
+	if err != nil {
+		log.Printf("[%s] Warning: unable to list submodules: %v", time.Now().Format(time.RFC3339), err)
+		return
+	}
+
+	if m.submoduleDrift == nil {
+		m.submoduleDrift = make(map[string]int)
+	}
+	for _, sub := range submodules {
+		drift, err := m.repo.CheckSubmoduleDrift(sub)
+		if err != nil {
+			log.Printf("[%s] Warning: unable to check submodule drift: %v", time.Now().Format(time.RFC3339), err)
+			continue
+		}
+
+		last := m.submoduleDrift[sub.Path]
+		m.submoduleDrift[sub.Path] = drift.Behind
+		if drift.Behind == 0 || drift.Behind == last {
+			continue
+		}
+		m.bus.Publish(events.SubmoduleDrift{Meta: m.meta(m.currentBranch), Submodule: sub.Path, Upstream: drift.Upstream, Commits: drift.Behind})
+	}
+}
+
+// resolveRemoteRef returns the remote-tracking ref the current branch is
+// compared against: the --remote-branch target on the branch's remote if one
+// was given, otherwise the branch's own upstream
+func (m *Monitor) resolveRemoteRef(branch string) (string, error) {
+	if m.targetBranch == "" {
+		return m.repo.RemoteRefOn(m.options.Remote, branch)
+	}
+
+	remote := m.options.Remote
+	if remote == "" {
+		var err error
+		remote, err = m.repo.GetRemoteName(branch)
+		if err != nil {
+			return "", err
+		}
+	}
+	return remote + "/" + m.targetBranch, nil
+}
+
+// syncStrategy returns the configured sync strategy, merge by default
+func (m *Monitor) syncStrategy() string {
+	strategy, err := git.ParseSyncStrategy(m.config.SyncStrategy)
+	if err != nil {
+		return git.SyncMerge
+	}
+	return strategy
+}
+
+// preflight checks that syncing with remoteRef under the configured strategy
+// will succeed, returning the conflicts it would run into
+func (m *Monitor) preflight(remoteRef string) ([]git.Conflict, error) {
+	switch m.syncStrategy() {
+	case git.SyncRebase:
+		return m.repo.RebasePreflight(remoteRef)
+	case git.SyncFFOnly:
+		ahead, err := m.repo.CountCommits(remoteRef, "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		if ahead > 0 {
+			return nil, fmt.Errorf("cannot fast-forward: %d local commit(s) not in %s", ahead, remoteRef)
+		}
+		return nil, nil
+	default:
+		return m.repo.CheckForConflicts(remoteRef)
+	}
+}
+
+// pullFrom brings the already fetched remoteRef into the current branch using
+// the configured sync strategy. Merging the ref explicitly, rather than
+// pulling, keeps the user's pull.rebase and pull.ff settings from overriding it.
+func (m *Monitor) pullFrom(remoteRef string) error {
+	switch m.syncStrategy() {
+	case git.SyncRebase:
+		return m.repo.Rebase(remoteRef)
+	case git.SyncFFOnly:
+		return m.repo.FastForward(remoteRef)
+	default:
+		return m.repo.MergeRef(remoteRef)
+	}
+}
+
+// syncWithStash runs pullFrom, first stashing uncommitted changes (untracked
+// files included) when there are any, and restoring them afterwards. If they
+// no longer apply, the stash entry is kept and the user is told how to recover.
+func (m *Monitor) syncWithStash(branch, remoteRef string, hasChanges bool) error {
+	if !hasChanges {
+		return m.pullFrom(remoteRef)
+	}
+
+	stash, err := m.repo.StashPush(fmt.Sprintf("harbinger: auto-stash before syncing %s with %s", branch, remoteRef))
+	if err != nil {
+		return err
+	}
+	log.Printf("[%s] Stashed uncommitted changes as %s", time.Now().Format(time.RFC3339), stash.OID[:8])
+
+	syncErr := m.pullFrom(remoteRef)
+
+	if err := m.repo.StashRestore(stash); err != nil {
+		ref, refErr := m.repo.StashRef(stash)
+		if refErr != nil || ref == "" {
+			ref = stash.OID
+		}
+		// The working tree was clean after stashing, so any change now is
+		// what git applied before it hit the conflicts
+		applied, _ := m.repo.HasUncommittedChanges()
+		m.bus.Publish(events.StashNotRestored{Meta: m.meta(branch), StashRef: ref, OID: stash.OID, Applied: applied, Err: err})
+		if syncErr != nil {
+			return syncErr
+		}
+		return fmt.Errorf("synced, but stashed changes were not restored: %w", err)
+	}
+	log.Printf("[%s] Restored stashed changes", time.Now().Format(time.RFC3339))
+
+	return syncErr
+}
+
+func (m *Monitor) attemptAutoPull(branch, remoteRef string, commitCount int) error {
 	// Check if we have uncommitted changes
 	hasChanges, err := m.repo.HasUncommittedChanges()
 	if err != nil {
 		return fmt.Errorf("failed to check for uncommitted changes: %w", err)
 	}
 
-	if hasChanges {
+	if hasChanges && !m.config.AutoStash {
 		log.Printf("Cannot auto-pull: uncommitted changes in working directory")
 		return fmt.Errorf("uncommitted changes prevent auto-pull")
 	}
 
+	// Only sync when it is known to apply cleanly
+	conflicts, err := m.preflight(remoteRef)
+	if err != nil {
+		return fmt.Errorf("%s preflight failed: %w", m.syncStrategy(), err)
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%d conflict(s) prevent a %s with %s", len(conflicts), m.syncStrategy(), remoteRef)
+	}
+
 	// Attempt to pull
-	log.Printf("Auto-pulling %d commit(s) into branch '%s'", commitCount, branch)
-	if err := m.repo.Pull(); err != nil {
+	log.Printf("Auto-pulling %d commit(s) from %s into branch '%s' (%s)", commitCount, remoteRef, branch, m.syncStrategy())
+	if err := m.syncWithStash(branch, remoteRef, hasChanges); err != nil {
 		return fmt.Errorf("pull failed: %w", err)
 	}
 
-	m.notifier.NotifyAutoPull(branch, commitCount)
-	log.Printf("Successfully auto-pulled %d commit(s)", commitCount)
+	m.bus.Publish(events.AutoSyncSucceeded{Meta: m.meta(branch), RemoteRef: remoteRef, Strategy: m.syncStrategy(), Commits: commitCount})
 	return nil
 }
 
-func (m *Monitor) attemptAutoResolve(currentBranch, remoteBranch string) error {
+func (m *Monitor) attemptAutoResolve(currentBranch, remoteRef string) error {
 	// Check if we have uncommitted changes
 	hasChanges, err := m.repo.HasUncommittedChanges()
 	if err != nil {
 		return fmt.Errorf("failed to check for uncommitted changes: %w", err)
 	}
 
-	if hasChanges {
+	if hasChanges && !m.config.AutoStash {
 		log.Printf("[%s] Cannot auto-resolve: uncommitted changes in working directory", time.Now().Format(time.RFC3339))
 		return fmt.Errorf("uncommitted changes prevent auto-resolve")
 	}
 
-	// Check for conflicts before attempting merge
-	conflicts, err := m.repo.CheckForConflicts(fmt.Sprintf("origin/%s", remoteBranch))
+	// Check for conflicts before attempting the merge or rebase
+	conflicts, err := m.preflight(remoteRef)
 	if err != nil {
-		return fmt.Errorf("failed to check for conflicts: %w", err)
+		return fmt.Errorf("%s preflight failed: %w", m.syncStrategy(), err)
 	}
 
 	if len(conflicts) > 0 {
-		log.Printf("[%s] Cannot auto-resolve: %d conflicts detected with %s", time.Now().Format(time.RFC3339), len(conflicts), remoteBranch)
-		m.handleConflicts(conflicts)
-		return fmt.Errorf("conflicts prevent automatic merge")
+		log.Printf("[%s] Cannot auto-resolve: %d conflicts detected with %s", time.Now().Format(time.RFC3339), len(conflicts), remoteRef)
+		m.handleConflicts(currentBranch, remoteRef, conflicts)
+		return fmt.Errorf("conflicts prevent automatic %s", m.syncStrategy())
+	}
+
+	behindCount, err := m.repo.CountCommits(currentBranch, remoteRef)
+	if err != nil {
+		return fmt.Errorf("failed to count remote commits: %w", err)
 	}
 
 	// Attempt the merge/pull
-	if m.targetBranch != "" && m.targetBranch != currentBranch {
-		// Cross-branch merge
-		log.Printf("[%s] Auto-merging from remote branch '%s' into current branch '%s'", time.Now().Format(time.RFC3339), remoteBranch, currentBranch)
-		if err := m.repo.MergeFromRemote(remoteBranch); err != nil {
-			return fmt.Errorf("merge failed: %w", err)
-		}
-		log.Printf("[%s] Successfully merged from %s", time.Now().Format(time.RFC3339), remoteBranch)
-		m.notifier.NotifyInSync(currentBranch)
-	} else {
-		// Same branch pull
-		log.Printf("[%s] Auto-pulling changes into branch '%s'", time.Now().Format(time.RFC3339), currentBranch)
-		if err := m.repo.Pull(); err != nil {
-			return fmt.Errorf("pull failed: %w", err)
-		}
-		log.Printf("[%s] Successfully pulled changes", time.Now().Format(time.RFC3339))
-		m.notifier.NotifyInSync(currentBranch)
+	log.Printf("[%s] Auto-syncing branch '%s' with %s (%s)", time.Now().Format(time.RFC3339), currentBranch, remoteRef, m.syncStrategy())
+	if err := m.syncWithStash(currentBranch, remoteRef, hasChanges); err != nil {
+		return fmt.Errorf("sync failed: %w", err)
+	}
+	if behindCount > 0 {
+		// Otherwise the branch is only ahead and there was nothing to bring in
+		m.bus.Publish(events.AutoSyncSucceeded{Meta: m.meta(currentBranch), RemoteRef: remoteRef, Strategy: m.syncStrategy(), Commits: behindCount})
 	}
 
 	return nil
 }
 
-func (m *Monitor) handleConflicts(conflicts []git.Conflict) {
-	m.notifier.NotifyConflicts(len(conflicts))
+func (m *Monitor) handleConflicts(branch, remoteRef string, conflicts []git.Conflict) {
+	files := make([]string, len(conflicts))
+	for i, c := range conflicts {
+		files[i] = c.Summary()
+	}
+	m.bus.Publish(events.ConflictsPredicted{Meta: m.meta(branch), RemoteRef: remoteRef, Files: files})
 
 	// Only launch conflict resolution UI if auto_resolve is enabled
 	if m.config.AutoResolve {
 		log.Println("Auto-resolving conflicts (use 'harbinger resolve' to manually resolve)")
-		resolver := conflict.NewResolver(m.repo)
+		options, err := conflict.OptionsFromConfig(m.config)
+		if err != nil {
+			log.Printf("Warning: ignoring automatic resolution settings: %v", err)
+		}
+		resolver := conflict.NewResolverWithOptions(m.repo, options)
 		if err := resolver.ResolveConflicts(conflicts); err != nil {
 			log.Printf("Error resolving conflicts: %v", err)
 		}
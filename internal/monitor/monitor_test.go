@@ -2,9 +2,15 @@ package monitor
 
 import (
 	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/javanhut/harbinger/internal/events"
 	"github.com/javanhut/harbinger/internal/git"
 	"github.com/javanhut/harbinger/internal/notify"
 	"github.com/javanhut/harbinger/pkg/config"
@@ -147,10 +153,10 @@ func TestMonitor_ConfigIntegration(t *testing.T) {
 	assert.NotNil(t, monitor.config)
 
 	// Test that config has expected default values
-	assert.Equal(t, "30s", monitor.config.PollInterval) // Default from config
-	assert.True(t, monitor.config.Notifications)        // Default should be true
-	assert.True(t, monitor.config.AutoResolve)          // Default should be true
-	assert.False(t, monitor.config.AutoSync)            // Default should be false for safety
+	assert.Equal(t, "30s", monitor.config.PollInterval.String()) // Default from config
+	assert.True(t, monitor.config.Notifications)                 // Default should be true
+	assert.True(t, monitor.config.AutoResolve)                   // Default should be true
+	assert.False(t, monitor.config.AutoSync)                     // Default should be false for safety
 }
 
 func TestMonitor_MultipleStartStop(t *testing.T) {
@@ -185,9 +191,9 @@ func TestMonitor_InvalidPollInterval(t *testing.T) {
 	monitor, err := New(".", options)
 	require.NoError(t, err)
 
-	// Should still be able to create monitor
+	// Should still be able to create monitor, polling at poll_interval from the config
 	assert.NotNil(t, monitor)
-	assert.Equal(t, time.Duration(0), monitor.options.PollInterval)
+	assert.Equal(t, config.DefaultPollInterval, monitor.options.PollInterval)
 }
 
 func TestMonitor_VeryShortPollInterval(t *testing.T) {
@@ -244,3 +250,369 @@ func TestMonitor_DoubleStart(t *testing.T) {
 	// Clean up
 	monitor.Stop()
 }
+
+func TestMonitor_PauseResume(t *testing.T) {
+	monitor, err := New(".", Options{PollInterval: time.Second})
+	require.NoError(t, err)
+
+	assert.False(t, monitor.Status().Paused)
+
+	monitor.Pause()
+	assert.True(t, monitor.Status().Paused)
+
+	monitor.Resume()
+	assert.False(t, monitor.Status().Paused)
+}
+
+func TestMonitor_SetPollInterval(t *testing.T) {
+	monitor, err := New(".", Options{PollInterval: time.Second})
+	require.NoError(t, err)
+
+	assert.Error(t, monitor.SetPollInterval(0))
+	assert.Error(t, monitor.SetPollInterval(-time.Second))
+
+	require.NoError(t, monitor.SetPollInterval(time.Minute))
+	require.NoError(t, monitor.SetPollInterval(2*time.Minute))
+	assert.Equal(t, 2*time.Minute, monitor.Status().PollInterval)
+
+	// Only the latest pending interval is kept for the loop
+	assert.Equal(t, 2*time.Minute, <-monitor.intervalCh)
+
+	// Concurrent changes do not wait for the loop to read them
+	var wg sync.WaitGroup
+	for i := 1; i <= 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, monitor.SetPollInterval(time.Duration(i)*time.Minute))
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, monitor.Status().PollInterval, <-monitor.intervalCh)
+}
+
+func TestMonitor_CheckNowRecordsStatus(t *testing.T) {
+	monitor, err := New(".", Options{PollInterval: time.Hour})
+	require.NoError(t, err)
+
+	require.NoError(t, monitor.Start())
+	defer monitor.Stop()
+
+	started := monitor.Status()
+	assert.NotEmpty(t, started.Branch)
+	assert.False(t, started.LastCheck.IsZero())
+
+	monitor.Pause()
+	monitor.CheckNow()
+	monitor.CheckNow() // Coalesced with the pending request
+
+	assert.Eventually(t, func() bool {
+		return monitor.Status().LastCheck.After(started.LastCheck)
+	}, 5*time.Second, 20*time.Millisecond, "forced check should run while paused")
+}
+
+// newSyncRepos creates a remote with one commit on main and a clone of it
+func newSyncRepos(t *testing.T) (string, string) {
+	t.Helper()
+	t.Setenv("GIT_AUTHOR_NAME", "Harbinger Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Harbinger Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote")
+	clone := filepath.Join(root, "clone")
+	require.NoError(t, os.MkdirAll(remote, 0755))
+	gitIn(t, remote, "init", "-q", "-b", "main")
+	commitIn(t, remote, "shared.txt", "line 1\nline 2\n", "initial")
+	gitIn(t, root, "clone", "-q", remote, clone)
+	return remote, clone
+}
+
+func gitIn(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, output)
+	return strings.TrimSpace(string(output))
+}
+
+func commitIn(t *testing.T, dir, name, content, message string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	gitIn(t, dir, "add", name)
+	gitIn(t, dir, "commit", "-q", "-m", message)
+}
+
+func TestMonitor_AutoPullSyncStrategies(t *testing.T) {
+	tests := []struct {
+		strategy   string
+		wantErr    bool
+		wantMerges string
+	}{
+		{strategy: git.SyncRebase, wantMerges: "0"},
+		{strategy: git.SyncMerge, wantMerges: "1"},
+		{strategy: git.SyncFFOnly, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			remote, clone := newSyncRepos(t)
+			commitIn(t, remote, "remote.txt", "remote\n", "remote file")
+			commitIn(t, clone, "local.txt", "local\n", "local file")
+
+			m, err := New(clone, Options{PollInterval: time.Hour})
+			require.NoError(t, err)
+			m.config.SyncStrategy = tt.strategy
+			require.NoError(t, m.repo.Fetch())
+			before := gitIn(t, clone, "rev-parse", "HEAD")
+
+			err = m.attemptAutoPull("main", "origin/main", 1)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, before, gitIn(t, clone, "rev-parse", "HEAD"))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "0", gitIn(t, clone, "rev-list", "--count", "HEAD..origin/main"))
+			assert.Equal(t, tt.wantMerges, gitIn(t, clone, "rev-list", "--count", "--merges", "HEAD"))
+		})
+	}
+}
+
+func TestMonitor_AutoPullRebaseRefusesConflicts(t *testing.T) {
+	remote, clone := newSyncRepos(t)
+	commitIn(t, remote, "shared.txt", "line 1\nremote\n", "remote edit")
+	commitIn(t, clone, "shared.txt", "line 1\nlocal\n", "local edit")
+
+	m, err := New(clone, Options{PollInterval: time.Hour})
+	require.NoError(t, err)
+	m.config.SyncStrategy = git.SyncRebase
+	require.NoError(t, m.repo.Fetch())
+	before := gitIn(t, clone, "rev-parse", "HEAD")
+
+	assert.Error(t, m.attemptAutoPull("main", "origin/main", 1))
+	assert.Equal(t, before, gitIn(t, clone, "rev-parse", "HEAD"))
+	operation, err := m.repo.InProgressOperation()
+	require.NoError(t, err)
+	assert.Empty(t, operation, "no rebase should have been started")
+}
+
+func TestNew_InvalidSyncStrategy(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("sync_strategy: squash\n"), 0644))
+	config.SetConfigFile(filepath.Join(dir, "config.yaml"))
+	defer func() {
+		config.SetConfigPath("")
+		config.SetConfigName("")
+	}()
+
+	_, err := New(".", Options{PollInterval: time.Second})
+	assert.Error(t, err)
+}
+
+func TestMonitor_AutoStash(t *testing.T) {
+	remote, clone := newSyncRepos(t)
+	commitIn(t, remote, "remote.txt", "remote\n", "remote file")
+	require.NoError(t, os.WriteFile(filepath.Join(clone, "shared.txt"), []byte("line 1\nwork in progress\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(clone, "notes.txt"), []byte("untracked\n"), 0644))
+
+	m, err := New(clone, Options{PollInterval: time.Hour})
+	require.NoError(t, err)
+	require.NoError(t, m.repo.Fetch())
+
+	assert.Error(t, m.attemptAutoPull("main", "origin/main", 1), "auto_stash is opt-in")
+
+	m.config.AutoStash = true
+	require.NoError(t, m.attemptAutoPull("main", "origin/main", 1))
+	assert.Equal(t, gitIn(t, clone, "rev-parse", "origin/main"), gitIn(t, clone, "rev-parse", "HEAD"))
+	assert.Equal(t, "M shared.txt\n?? notes.txt", gitIn(t, clone, "status", "--porcelain"))
+	assert.Empty(t, gitIn(t, clone, "stash", "list"))
+}
+
+func TestMonitor_AutoStashConflictKeepsStash(t *testing.T) {
+	remote, clone := newSyncRepos(t)
+	commitIn(t, remote, "shared.txt", "line 1\nremote\n", "remote edit")
+	require.NoError(t, os.WriteFile(filepath.Join(clone, "shared.txt"), []byte("line 1\nlocal\n"), 0644))
+
+	m, err := New(clone, Options{PollInterval: time.Hour})
+	require.NoError(t, err)
+	m.config.AutoStash = true
+	require.NoError(t, m.repo.Fetch())
+	recorder := &events.Recorder{}
+	m.Subscribe(recorder.Handle)
+
+	err = m.attemptAutoPull("main", "origin/main", 1)
+	assert.ErrorContains(t, err, "not restored")
+	require.Len(t, recorder.Events(), 1)
+	assert.True(t, recorder.Events()[0].(events.StashNotRestored).Applied, "the stash was applied with conflicts")
+	assert.Equal(t, gitIn(t, clone, "rev-parse", "origin/main"), gitIn(t, clone, "rev-parse", "HEAD"))
+	assert.Contains(t, gitIn(t, clone, "stash", "list"), "harbinger: auto-stash")
+}
+
+func TestMonitor_PublishesEvents(t *testing.T) {
+	remote, clone := newSyncRepos(t)
+
+	notifications := &notify.Recorder{}
+	m, err := New(clone, Options{PollInterval: time.Hour, Sinks: []notify.Sink{notifications}})
+	require.NoError(t, err)
+	m.config.AutoResolve = false
+	m.config.AutoSync = true
+
+	recorder := &events.Recorder{}
+	unsubscribe := m.Subscribe(recorder.Handle)
+
+	require.NoError(t, m.checkForChanges())
+	commitIn(t, remote, "remote.txt", "remote\n", "remote file")
+	require.NoError(t, m.checkForChanges())
+	require.NoError(t, m.checkForChanges())
+	gitIn(t, clone, "checkout", "-q", "-b", "feature")
+	m.checkForChanges()
+
+	assert.Equal(t, []string{
+		events.KindBecameInSync,
+		events.KindRemoteAdvanced,
+		events.KindBehindRemote,
+		events.KindAutoSyncSucceeded,
+		events.KindBecameInSync,
+		events.KindBranchSwitched,
+	}, recorder.Kinds())
+
+	advanced := recorder.Events()[1].(events.RemoteAdvanced)
+	assert.Equal(t, "origin/main", advanced.RemoteRef)
+	assert.Equal(t, gitIn(t, remote, "rev-parse", "HEAD"), advanced.To)
+	assert.Equal(t, m.repo.Path(), advanced.Repository)
+	assert.Equal(t, "main", advanced.Branch)
+	switched := recorder.Events()[5].(events.BranchSwitched)
+	assert.Equal(t, "main", switched.From)
+	assert.Equal(t, "feature", switched.Branch)
+
+	var types []string
+	for _, event := range notifications.Events() {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{
+		notify.EventInSync, notify.EventRemoteChange, notify.EventBehindRemote, notify.EventAutoPull, notify.EventInSync,
+	}, types, "branch switches are not notified")
+
+	unsubscribe()
+	gitIn(t, clone, "checkout", "-q", "main")
+	require.NoError(t, m.checkForChanges())
+	assert.Len(t, recorder.Events(), 6)
+}
+
+func TestMonitor_CheckSubmodulesTracksDrift(t *testing.T) {
+	library, super := newSyncRepos(t)
+	gitIn(t, super, "-c", "protocol.file.allow=always", "submodule", "add", "-q", "-b", "main", library, "lib")
+	gitIn(t, super, "commit", "-q", "-m", "add lib")
+
+	recorder := &notify.Recorder{}
+	m, err := New(super, Options{PollInterval: time.Hour, Submodules: true, Sinks: []notify.Sink{recorder}})
+	require.NoError(t, err)
+
+	m.checkSubmodules()
+	assert.Equal(t, 0, m.submoduleDrift["lib"])
+	assert.Empty(t, recorder.Events())
+
+	commitIn(t, library, "new.txt", "new\n", "library change")
+	m.checkSubmodules()
+	assert.Equal(t, 1, m.submoduleDrift["lib"])
+	require.Len(t, recorder.Events(), 1)
+	assert.Equal(t, notify.EventSubmoduleDrift, recorder.Events()[0].Type)
+	assert.Equal(t, super, recorder.Events()[0].Repository)
+}
+
+func TestMonitor_ConfigPollIntervalAndIgnoreBranches(t *testing.T) {
+	remote, clone := newSyncRepos(t)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("poll_interval: 5m\nnotifications: false\nignore_branches: [\"ma*\"]\n"), 0644))
+	config.SetConfigFile(filepath.Join(dir, "config.yaml"))
+	defer func() {
+		config.SetConfigPath("")
+		config.SetConfigName("")
+	}()
+
+	m, err := New(clone, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, m.options.PollInterval)
+
+	explicit, err := New(clone, Options{PollInterval: time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, explicit.options.PollInterval, "an explicit interval wins over the config")
+
+	commitIn(t, remote, "remote.txt", "remote\n", "remote file")
+	fetched := gitIn(t, clone, "rev-parse", "origin/main")
+	require.NoError(t, m.checkForChanges())
+	m.recordCheck(nil)
+	assert.True(t, m.Status().Ignored)
+	assert.Equal(t, fetched, gitIn(t, clone, "rev-parse", "origin/main"), "ignored branches are not fetched")
+
+	gitIn(t, clone, "checkout", "-q", "-b", "feature")
+	gitIn(t, clone, "branch", "-q", "--set-upstream-to", "origin/main")
+	require.NoError(t, m.checkForChanges())
+	m.recordCheck(nil)
+	assert.False(t, m.Status().Ignored)
+	assert.Equal(t, "origin/main", m.Status().RemoteRef)
+}
+
+func TestMonitor_ReloadConfig(t *testing.T) {
+	_, clone := newSyncRepos(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("poll_interval: 5m\nauto_resolve: true\n"), 0644))
+	config.SetConfigFile(path)
+	defer func() {
+		config.SetConfigPath("")
+		config.SetConfigName("")
+	}()
+
+	m, err := New(clone, Options{})
+	require.NoError(t, err)
+	explicit, err := New(clone, Options{PollInterval: 10 * time.Minute})
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("poll_interval: 1m\nauto_resolve: false\nignore_branches: [main]\n"), 0644))
+	require.NoError(t, m.Reload())
+	m.applyConfig(<-m.configCh)
+	assert.False(t, m.config.AutoResolve)
+	assert.True(t, m.config.IsBranchIgnored("main"))
+	assert.Equal(t, time.Minute, m.Status().PollInterval)
+	assert.Equal(t, time.Minute, <-m.intervalCh, "the loop's ticker follows poll_interval")
+
+	explicit.reloadIfChanged()
+	assert.False(t, explicit.config.AutoResolve, "edited files are picked up by the watcher")
+	assert.Equal(t, 10*time.Minute, explicit.Status().PollInterval, "an explicit interval is kept")
+
+	require.NoError(t, os.WriteFile(path, []byte("poll_interval: soon\n"), 0644))
+	assert.Error(t, m.Reload())
+	m.reloadIfChanged()
+	assert.False(t, m.config.AutoResolve, "an invalid config is not applied")
+	assert.Equal(t, time.Minute, m.Status().PollInterval)
+}
+
+func TestMonitor_ReloadConcurrently(t *testing.T) {
+	_, clone := newSyncRepos(t)
+	m, err := New(clone, Options{})
+	require.NoError(t, err)
+
+	// Nothing reads configCh, as when the loop has exited; concurrent
+	// reloads must still return
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, m.Reload())
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, m.configCh, 1)
+
+	m.cancel()
+	assert.ErrorContains(t, m.Reload(), "monitor is stopped")
+}
@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CommandSink runs a command for every event, with the event as JSON on
+// stdin and its main fields in HARBINGER_EVENT_* environment variables
+type CommandSink struct {
+	args    []string
+	timeout time.Duration
+}
+
+// NewCommandSink creates a command sink. The command is split on spaces and
+// not run through a shell; timeout defaults to DefaultWebhookTimeout.
+func NewCommandSink(command string, timeout time.Duration) (*CommandSink, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("command is required")
+	}
+	if timeout <= 0 {
+		timeout = DefaultWebhookTimeout
+	}
+	return &CommandSink{args: args, timeout: timeout}, nil
+}
+
+func (c *CommandSink) Name() string {
+	return "command " + c.args[0]
+}
+
+func (c *CommandSink) Background() bool {
+	return true
+}
+
+func (c *CommandSink) Send(event Event) error {
+	input, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.args[0], c.args[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(),
+		"HARBINGER_EVENT_TYPE="+event.Type,
+		"HARBINGER_EVENT_LEVEL="+event.Level,
+		"HARBINGER_EVENT_TITLE="+event.Title,
+		"HARBINGER_EVENT_MESSAGE="+event.Message,
+		"HARBINGER_EVENT_REPOSITORY="+event.Repository,
+		"HARBINGER_EVENT_BRANCH="+event.Branch,
+		"HARBINGER_EVENT_COUNT="+strconv.Itoa(event.Count),
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", c.args[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/javanhut/harbinger/pkg/config"
+)
+
+// DefaultSMTPPort is the submission port, which expects STARTTLS
+const DefaultSMTPPort = 587
+
+// StartTLS modes
+const (
+	StartTLSRequired = "required"
+	StartTLSOptional = "optional"
+	StartTLSNever    = "never"
+)
+
+// emailEvents are the events an email notifier sends when its events are
+// not configured
+var emailEvents = []string{EventConflicts, EventBehindRemote}
+
+// EmailOptions configures an Email
+type EmailOptions struct {
+	Host string
+	Port int
+	// Username and Password authenticate with PLAIN, which net/smtp only
+	// allows over TLS or to localhost
+	Username string
+	Password string
+	StartTLS string // StartTLSRequired by default
+	From     string
+	To       []string
+	// Digest collects the events and sends them in one email per period
+	Digest time.Duration
+	// Timeout limits each delivery, DefaultWebhookTimeout by default
+	Timeout time.Duration
+	// TLSConfig is used for STARTTLS, verifying Host by default
+	TLSConfig *tls.Config
+}
+
+// Email sends events over SMTP
+type Email struct {
+	options EmailOptions
+	from    string
+	to      []string
+
+	mu      sync.Mutex
+	pending []Event
+	timer   *time.Timer
+}
+
+// NewEmail checks the options and creates an email sink
+func NewEmail(options EmailOptions) (*Email, error) {
+	if options.Host == "" {
+		return nil, fmt.Errorf("smtp_host is required")
+	}
+	if options.Port == 0 {
+		options.Port = DefaultSMTPPort
+	}
+	if options.Port < 0 || options.Port > 65535 {
+		return nil, fmt.Errorf("invalid smtp_port %d", options.Port)
+	}
+	switch options.StartTLS {
+	case "":
+		options.StartTLS = StartTLSRequired
+	case StartTLSRequired, StartTLSOptional, StartTLSNever:
+	default:
+		return nil, fmt.Errorf("unknown starttls %q, expected required, optional or never", options.StartTLS)
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultWebhookTimeout
+	}
+
+	from, err := mail.ParseAddress(options.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %q: %w", options.From, err)
+	}
+	if len(options.To) == 0 {
+		return nil, fmt.Errorf("to is required")
+	}
+	e := &Email{options: options, from: from.Address}
+	for _, recipient := range options.To {
+		to, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to address %q: %w", recipient, err)
+		}
+		e.to = append(e.to, to.Address)
+	}
+	return e, nil
+}
+
+func (e *Email) Name() string {
+	return "email " + e.options.Host
+}
+
+func (e *Email) Background() bool {
+	return true
+}
+
+// Send emails the event, or holds it for the next digest
+func (e *Email) Send(event Event) error {
+	if e.options.Digest <= 0 {
+		return e.deliver(event)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pending = append(e.pending, event)
+	if e.timer == nil {
+		e.timer = time.AfterFunc(e.options.Digest, func() {
+			if err := e.Flush(); err != nil {
+				log.Printf("[%s] Warning: %s: %v", time.Now().Format(time.RFC3339), e.Name(), err)
+			}
+		})
+	}
+	return nil
+}
+
+// Flush emails the events held for the digest right away
+func (e *Email) Flush() error {
+	e.mu.Lock()
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+	pending := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+
+	switch len(pending) {
+	case 0:
+		return nil
+	case 1:
+		return e.deliver(pending[0])
+	default:
+		return e.deliver(digest(pending))
+	}
+}
+
+// deliver sends one email, upgrading the connection with STARTTLS as
+// configured
+func (e *Email) deliver(event Event) error {
+	addr := net.JoinHostPort(e.options.Host, strconv.Itoa(e.options.Port))
+	conn, err := net.DialTimeout("tcp", addr, e.options.Timeout)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(e.options.Timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+	client, err := smtp.NewClient(conn, e.options.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if e.options.StartTLS != StartTLSNever {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(e.tlsConfig()); err != nil {
+				return fmt.Errorf("starttls failed: %w", err)
+			}
+		} else if e.options.StartTLS == StartTLSRequired {
+			return fmt.Errorf("%s does not support STARTTLS", addr)
+		}
+	}
+	if e.options.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.options.Username, e.options.Password, e.options.Host)); err != nil {
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(e.from); err != nil {
+		return err
+	}
+	for _, to := range e.to {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(e.message(event)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (e *Email) tlsConfig() *tls.Config {
+	if e.options.TLSConfig == nil {
+		return &tls.Config{ServerName: e.options.Host}
+	}
+	cfg := e.options.TLSConfig.Clone()
+	if cfg.ServerName == "" {
+		cfg.ServerName = e.options.Host
+	}
+	return cfg
+}
+
+// message renders the event as a plain text email
+func (e *Email) message(event Event) []byte {
+	subject := event.Title
+	if event.Repository != "" {
+		subject += " (" + event.Repository + ")"
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\n", e.options.From)
+	fmt.Fprintf(&buf, "To: %s\n", strings.Join(e.options.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\n\n")
+
+	buf.WriteString(event.Message + "\n\n")
+	if event.Repository != "" {
+		fmt.Fprintf(&buf, "Repository: %s\n", event.Repository)
+	}
+	if event.Branch != "" {
+		fmt.Fprintf(&buf, "Branch: %s\n", event.Branch)
+	}
+	fmt.Fprintf(&buf, "Level: %s\n", event.Level)
+	return buf.Bytes()
+}
+
+// emailFromConfig creates an email sink, expanding ${VAR} in its username
+// and password. Without an events filter it only sends conflicts and
+// behind-remote alerts.
+func emailFromConfig(cfg config.NotifierConfig) (Sink, error) {
+	email, err := NewEmail(EmailOptions{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: os.ExpandEnv(cfg.Username),
+		Password: os.ExpandEnv(cfg.Password),
+		StartTLS: cfg.StartTLS,
+		From:     cfg.From,
+		To:       cfg.To,
+		Digest:   time.Duration(cfg.Digest),
+		Timeout:  time.Duration(cfg.Timeout),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Events) > 0 {
+		return email, nil
+	}
+	filter, err := NewFilter(config.EventFilter{Events: emailEvents})
+	if err != nil {
+		return nil, err
+	}
+	return Filtered(email, filter), nil
+}
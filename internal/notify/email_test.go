@@ -0,0 +1,247 @@
+package notify
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/javanhut/harbinger/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMail is an email the fake SMTP server received
+type fakeMail struct {
+	From string
+	To   []string
+	Data string
+	TLS  bool
+	Auth string // The decoded AUTH PLAIN response
+}
+
+// fakeSMTP is a local SMTP server that records the emails it receives.
+// With a TLS config it offers STARTTLS.
+type fakeSMTP struct {
+	listener net.Listener
+	tls      *tls.Config
+
+	mu    sync.Mutex
+	mails []fakeMail
+}
+
+func newFakeSMTP(t *testing.T, tlsConfig *tls.Config) *fakeSMTP {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeSMTP{listener: listener, tls: tlsConfig}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTP) Mails() []fakeMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeMail(nil), s.mails...)
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	text := textproto.NewConn(conn)
+	reply := func(format string, args ...interface{}) {
+		text.PrintfLine(format, args...)
+	}
+	var current fakeMail
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			if s.tls != nil && !current.TLS {
+				reply("250-fake")
+				reply("250-STARTTLS")
+			} else {
+				reply("250-fake")
+			}
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			text = textproto.NewConn(conn)
+			current.TLS = true
+		case "AUTH":
+			decoded, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			current.Auth = string(decoded)
+			reply("235 ok")
+		case "MAIL":
+			current.From = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			reply("250 ok")
+		case "RCPT":
+			current.To = append(current.To, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			current.Data = string(data)
+			s.mu.Lock()
+			s.mails = append(s.mails, current)
+			s.mu.Unlock()
+			current = fakeMail{TLS: current.TLS}
+			reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// testTLS returns a server certificate for 127.0.0.1 and a client config
+// that trusts it
+func testTLS(t *testing.T) (*tls.Config, *tls.Config) {
+	t.Helper()
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	client := server.Client().Transport.(*http.Transport).TLSClientConfig
+	return &tls.Config{Certificates: server.TLS.Certificates}, client
+}
+
+func TestEmail_SendsOverSTARTTLS(t *testing.T) {
+	serverTLS, clientTLS := testTLS(t)
+	server := newFakeSMTP(t, serverTLS)
+
+	email, err := NewEmail(EmailOptions{
+		Host:      "127.0.0.1",
+		Port:      server.port(),
+		Username:  "harbinger",
+		Password:  "secret",
+		From:      "Harbinger <harbinger@example.com>",
+		To:        []string{"dev@example.com", "ops@example.com"},
+		TLSConfig: clientTLS,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "email 127.0.0.1", email.Name())
+
+	require.NoError(t, email.Send(Event{
+		Type:       EventConflicts,
+		Level:      LevelError,
+		Title:      "Merge Conflicts Detected",
+		Message:    "Found 2 potential merge conflicts that need resolution",
+		Repository: "/src/app",
+		Branch:     "main",
+	}))
+
+	mails := server.Mails()
+	require.Len(t, mails, 1)
+	assert.True(t, mails[0].TLS)
+	assert.Equal(t, "\x00harbinger\x00secret", mails[0].Auth)
+	assert.Equal(t, "harbinger@example.com", mails[0].From)
+	assert.Equal(t, []string{"dev@example.com", "ops@example.com"}, mails[0].To)
+
+	message, err := textproto.NewReader(bufio.NewReader(strings.NewReader(mails[0].Data))).ReadMIMEHeader()
+	require.NoError(t, err)
+	assert.Equal(t, "Merge Conflicts Detected (/src/app)", message.Get("Subject"))
+	assert.Equal(t, "dev@example.com, ops@example.com", message.Get("To"))
+	assert.Contains(t, mails[0].Data, "Found 2 potential merge conflicts that need resolution\n\nRepository: /src/app\nBranch: main\n")
+}
+
+func TestEmail_StartTLSModes(t *testing.T) {
+	server := newFakeSMTP(t, nil)
+	event := Event{Type: EventBehindRemote, Level: LevelInfo, Title: "Branch Behind Remote"}
+
+	required, err := NewEmail(EmailOptions{Host: "127.0.0.1", Port: server.port(), From: "a@example.com", To: []string{"b@example.com"}})
+	require.NoError(t, err)
+	assert.ErrorContains(t, required.Send(event), "does not support STARTTLS")
+
+	optional, err := NewEmail(EmailOptions{Host: "127.0.0.1", Port: server.port(), StartTLS: StartTLSOptional, From: "a@example.com", To: []string{"b@example.com"}})
+	require.NoError(t, err)
+	require.NoError(t, optional.Send(event))
+	require.Len(t, server.Mails(), 1)
+	assert.False(t, server.Mails()[0].TLS)
+
+	for options, want := range map[*EmailOptions]string{
+		{From: "a@example.com", To: []string{"b@example.com"}}:                                     "smtp_host is required",
+		{Host: "localhost", From: "a@example.com", To: []string{"b@example.com"}, StartTLS: "yes"}: `unknown starttls "yes"`,
+		{Host: "localhost", From: "nobody", To: []string{"b@example.com"}}:                         `invalid from address "nobody"`,
+		{Host: "localhost", From: "a@example.com"}:                                                 "to is required",
+	} {
+		_, err := NewEmail(*options)
+		assert.ErrorContains(t, err, want)
+	}
+}
+
+func TestEmail_Digest(t *testing.T) {
+	server := newFakeSMTP(t, nil)
+	email, err := NewEmail(EmailOptions{
+		Host:     "127.0.0.1",
+		Port:     server.port(),
+		StartTLS: StartTLSNever,
+		From:     "a@example.com",
+		To:       []string{"b@example.com"},
+		Digest:   time.Hour,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, email.Send(behindRemoteEvent("main", 2)))
+	require.NoError(t, email.Send(conflictsEvent(3)))
+	assert.Empty(t, server.Mails(), "held for the digest")
+
+	NewWithOptions(Options{Sinks: []Sink{email}}).Flush()
+	mails := server.Mails()
+	require.Len(t, mails, 1)
+	assert.Contains(t, mails[0].Data, "Subject: 2 Harbinger Notifications\n")
+	assert.Contains(t, mails[0].Data, "Branch Behind Remote: Branch 'main' is 2 commit(s) behind remote\nMerge Conflicts Detected: Found 3 potential merge conflicts")
+}
+
+func TestEmail_DefaultEvents(t *testing.T) {
+	cfg := config.NotifierConfig{Type: "email", EmailConfig: config.EmailConfig{SMTPHost: "localhost", From: "a@example.com", To: []string{"b@example.com"}}}
+	sink, err := NewSink(cfg)
+	require.NoError(t, err)
+	assert.True(t, accepts(sink, Event{Type: EventConflicts, Level: LevelError}))
+	assert.True(t, accepts(sink, Event{Type: EventBehindRemote, Level: LevelInfo}))
+	assert.False(t, accepts(sink, Event{Type: EventInSync, Level: LevelSuccess}))
+
+	cfg.MinLevel = "warn"
+	sink, err = NewSink(cfg)
+	require.NoError(t, err)
+	assert.False(t, accepts(sink, Event{Type: EventBehindRemote, Level: LevelInfo}), "min_level still applies")
+
+	cfg.EventFilter = config.EventFilter{Events: []string{EventInSync}}
+	sink, err = NewSink(cfg)
+	require.NoError(t, err)
+	assert.True(t, accepts(sink, Event{Type: EventInSync, Level: LevelSuccess}))
+}
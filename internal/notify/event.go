@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"time"
+
+	"github.com/javanhut/harbinger/internal/events"
+)
+
+// Event types, as named in the webhooks events filter. Those with a monitor
+// event share its kind.
+const (
+	EventRemoteChange     = events.KindRemoteAdvanced
+	EventOutOfSync        = "out_of_sync"
+	EventConflicts        = events.KindConflictsPredicted
+	EventInSync           = events.KindBecameInSync
+	EventAutoPull         = events.KindAutoSyncSucceeded
+	EventBehindRemote     = events.KindBehindRemote
+	EventSubmoduleDrift   = events.KindSubmoduleDrift
+	EventStashNotRestored = events.KindStashNotRestored
+	EventAutoSyncFailed   = events.KindAutoSyncFailed
+	EventFetchFailed      = events.KindFetchFailed
+)
+
+// Event levels
+const (
+	LevelInfo    = "info"
+	LevelSuccess = "success"
+	LevelWarn    = "warn"
+	LevelError   = "error"
+)
+
+// Event is a single notification, as sent to webhooks
+type Event struct {
+	Type       string    `json:"type"`
+	Level      string    `json:"level"`
+	Title      string    `json:"title"`
+	Message    string    `json:"message"`
+	Repository string    `json:"repository,omitempty"`
+	Branch     string    `json:"branch,omitempty"`
+	Count      int       `json:"count,omitempty"` // Commits or conflicting files, depending on the type
+	Time       time.Time `json:"time"`
+
+	// state is what the event reports about the branch, compared by
+	// Policy.Dedup; events without one are never deduplicated
+	state string
+}
+
+// EventTypes returns every event type
+func EventTypes() []string {
+	return []string{
+		EventRemoteChange, EventOutOfSync, EventConflicts, EventInSync,
+		EventAutoPull, EventBehindRemote, EventSubmoduleDrift, EventStashNotRestored,
+		EventAutoSyncFailed, EventFetchFailed,
+	}
+}
+
+// logPrefixes keeps the log lines the notifier has always written
+var logPrefixes = map[string]string{
+	LevelInfo:    "INFO",
+	LevelSuccess: "SUCCESS",
+	LevelWarn:    "WARN",
+	LevelError:   "❌",
+}
+
+// levelRanks orders levels for min_level; success counts as info
+var levelRanks = map[string]int{
+	LevelInfo:    0,
+	LevelSuccess: 0,
+	LevelWarn:    1,
+	LevelError:   2,
+}
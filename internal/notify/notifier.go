@@ -1,213 +1,467 @@
 package notify
 
 import (
-	"bytes"
 	"fmt"
 	"log"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/javanhut/harbinger/internal/events"
+	"github.com/javanhut/harbinger/pkg/config"
 )
 
+// Notifier turns monitor events into notifications and hands them to its
+// sinks, as its Policy allows
 type Notifier struct {
-	useDesktopNotifications bool
+	repository string
+	deliveries sync.WaitGroup
+
+	// Guarded by mu, since held notifications are sent from a timer
+	mu     sync.Mutex
+	sinks  []Sink
+	policy Policy
+	// Last state notified by event type and branch, for Policy.Dedup
+	states map[string]string
+	// Last notification by event type, for Policy.RateLimits
+	sent map[string]time.Time
+	// Notifications held back by Policy.Digest or Policy.QuietHours
+	pending    []Event
+	flushTimer *time.Timer
+	now        func() time.Time
+}
+
+// Options configures a Notifier
+type Options struct {
+	// Desktop adds a desktop sink for every event, where the platform
+	// supports desktop notifications
+	Desktop bool
+	// Repository is reported with every event
+	Repository string
+	// Sinks receive the events they accept. Events are also logged unless
+	// one of them is a log sink.
+	Sinks []Sink
+	// Policy deduplicates, rate limits and delays notifications
+	Policy Policy
 }
 
 func New() *Notifier {
+	return NewWithOptions(Options{Desktop: true})
+}
+
+// NewWithOptions creates a notifier, e.g. with desktop notifications turned
+// off by the notifications config option
+func NewWithOptions(options Options) *Notifier {
 	return &Notifier{
-		useDesktopNotifications: checkDesktopNotificationSupport("/proc/version"),
+		repository: options.Repository,
+		sinks:      options.sinks(),
+		policy:     options.Policy,
+		states:     map[string]string{},
+		sent:       map[string]time.Time{},
+		now:        time.Now,
 	}
 }
 
-func (n *Notifier) NotifyRemoteChange(branch, commit string) {
-	title := "Remote Branch Updated"
-	message := fmt.Sprintf("Branch '%s' has new commits on remote\nLatest: %s", branch, commit[:7])
+// OptionsFromConfig returns the notifier options for the notifications,
+// webhooks, notifiers and notification policy config. With notifications
+// turned off, desktop notifiers are left out too.
+func OptionsFromConfig(cfg *config.Config) (Options, error) {
+	policy, err := PolicyFromConfig(cfg)
+	if err != nil {
+		return Options{}, err
+	}
+	options := Options{Desktop: cfg.Notifications, Policy: policy}
+	for i, hook := range cfg.Webhooks {
+		sink, err := NewSink(config.NotifierConfig{Type: "webhook", WebhookConfig: hook})
+		if err != nil {
+			return Options{}, fmt.Errorf("webhooks[%d]: %w", i, err)
+		}
+		options.Sinks = append(options.Sinks, sink)
+	}
 
-	n.sendNotification(title, message)
-	log.Printf("INFO %s: %s", title, message)
+	for i, notifier := range cfg.Notifiers {
+		sink, err := NewSink(notifier)
+		if err != nil {
+			return Options{}, fmt.Errorf("notifiers[%d]: %w", i, err)
+		}
+		if notifier.Type == "desktop" {
+			// Desktop notifiers replace the unfiltered default one
+			options.Desktop = false
+			if !cfg.Notifications {
+				continue
+			}
+		}
+		options.Sinks = append(options.Sinks, sink)
+	}
+	return options, nil
 }
 
-func (n *Notifier) NotifyOutOfSync(branch, localCommit, remoteCommit string) {
-	title := "Branch Out of Sync"
-	message := fmt.Sprintf("Branch '%s' is out of sync\nLocal: %s\nRemote: %s",
-		branch, localCommit[:7], remoteCommit[:7])
+func (o Options) sinks() []Sink {
+	sinks := append([]Sink(nil), o.Sinks...)
+	hasLog := false
+	for _, sink := range sinks {
+		if sink.Name() == "log" {
+			hasLog = true
+		}
+	}
+	if !hasLog {
+		sinks = append([]Sink{LogSink{}}, sinks...)
+	}
+	if o.Desktop {
+		sinks = append(sinks, NewDesktopSink())
+	}
+	return sinks
+}
 
-	n.sendNotification(title, message)
-	log.Printf("WARN %s: %s", title, message)
+// Configure replaces the sinks and the policy, e.g. after the config was
+// reloaded. The repository is kept unless options names another one, and
+// held notifications are still sent.
+func (n *Notifier) Configure(options Options) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sinks = options.sinks()
+	n.policy = options.Policy
+	if options.Repository != "" {
+		n.repository = options.Repository
+	}
 }
 
-func (n *Notifier) NotifyConflicts(count int) {
-	title := "Merge Conflicts Detected"
-	message := fmt.Sprintf("Found %d potential merge conflicts that need resolution", count)
+// Flush sends the notifications held back by the policy right away, then
+// waits for them and flushes the sinks that hold events back themselves
+func (n *Notifier) Flush() {
+	n.mu.Lock()
+	if n.flushTimer != nil {
+		n.flushTimer.Stop()
+		n.flushTimer = nil
+	}
+	pending, sinks := n.pending, n.sinks
+	n.pending = nil
+	n.mu.Unlock()
+
+	n.sendHeld(sinks, pending)
+	n.deliveries.Wait()
+	for _, sink := range sinks {
+		if flusher, ok := sink.(flushingSink); ok {
+			if err := flusher.Flush(); err != nil {
+				log.Printf("[%s] Warning: %s: %v", time.Now().Format(time.RFC3339), sink.Name(), err)
+			}
+		}
+	}
+}
 
-	n.sendNotification(title, message)
-	log.Printf("❌ %s: %s", title, message)
+// Wait blocks until every background delivery in progress has finished
+func (n *Notifier) Wait() {
+	n.deliveries.Wait()
 }
 
-func (n *Notifier) NotifyInSync(branch string) {
-	title := "Branch In Sync"
-	message := fmt.Sprintf("Branch '%s' is up to date with remote", branch)
+// filteringSink is implemented by sinks that only want some events
+type filteringSink interface {
+	Accepts(event Event) bool
+}
 
-	n.sendNotification(title, message)
-	log.Printf("SUCCESS %s: %s", title, message)
+func accepts(sink Sink, event Event) bool {
+	filter, ok := sink.(filteringSink)
+	return !ok || filter.Accepts(event)
 }
 
-func (n *Notifier) NotifyAutoPull(branch string, commitCount int) {
-	title := "Auto-Pull Completed"
-	message := fmt.Sprintf("Pulled %d commit(s) into branch '%s'", commitCount, branch)
+// notify logs an event and hands it to every other sink that accepts it,
+// unless the policy drops it or holds it back
+func (n *Notifier) notify(event Event) {
+	n.mu.Lock()
+	event.Repository = n.repository
+	event.Time = n.now()
+	sinks := n.sinks
+	admitted := n.admit(event)
+	n.mu.Unlock()
+
+	for _, sink := range sinks {
+		if sink.Name() == "log" || admitted {
+			n.deliver(sink, event)
+		}
+	}
+}
 
-	n.sendNotification(title, message)
-	log.Printf("SUCCESS %s: %s", title, message)
+// admit applies the policy to an event, returning whether it is sent now.
+// It is called with mu held.
+func (n *Notifier) admit(event Event) bool {
+	n.forget(event)
+	slot := event.Type + "\x00" + event.Branch
+	if n.policy.Dedup && event.state != "" && n.states[slot] == event.state {
+		return false
+	}
+	if limit := n.policy.rateLimit(event.Type); limit > 0 {
+		if last, ok := n.sent[event.Type]; ok && event.Time.Sub(last) < limit {
+			// Not remembered, so a state that persists is notified once
+			// the limit allows it
+			return false
+		}
+		n.sent[event.Type] = event.Time
+	}
+	if event.state != "" {
+		n.states[slot] = event.state
+	}
+
+	if until, quiet := n.policy.quietUntil(event.Time); quiet {
+		n.hold(event, until)
+		return false
+	}
+	if n.policy.Digest > 0 {
+		n.hold(event, event.Time.Add(n.policy.Digest))
+		return false
+	}
+	return true
 }
 
-func (n *Notifier) NotifyBehindRemote(branch string, commitCount int) {
-	title := "Branch Behind Remote"
-	message := fmt.Sprintf("Branch '%s' is %d commit(s) behind remote", branch, commitCount)
+// forget drops the states an event ends: being in sync ends every state of
+// the branch, a sync its earlier failure, and any other event shows that
+// fetching works again
+func (n *Notifier) forget(event Event) {
+	switch event.Type {
+	case EventInSync:
+		for slot := range n.states {
+			if strings.HasSuffix(slot, "\x00"+event.Branch) {
+				delete(n.states, slot)
+			}
+		}
+	case EventAutoPull:
+		delete(n.states, EventAutoSyncFailed+"\x00"+event.Branch)
+	}
+	if event.Type != EventFetchFailed {
+		delete(n.states, EventFetchFailed+"\x00"+event.Branch)
+	}
+}
 
-	n.sendNotification(title, message)
-	log.Printf("INFO %s: %s", title, message)
+// hold keeps an event until at, or longer if quiet hours have started by then
+func (n *Notifier) hold(event Event, at time.Time) {
+	n.pending = append(n.pending, event)
+	if n.flushTimer == nil {
+		n.flushTimer = time.AfterFunc(at.Sub(n.now()), n.flushHeld)
+	}
 }
 
-func (n *Notifier) sendNotification(title, message string) {
-	if !n.useDesktopNotifications {
+func (n *Notifier) flushHeld() {
+	n.mu.Lock()
+	n.flushTimer = nil
+	if until, quiet := n.policy.quietUntil(n.now()); quiet && len(n.pending) > 0 {
+		n.flushTimer = time.AfterFunc(until.Sub(n.now()), n.flushHeld)
+		n.mu.Unlock()
 		return
 	}
+	pending, sinks := n.pending, n.sinks
+	n.pending = nil
+	n.mu.Unlock()
 
-	switch runtime.GOOS {
-	case "darwin":
-		// macOS notification
-		script := fmt.Sprintf(`display notification "%s" with title "%s"`, message, title)
-		exec.Command("osascript", "-e", script).Run()
-	case "linux":
-		// Linux notification (requires notify-send) or WSL notification
-		if isWSL("/proc/version") {
-			n.sendWSLNotification(title, message)
-		} else {
-			exec.Command("notify-send", title, message).Run()
+	n.sendHeld(sinks, pending)
+}
+
+// sendHeld gives each sink the held events it accepts, combined into a
+// digest when there are several. Log sinks had them already.
+func (n *Notifier) sendHeld(sinks []Sink, pending []Event) {
+	for _, sink := range sinks {
+		if sink.Name() == "log" {
+			continue
 		}
-	case "windows":
-		// Windows notification (requires PowerShell)
-		script := fmt.Sprintf(`
-			[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
-			[Windows.UI.Notifications.ToastNotification, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
-			[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] | Out-Null
-
-			$template = @"
-<toast>
-	<visual>
-		<binding template="ToastText02">
-			<text id="1">%s</text>
-			<text id="2">%s</text>
-		</binding>
-	</visual>
-</toast>
-"@
-
-			$xml = New-Object Windows.Data.Xml.Dom.XmlDocument
-			$xml.LoadXml($template)
-			$toast = New-Object Windows.UI.Notifications.ToastNotification $xml
-			[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier("Harbinger").Show($toast)
-		`, title, message)
-		exec.Command("powershell", "-Command", script).Run()
-	}
-}
-
-func checkDesktopNotificationSupport(procVersionPath string) bool {
-	switch runtime.GOOS {
-	case "darwin":
-		return true
-	case "linux":
-		// Check if notify-send is available or if running on WSL
-		if isWSL(procVersionPath) {
-			return true // We will use PowerShell script for notifications on WSL
+		var accepted []Event
+		for _, event := range pending {
+			if accepts(sink, event) {
+				accepted = append(accepted, event)
+			}
 		}
-		if err := exec.Command("which", "notify-send").Run(); err == nil {
-			return true
+		switch len(accepted) {
+		case 0:
+		case 1:
+			n.send(sink, accepted[0])
+		default:
+			n.send(sink, digest(accepted))
 		}
-	case "windows":
-		return true
 	}
-	return false
 }
 
-// sendWSLNotification sends a notification through WSL to Windows
-func (n *Notifier) sendWSLNotification(title, message string) {
-	// Create the PowerShell script content
-	scriptContent := fmt.Sprintf(`
-param([string]$Title, [string]$Message)
-
-Add-Type -AssemblyName System.Windows.Forms
-Add-Type -AssemblyName System.Drawing
-
-$notify = New-Object System.Windows.Forms.NotifyIcon
-$notify.Icon = [System.Drawing.SystemIcons]::Information
-$notify.BalloonTipIcon = [System.Windows.Forms.ToolTipIcon]::Info
-$notify.BalloonTipText = $Message
-$notify.BalloonTipTitle = $Title
-$notify.Visible = $true
-$notify.ShowBalloonTip(5000)
-
-# Keep the script running for a moment so the notification shows
-Start-Sleep -Seconds 1
-$notify.Dispose()
-`)
+// deliver hands an event to a sink that accepts it. Background sinks get it
+// from a goroutine, the others in order.
+func (n *Notifier) deliver(sink Sink, event Event) {
+	if accepts(sink, event) {
+		n.send(sink, event)
+	}
+}
 
-	// Create temp directory for the script
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		log.Printf("Error getting user home directory: %v", err)
+func (n *Notifier) send(sink Sink, event Event) {
+	if background, ok := sink.(backgroundSink); ok && background.Background() {
+		n.deliveries.Add(1)
+		go func() {
+			defer n.deliveries.Done()
+			n.sendNow(sink, event)
+		}()
 		return
 	}
+	n.sendNow(sink, event)
+}
 
-	harbingerDir := filepath.Join(homeDir, ".harbinger")
-	if err := os.MkdirAll(harbingerDir, 0755); err != nil {
-		log.Printf("Error creating harbinger directory: %v", err)
-		return
+func (n *Notifier) sendNow(sink Sink, event Event) {
+	if err := sink.Send(event); err != nil {
+		log.Printf("[%s] Warning: %s: %v", time.Now().Format(time.RFC3339), sink.Name(), err)
+	}
+}
+
+// Handle turns a monitor event into a notification. Branch switches are
+// only logged. The remote commit, the conflicting files and the errors are
+// the states Policy.Dedup compares.
+func (n *Notifier) Handle(event events.Event) {
+	switch e := event.(type) {
+	case events.RemoteAdvanced:
+		n.NotifyRemoteChange(e.Branch, e.To)
+	case events.BecameInSync:
+		n.NotifyInSync(e.Branch)
+	case events.BehindRemote:
+		notification := behindRemoteEvent(e.Branch, e.Commits)
+		notification.state = e.Commit
+		n.notify(notification)
+	case events.ConflictsPredicted:
+		notification := conflictsEvent(len(e.Files))
+		notification.Branch = e.Branch
+		files := append([]string(nil), e.Files...)
+		sort.Strings(files)
+		notification.state = strings.Join(files, "\n")
+		n.notify(notification)
+	case events.AutoSyncSucceeded:
+		n.NotifyAutoPull(e.Branch, e.Commits)
+	case events.AutoSyncFailed:
+		notification := autoSyncFailedEvent(e.Branch, e.Err)
+		notification.state = e.Err.Error()
+		n.notify(notification)
+	case events.FetchFailed:
+		notification := fetchFailedEvent(e.Err)
+		notification.Branch = e.Branch
+		notification.state = e.Err.Error()
+		n.notify(notification)
+	case events.SubmoduleDrift:
+		n.NotifySubmoduleDrift(e.Submodule, e.Upstream, e.Commits)
+	case events.StashNotRestored:
+		n.NotifyStashNotRestored(e.Branch, e.StashRef, e.OID, e.Applied)
 	}
+}
 
-	scriptPath := filepath.Join(harbingerDir, "notify.ps1")
+func (n *Notifier) NotifyRemoteChange(branch, commit string) {
+	n.notify(Event{
+		Type:    EventRemoteChange,
+		Level:   LevelInfo,
+		Title:   "Remote Branch Updated",
+		Message: fmt.Sprintf("Branch '%s' has new commits on remote\nLatest: %s", branch, commit[:7]),
+		Branch:  branch,
+	})
+}
 
-	// Write the script to a temporary file
-	if err := os.WriteFile(scriptPath, []byte(scriptContent), 0644); err != nil {
-		log.Printf("Error writing PowerShell script: %v", err)
-		return
+func (n *Notifier) NotifyOutOfSync(branch, localCommit, remoteCommit string) {
+	n.notify(Event{
+		Type:  EventOutOfSync,
+		Level: LevelWarn,
+		Title: "Branch Out of Sync",
+		Message: fmt.Sprintf("Branch '%s' is out of sync\nLocal: %s\nRemote: %s",
+			branch, localCommit[:7], remoteCommit[:7]),
+		Branch: branch,
+	})
+}
+
+func (n *Notifier) NotifyConflicts(count int) {
+	n.notify(conflictsEvent(count))
+}
+
+func conflictsEvent(count int) Event {
+	return Event{
+		Type:    EventConflicts,
+		Level:   LevelError,
+		Title:   "Merge Conflicts Detected",
+		Message: fmt.Sprintf("Found %d potential merge conflicts that need resolution", count),
+		Count:   count,
 	}
+}
 
-	// Convert WSL path to Windows path for PowerShell
-	windowsScriptPath, err := n.convertWSLPathToWindows(scriptPath)
-	if err != nil {
-		log.Printf("Error converting WSL path: %v", err)
-		return
+func (n *Notifier) NotifyInSync(branch string) {
+	n.notify(Event{
+		Type:    EventInSync,
+		Level:   LevelSuccess,
+		Title:   "Branch In Sync",
+		Message: fmt.Sprintf("Branch '%s' is up to date with remote", branch),
+		Branch:  branch,
+	})
+}
+
+func (n *Notifier) NotifyAutoPull(branch string, commitCount int) {
+	n.notify(Event{
+		Type:    EventAutoPull,
+		Level:   LevelSuccess,
+		Title:   "Auto-Pull Completed",
+		Message: fmt.Sprintf("Pulled %d commit(s) into branch '%s'", commitCount, branch),
+		Branch:  branch,
+		Count:   commitCount,
+	})
+}
+
+func (n *Notifier) NotifyBehindRemote(branch string, commitCount int) {
+	n.notify(behindRemoteEvent(branch, commitCount))
+}
+
+func behindRemoteEvent(branch string, commitCount int) Event {
+	return Event{
+		Type:    EventBehindRemote,
+		Level:   LevelInfo,
+		Title:   "Branch Behind Remote",
+		Message: fmt.Sprintf("Branch '%s' is %d commit(s) behind remote", branch, commitCount),
+		Branch:  branch,
+		Count:   commitCount,
 	}
+}
 
-	// Execute the PowerShell script with Windows paths
-	cmd := exec.Command("powershell.exe", "-ExecutionPolicy", "Bypass", "-File", windowsScriptPath, "-Title", title, "-Message", message)
-	if err := cmd.Run(); err != nil {
-		log.Printf("Error executing PowerShell notification: %v", err)
+func (n *Notifier) NotifySubmoduleDrift(submodule, upstream string, commitCount int) {
+	n.notify(Event{
+		Type:    EventSubmoduleDrift,
+		Level:   LevelInfo,
+		Title:   "Submodule Behind Upstream",
+		Message: fmt.Sprintf("Submodule '%s' is %d commit(s) behind %s", submodule, commitCount, upstream),
+		Count:   commitCount,
+	})
+}
+
+// NotifyStashNotRestored reports auto-stashed changes kept in the stash entry
+// stashRef (commit oid), which were applied with conflicts or not at all
+func (n *Notifier) NotifyStashNotRestored(branch, stashRef, oid string, applied bool) {
+	recovery := fmt.Sprintf("Nothing was applied; run git stash apply %s once the files are clean", oid)
+	if applied {
+		recovery = fmt.Sprintf("They were applied with conflicts: resolve them, then run git stash drop %s", stashRef)
 	}
+	n.notify(Event{
+		Type:    EventStashNotRestored,
+		Level:   LevelWarn,
+		Title:   "Auto-Stash Not Restored",
+		Message: fmt.Sprintf("Your changes on '%s' conflict with the synced commits and are kept in %s (%s)\n%s\nSee the harbinger log for recovery steps", branch, stashRef, oid, recovery),
+		Branch:  branch,
+	})
 }
 
-// convertWSLPathToWindows converts a WSL path to Windows path
-func (n *Notifier) convertWSLPathToWindows(wslPath string) (string, error) {
-	cmd := exec.Command("wslpath", "-w", wslPath)
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to convert WSL path: %w", err)
+func (n *Notifier) NotifyAutoSyncFailed(branch string, err error) {
+	n.notify(autoSyncFailedEvent(branch, err))
+}
+
+func autoSyncFailedEvent(branch string, err error) Event {
+	return Event{
+		Type:    EventAutoSyncFailed,
+		Level:   LevelWarn,
+		Title:   "Auto-Sync Failed",
+		Message: fmt.Sprintf("Could not sync branch '%s': %v", branch, err),
+		Branch:  branch,
 	}
-	return string(bytes.TrimSpace(output)), nil
 }
 
-// isWSL checks if the current environment is Windows Subsystem for Linux
-func isWSL(procVersionPath string) bool {
-	if runtime.GOOS == "linux" {
-		content, err := os.ReadFile(procVersionPath)
-		if err != nil {
-			return false
-		}
-		if bytes.Contains(content, []byte("microsoft")) || bytes.Contains(content, []byte("Microsoft")) {
-			return true
-		}
+func (n *Notifier) NotifyFetchFailed(err error) {
+	n.notify(fetchFailedEvent(err))
+}
+
+func fetchFailedEvent(err error) Event {
+	return Event{
+		Type:    EventFetchFailed,
+		Level:   LevelError,
+		Title:   "Fetch Failed",
+		Message: fmt.Sprintf("Could not fetch remote changes: %v", err),
 	}
-	return false
 }
@@ -73,6 +73,13 @@ func TestNotifier_New(t *testing.T) {
 	// without making it exported, but we can verify the notifier is created
 }
 
+func TestNotifier_DesktopDisabled(t *testing.T) {
+	notifier := NewWithOptions(Options{Desktop: false})
+	for _, sink := range notifier.sinks {
+		assert.NotEqual(t, "desktop", sink.Name())
+	}
+}
+
 func TestNotifier_NotificationMethods(t *testing.T) {
 	notifier := New()
 
@@ -120,11 +127,11 @@ func TestConvertWSLPathToWindows(t *testing.T) {
 		t.Skip("WSL path conversion only applies to Linux")
 	}
 
-	notifier := New()
+	desktop := NewDesktopSink()
 
 	// We can't easily test this without actual WSL environment
 	// but we can test that the method exists and handles errors
-	_, err := notifier.convertWSLPathToWindows("/some/path")
+	_, err := desktop.convertWSLPathToWindows("/some/path")
 	// This will likely fail in non-WSL environment, which is expected
 	assert.Error(t, err)
 }
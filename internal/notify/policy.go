@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/javanhut/harbinger/pkg/config"
+)
+
+// EventDigest is the type of the notification that batches several others
+const EventDigest = "digest"
+
+// Policy decides which notifications reach the sinks and when. Log sinks
+// are not affected: they get every event straight away.
+type Policy struct {
+	// Dedup drops a notification that reports the same state as the last
+	// one of its type for the branch, e.g. the same remote commit
+	Dedup bool
+	// RateLimits is the least time between two notifications of an event
+	// type; the "default" entry applies to the types not listed
+	RateLimits map[string]time.Duration
+	// Digest holds notifications back and sends them together once per period
+	Digest time.Duration
+	// QuietHours hold notifications back until the window ends
+	QuietHours []QuietHours
+}
+
+// QuietHours is a daily window, in minutes after local midnight
+type QuietHours struct {
+	Start, End int
+	// Days the window starts on, every day if empty
+	Days []time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// PolicyFromConfig checks the notification_* and quiet_hours config
+func PolicyFromConfig(cfg *config.Config) (Policy, error) {
+	policy := Policy{
+		Dedup:  cfg.NotificationDedup,
+		Digest: time.Duration(cfg.NotificationDigest),
+	}
+
+	if len(cfg.NotificationRateLimits) > 0 {
+		known := map[string]bool{"default": true}
+		for _, eventType := range EventTypes() {
+			known[eventType] = true
+		}
+		policy.RateLimits = map[string]time.Duration{}
+		for eventType, limit := range cfg.NotificationRateLimits {
+			if !known[eventType] {
+				return Policy{}, fmt.Errorf("notification_rate_limits: unknown event %q", eventType)
+			}
+			policy.RateLimits[eventType] = time.Duration(limit)
+		}
+	}
+
+	for i, window := range cfg.QuietHours {
+		quiet, err := parseQuietHours(window)
+		if err != nil {
+			return Policy{}, fmt.Errorf("quiet_hours[%d]: %w", i, err)
+		}
+		policy.QuietHours = append(policy.QuietHours, quiet)
+	}
+	return policy, nil
+}
+
+func parseQuietHours(cfg config.QuietHours) (QuietHours, error) {
+	var quiet QuietHours
+	for _, clock := range []struct {
+		value string
+		out   *int
+	}{{cfg.Start, &quiet.Start}, {cfg.End, &quiet.End}} {
+		t, err := time.Parse("15:04", clock.value)
+		if err != nil {
+			return QuietHours{}, fmt.Errorf("invalid time %q, expected HH:MM", clock.value)
+		}
+		*clock.out = t.Hour()*60 + t.Minute()
+	}
+
+	for _, day := range cfg.Days {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return QuietHours{}, fmt.Errorf("unknown day %q, expected mon, tue, wed, thu, fri, sat or sun", day)
+		}
+		quiet.Days = append(quiet.Days, weekday)
+	}
+	return quiet, nil
+}
+
+// rateLimit returns the least time between notifications of the type
+func (p Policy) rateLimit(eventType string) time.Duration {
+	if limit, ok := p.RateLimits[eventType]; ok {
+		return limit
+	}
+	return p.RateLimits["default"]
+}
+
+// quietUntil returns the end of the quiet hours t falls in, if any
+func (p Policy) quietUntil(t time.Time) (time.Time, bool) {
+	var until time.Time
+	for _, quiet := range p.QuietHours {
+		if end, ok := quiet.until(t); ok && end.After(until) {
+			until = end
+		}
+	}
+	return until, !until.IsZero()
+}
+
+// until returns the end of the window t falls in, which may have started
+// the day before
+func (q QuietHours) until(t time.Time) (time.Time, bool) {
+	for _, daysAgo := range []int{0, 1} {
+		day := t.AddDate(0, 0, -daysAgo)
+		if !q.startsOn(day.Weekday()) {
+			continue
+		}
+		start := time.Date(day.Year(), day.Month(), day.Day(), 0, q.Start, 0, 0, t.Location())
+		end := time.Date(day.Year(), day.Month(), day.Day(), 0, q.End, 0, 0, t.Location())
+		if q.End <= q.Start {
+			end = end.AddDate(0, 0, 1)
+		}
+		if !t.Before(start) && t.Before(end) {
+			return end, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (q QuietHours) startsOn(weekday time.Weekday) bool {
+	if len(q.Days) == 0 {
+		return true
+	}
+	for _, day := range q.Days {
+		if day == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// digest combines notifications into one, as severe as the most severe of them
+func digest(events []Event) Event {
+	combined := Event{
+		Type:       EventDigest,
+		Level:      LevelInfo,
+		Title:      fmt.Sprintf("%d Harbinger Notifications", len(events)),
+		Repository: events[0].Repository,
+		Count:      len(events),
+		Time:       events[len(events)-1].Time,
+	}
+	var lines []string
+	for _, event := range events {
+		if levelRanks[event.Level] > levelRanks[combined.Level] {
+			combined.Level = event.Level
+		}
+		message, _, _ := strings.Cut(event.Message, "\n")
+		lines = append(lines, fmt.Sprintf("%s: %s", event.Title, message))
+	}
+	combined.Message = strings.Join(lines, "\n")
+	return combined
+}
@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/javanhut/harbinger/internal/events"
+	"github.com/javanhut/harbinger/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyFromConfig(t *testing.T) {
+	policy, err := PolicyFromConfig(&config.Config{
+		NotificationDedup:      true,
+		NotificationRateLimits: map[string]config.Duration{"default": config.Duration(time.Minute), EventFetchFailed: config.Duration(time.Hour)},
+		NotificationDigest:     config.Duration(10 * time.Minute),
+		QuietHours:             []config.QuietHours{{Start: "22:00", End: "07:30", Days: []string{"Fri", "sat"}}},
+	})
+	require.NoError(t, err)
+	assert.True(t, policy.Dedup)
+	assert.Equal(t, time.Hour, policy.rateLimit(EventFetchFailed))
+	assert.Equal(t, time.Minute, policy.rateLimit(EventBehindRemote))
+	assert.Equal(t, 10*time.Minute, policy.Digest)
+	assert.Equal(t, []QuietHours{{Start: 22 * 60, End: 7*60 + 30, Days: []time.Weekday{time.Friday, time.Saturday}}}, policy.QuietHours)
+
+	for cfg, want := range map[*config.Config]string{
+		{NotificationRateLimits: map[string]config.Duration{"merged": 0}}:                            `unknown event "merged"`,
+		{QuietHours: []config.QuietHours{{Start: "10pm", End: "07:00"}}}:                             `quiet_hours[0]: invalid time "10pm"`,
+		{QuietHours: []config.QuietHours{{Start: "22:00", End: "07:00", Days: []string{"weekend"}}}}: `unknown day "weekend"`,
+	} {
+		_, err := PolicyFromConfig(cfg)
+		assert.ErrorContains(t, err, want)
+	}
+}
+
+func TestPolicy_QuietUntil(t *testing.T) {
+	policy := Policy{QuietHours: []QuietHours{
+		{Start: 22 * 60, End: 7 * 60},
+		{Start: 12 * 60, End: 13 * 60, Days: []time.Weekday{time.Saturday}},
+	}}
+	// 2024-06-07 is a Friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 6, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	until, quiet := policy.quietUntil(at(7, 23, 0))
+	assert.True(t, quiet)
+	assert.Equal(t, at(8, 7, 0), until)
+
+	until, quiet = policy.quietUntil(at(8, 6, 59))
+	assert.True(t, quiet, "the window started the day before")
+	assert.Equal(t, at(8, 7, 0), until)
+
+	_, quiet = policy.quietUntil(at(8, 7, 0))
+	assert.False(t, quiet)
+	_, quiet = policy.quietUntil(at(7, 12, 30))
+	assert.False(t, quiet, "only on Saturdays")
+	until, quiet = policy.quietUntil(at(8, 12, 30))
+	assert.True(t, quiet)
+	assert.Equal(t, at(8, 13, 0), until)
+}
+
+// newPolicyNotifier returns a notifier with a recording sink and a clock
+// the test moves forward
+func newPolicyNotifier(policy Policy) (*Notifier, *Recorder, *time.Time) {
+	recorder := &Recorder{}
+	notifier := NewWithOptions(Options{Sinks: []Sink{recorder}, Policy: policy})
+	clock := time.Date(2024, 6, 7, 9, 0, 0, 0, time.Local)
+	notifier.now = func() time.Time { return clock }
+	return notifier, recorder, &clock
+}
+
+func TestNotifier_Dedup(t *testing.T) {
+	notifier, recorder, _ := newPolicyNotifier(Policy{Dedup: true})
+	meta := events.Meta{Branch: "main"}
+
+	notifier.Handle(events.BehindRemote{Meta: meta, Commit: "aaa", Commits: 1})
+	notifier.Handle(events.BehindRemote{Meta: meta, Commit: "aaa", Commits: 1})
+	notifier.Handle(events.BehindRemote{Meta: meta, Commit: "bbb", Commits: 2})
+	notifier.Handle(events.ConflictsPredicted{Meta: meta, Files: []string{"b.go", "a.go"}})
+	notifier.Handle(events.ConflictsPredicted{Meta: meta, Files: []string{"a.go", "b.go"}})
+	notifier.Handle(events.FetchFailed{Meta: meta, Err: errors.New("offline")})
+	notifier.Handle(events.FetchFailed{Meta: meta, Err: errors.New("offline")})
+	notifier.Handle(events.BecameInSync{Meta: meta})
+	notifier.Handle(events.BehindRemote{Meta: meta, Commit: "bbb", Commits: 2})
+	notifier.Handle(events.FetchFailed{Meta: meta, Err: errors.New("offline")})
+
+	var types []string
+	for _, event := range recorder.Events() {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{
+		EventBehindRemote, EventBehindRemote, EventConflicts, EventFetchFailed, EventInSync, EventBehindRemote, EventFetchFailed,
+	}, types, "being in sync and fetching again end the earlier states")
+
+	notifier.Configure(Options{Sinks: []Sink{recorder}})
+	notifier.Handle(events.BehindRemote{Meta: meta, Commit: "bbb", Commits: 2})
+	assert.Len(t, recorder.Events(), 8, "without dedup every event is sent")
+}
+
+func TestNotifier_RateLimits(t *testing.T) {
+	notifier, recorder, clock := newPolicyNotifier(Policy{RateLimits: map[string]time.Duration{
+		"default":         time.Hour,
+		EventConflicts:    0,
+		EventBehindRemote: 10 * time.Minute,
+	}})
+
+	notifier.NotifyBehindRemote("main", 1)
+	notifier.NotifyBehindRemote("main", 2)
+	notifier.NotifyConflicts(1)
+	notifier.NotifyConflicts(1)
+	notifier.NotifyAutoPull("main", 1)
+	*clock = clock.Add(10 * time.Minute)
+	notifier.NotifyBehindRemote("main", 3)
+	notifier.NotifyAutoPull("main", 1)
+
+	var counts []int
+	for _, event := range recorder.Events() {
+		counts = append(counts, event.Count)
+	}
+	assert.Equal(t, []int{1, 1, 1, 1, 3}, counts)
+}
+
+func TestNotifier_DigestAndQuietHours(t *testing.T) {
+	notifier, recorder, clock := newPolicyNotifier(Policy{Digest: time.Hour})
+	notifier.NotifyBehindRemote("main", 2)
+	notifier.NotifyConflicts(3)
+	assert.Empty(t, recorder.Events(), "held for the digest")
+
+	notifier.Flush()
+	require.Len(t, recorder.Events(), 1)
+	combined := recorder.Events()[0]
+	assert.Equal(t, EventDigest, combined.Type)
+	assert.Equal(t, LevelError, combined.Level)
+	assert.Equal(t, 2, combined.Count)
+	assert.Equal(t, "Branch Behind Remote: Branch 'main' is 2 commit(s) behind remote\nMerge Conflicts Detected: Found 3 potential merge conflicts that need resolution", combined.Message)
+
+	quiet := Policy{QuietHours: []QuietHours{{Start: 8 * 60, End: 10 * 60}}}
+	notifier.Configure(Options{Sinks: []Sink{recorder}, Policy: quiet})
+	notifier.NotifyInSync("main")
+	assert.Len(t, recorder.Events(), 1, "held during quiet hours")
+
+	*clock = clock.Add(2 * time.Hour)
+	notifier.flushHeld()
+	require.Len(t, recorder.Events(), 2)
+	assert.Equal(t, EventInSync, recorder.Events()[1].Type, "a single held event is sent as it is")
+}
+
+func TestNotifier_PolicySkipsLog(t *testing.T) {
+	logged := &Recorder{}
+	notifier := NewWithOptions(Options{
+		Sinks:  []Sink{namedSink{Sink: logged, name: "log"}},
+		Policy: Policy{Dedup: true, Digest: time.Hour},
+	})
+	defer notifier.Flush()
+
+	notifier.Handle(events.BehindRemote{Meta: events.Meta{Branch: "main"}, Commit: "aaa", Commits: 1})
+	notifier.Handle(events.BehindRemote{Meta: events.Meta{Branch: "main"}, Commit: "aaa", Commits: 1})
+	assert.Len(t, logged.Events(), 2)
+}
+
+type namedSink struct {
+	Sink
+	name string
+}
+
+func (s namedSink) Name() string {
+	return s.name
+}
@@ -0,0 +1,221 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/javanhut/harbinger/pkg/config"
+)
+
+// Sink delivers events to one notification backend
+type Sink interface {
+	Name() string
+	Send(event Event) error
+}
+
+// backgroundSink is implemented by sinks that can block for a while, such as
+// network backends. The notifier sends to them from a goroutine so a slow
+// endpoint does not hold up the monitor.
+type backgroundSink interface {
+	Background() bool
+}
+
+// flushingSink is implemented by sinks that hold events back themselves,
+// such as an email digest. The notifier flushes them when it is flushed.
+type flushingSink interface {
+	Flush() error
+}
+
+// SinkFactory creates a sink from its notifiers config entry
+type SinkFactory func(cfg config.NotifierConfig) (Sink, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]SinkFactory{}
+)
+
+// Register makes a sink type available to the notifiers config. It panics
+// if the type is registered twice.
+func Register(sinkType string, factory SinkFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := registry[sinkType]; exists {
+		panic(fmt.Sprintf("notify: sink type %q registered twice", sinkType))
+	}
+	registry[sinkType] = factory
+}
+
+// SinkTypes returns the registered sink types, sorted
+func SinkTypes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	types := make([]string, 0, len(registry))
+	for sinkType := range registry {
+		types = append(types, sinkType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// NewSink creates a sink of the configured type, limited to the events its
+// filter accepts
+func NewSink(cfg config.NotifierConfig) (Sink, error) {
+	registryMu.RLock()
+	factory, ok := registry[cfg.Type]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown notifier type %q, expected one of %v", cfg.Type, SinkTypes())
+	}
+
+	filter, err := NewFilter(cfg.EventFilter)
+	if err != nil {
+		return nil, err
+	}
+	sink, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s notifier: %w", cfg.Type, err)
+	}
+	return Filtered(sink, filter), nil
+}
+
+func init() {
+	Register("log", func(config.NotifierConfig) (Sink, error) {
+		return LogSink{}, nil
+	})
+	Register("desktop", func(config.NotifierConfig) (Sink, error) {
+		return NewDesktopSink(), nil
+	})
+	Register("webhook", func(cfg config.NotifierConfig) (Sink, error) {
+		return webhookFromConfig(cfg.WebhookConfig)
+	})
+	Register("command", func(cfg config.NotifierConfig) (Sink, error) {
+		return NewCommandSink(cfg.Command, time.Duration(cfg.Timeout))
+	})
+	Register("email", emailFromConfig)
+}
+
+// Filter selects the events a sink receives by type and level
+type Filter struct {
+	events   map[string]bool
+	minLevel int
+}
+
+// NewFilter checks an events and min_level config
+func NewFilter(cfg config.EventFilter) (Filter, error) {
+	var filter Filter
+	if cfg.MinLevel != "" {
+		rank, ok := levelRanks[cfg.MinLevel]
+		if !ok {
+			return Filter{}, fmt.Errorf("unknown min_level %q, expected info, warn or error", cfg.MinLevel)
+		}
+		filter.minLevel = rank
+	}
+
+	if len(cfg.Events) > 0 {
+		known := map[string]bool{}
+		for _, eventType := range EventTypes() {
+			known[eventType] = true
+		}
+		filter.events = map[string]bool{}
+		for _, eventType := range cfg.Events {
+			if !known[eventType] {
+				return Filter{}, fmt.Errorf("unknown event %q", eventType)
+			}
+			filter.events[eventType] = true
+		}
+	}
+	return filter, nil
+}
+
+// Accepts reports whether the event passes the filter
+func (f Filter) Accepts(event Event) bool {
+	if f.events != nil && !f.events[event.Type] {
+		return false
+	}
+	return levelRanks[event.Level] >= f.minLevel
+}
+
+// Filtered limits a sink to the events the filter accepts
+func Filtered(sink Sink, filter Filter) Sink {
+	return filteredSink{Sink: sink, filter: filter}
+}
+
+type filteredSink struct {
+	Sink
+	filter Filter
+}
+
+// Accepts also applies the filter of a sink that was filtered already, such
+// as an email sink's default events
+func (s filteredSink) Accepts(event Event) bool {
+	return accepts(s.Sink, event) && s.filter.Accepts(event)
+}
+
+func (s filteredSink) Background() bool {
+	background, ok := s.Sink.(backgroundSink)
+	return ok && background.Background()
+}
+
+func (s filteredSink) Flush() error {
+	if flusher, ok := s.Sink.(flushingSink); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// LogSink writes events to the log, which is where detached monitors keep
+// their output
+type LogSink struct{}
+
+func (LogSink) Name() string {
+	return "log"
+}
+
+func (LogSink) Send(event Event) error {
+	log.Printf("%s %s: %s", logPrefixes[event.Level], event.Title, event.Message)
+	return nil
+}
+
+// Recorder is a sink that keeps every event, for tests
+type Recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *Recorder) Name() string {
+	return "recorder"
+}
+
+func (r *Recorder) Send(event Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+// Events returns the events recorded so far
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// webhookFromConfig creates a webhook, expanding ${VAR} in its URL and headers
+func webhookFromConfig(cfg config.WebhookConfig) (*Webhook, error) {
+	headers := make(map[string]string, len(cfg.Headers))
+	for name, value := range cfg.Headers {
+		headers[name] = os.ExpandEnv(value)
+	}
+	return NewWebhook(WebhookOptions{
+		URL:      os.ExpandEnv(cfg.URL),
+		Headers:  headers,
+		Template: cfg.Template,
+		Retries:  cfg.Retries,
+		Backoff:  time.Duration(cfg.Backoff),
+		Timeout:  time.Duration(cfg.Timeout),
+	})
+}
@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/javanhut/harbinger/internal/events"
+	"github.com/javanhut/harbinger/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	assert.Equal(t, []string{"command", "desktop", "email", "log", "webhook"}, SinkTypes())
+	assert.Panics(t, func() {
+		Register("log", func(config.NotifierConfig) (Sink, error) { return LogSink{}, nil })
+	})
+
+	_, err := NewSink(config.NotifierConfig{Type: "pager"})
+	assert.ErrorContains(t, err, `unknown notifier type "pager"`)
+	_, err = NewSink(config.NotifierConfig{Type: "command"})
+	assert.ErrorContains(t, err, "command is required")
+}
+
+func TestFilter(t *testing.T) {
+	filter, err := NewFilter(config.EventFilter{MinLevel: "warn"})
+	require.NoError(t, err)
+	assert.False(t, filter.Accepts(Event{Type: EventBehindRemote, Level: LevelInfo}))
+	assert.False(t, filter.Accepts(Event{Type: EventInSync, Level: LevelSuccess}))
+	assert.True(t, filter.Accepts(Event{Type: EventStashNotRestored, Level: LevelWarn}))
+	assert.True(t, filter.Accepts(Event{Type: EventConflicts, Level: LevelError}))
+
+	filter, err = NewFilter(config.EventFilter{Events: []string{EventInSync, EventConflicts}, MinLevel: "info"})
+	require.NoError(t, err)
+	assert.True(t, filter.Accepts(Event{Type: EventInSync, Level: LevelSuccess}))
+	assert.False(t, filter.Accepts(Event{Type: EventBehindRemote, Level: LevelInfo}))
+
+	_, err = NewFilter(config.EventFilter{Events: []string{"merged"}})
+	assert.ErrorContains(t, err, `unknown event "merged"`)
+	_, err = NewFilter(config.EventFilter{MinLevel: "critical"})
+	assert.ErrorContains(t, err, "unknown min_level")
+}
+
+func TestNotifier_SinksAndFilters(t *testing.T) {
+	all, errorsOnly := &Recorder{}, &Recorder{}
+	filter, err := NewFilter(config.EventFilter{MinLevel: "error"})
+	require.NoError(t, err)
+
+	notifier := NewWithOptions(Options{Repository: "/src/api", Sinks: []Sink{all, Filtered(errorsOnly, filter)}})
+	notifier.NotifyBehindRemote("main", 2)
+	notifier.NotifyConflicts(1)
+
+	require.Len(t, all.Events(), 2)
+	assert.Equal(t, EventBehindRemote, all.Events()[0].Type)
+	assert.Equal(t, "/src/api", all.Events()[0].Repository)
+	require.Len(t, errorsOnly.Events(), 1)
+	assert.Equal(t, EventConflicts, errorsOnly.Events()[0].Type)
+
+	// Events are logged unless a log sink is configured
+	assert.Equal(t, "log", notifier.sinks[0].Name())
+	logged := NewWithOptions(Options{Sinks: []Sink{LogSink{}}})
+	assert.Len(t, logged.sinks, 1)
+}
+
+func TestOptionsFromConfig_Notifiers(t *testing.T) {
+	cfg := &config.Config{
+		Notifications: true,
+		Notifiers: []config.NotifierConfig{
+			{Type: "desktop", WebhookConfig: config.WebhookConfig{EventFilter: config.EventFilter{MinLevel: "warn"}}},
+			{Type: "log", WebhookConfig: config.WebhookConfig{EventFilter: config.EventFilter{Events: []string{EventConflicts}}}},
+		},
+	}
+	options, err := OptionsFromConfig(cfg)
+	require.NoError(t, err)
+	assert.False(t, options.Desktop, "a desktop notifier replaces the default one")
+	require.Len(t, options.Sinks, 2)
+	assert.Len(t, NewWithOptions(options).sinks, 2, "a log notifier replaces the default log")
+
+	cfg.Notifications = false
+	options, err = OptionsFromConfig(cfg)
+	require.NoError(t, err)
+	require.Len(t, options.Sinks, 1, "notifications: false turns desktop notifiers off")
+	assert.Equal(t, "log", options.Sinks[0].Name())
+
+	cfg.Notifiers = append(cfg.Notifiers, config.NotifierConfig{Type: "log", WebhookConfig: config.WebhookConfig{EventFilter: config.EventFilter{MinLevel: "loud"}}})
+	_, err = OptionsFromConfig(cfg)
+	assert.ErrorContains(t, err, "notifiers[2]")
+}
+
+func TestCommandSink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "notify.sh")
+	out := filepath.Join(dir, "out")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\ncat > \"$1\"\necho \"$HARBINGER_EVENT_TYPE $HARBINGER_EVENT_COUNT\" >> \"$1\"\n"), 0755))
+
+	sink, err := NewSink(config.NotifierConfig{Type: "command", Command: script + " " + out})
+	require.NoError(t, err)
+	notifier := NewWithOptions(Options{Sinks: []Sink{sink}})
+	notifier.NotifyConflicts(3)
+	notifier.Wait()
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	var event Event
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	require.NoError(t, decoder.Decode(&event))
+	assert.Equal(t, EventConflicts, event.Type)
+	assert.Contains(t, string(data), "conflicts 3")
+
+	failing, err := NewCommandSink("false", 0)
+	require.NoError(t, err)
+	assert.Error(t, failing.Send(Event{}))
+}
+
+func TestNotifier_Handle(t *testing.T) {
+	recorder := &Recorder{}
+	notifier := NewWithOptions(Options{Sinks: []Sink{recorder}})
+
+	meta := events.Meta{Branch: "main"}
+	notifier.Handle(events.BranchSwitched{Meta: meta, From: "feature"})
+	notifier.Handle(events.RemoteAdvanced{Meta: meta, From: "1111111111", To: "2222222222"})
+	notifier.Handle(events.ConflictsPredicted{Meta: meta, Files: []string{"a.go", "b.go"}})
+	notifier.Handle(events.AutoSyncFailed{Meta: meta, Option: "auto_sync", Err: errors.New("uncommitted changes")})
+	notifier.Handle(events.FetchFailed{Meta: meta, Err: errors.New("offline")})
+
+	require.Len(t, recorder.Events(), 4, "branch switches are only logged")
+	assert.Equal(t, EventRemoteChange, recorder.Events()[0].Type)
+	assert.Contains(t, recorder.Events()[0].Message, "2222222")
+	assert.Equal(t, 2, recorder.Events()[1].Count)
+	assert.Equal(t, EventAutoSyncFailed, recorder.Events()[2].Type)
+	assert.Contains(t, recorder.Events()[2].Message, "uncommitted changes")
+	assert.Equal(t, EventFetchFailed, recorder.Events()[3].Type)
+	assert.Equal(t, LevelError, recorder.Events()[3].Level)
+}
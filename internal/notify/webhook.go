@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"text/template"
+	"time"
+)
+
+const (
+	// DefaultWebhookTimeout limits each delivery attempt
+	DefaultWebhookTimeout = 10 * time.Second
+	// DefaultWebhookBackoff is the wait before the first retry
+	DefaultWebhookBackoff = time.Second
+)
+
+// WebhookOptions configures a Webhook
+type WebhookOptions struct {
+	URL     string
+	Headers map[string]string
+	// Template is a text/template for the request body, executed with the
+	// Event. Use {{json .Message}} to insert a JSON-quoted value. The event
+	// itself is sent as JSON when Template is empty.
+	Template string
+	Retries  int // Extra attempts after a failed delivery
+	Backoff  time.Duration
+	Timeout  time.Duration
+	// Client sends the requests, a client with Timeout by default
+	Client *http.Client
+}
+
+// Webhook posts events to an HTTP endpoint
+type Webhook struct {
+	options  WebhookOptions
+	host     string // Logged instead of the URL, which often holds a token
+	template *template.Template
+	client   *http.Client
+}
+
+// NewWebhook checks the options and creates a webhook
+func NewWebhook(options WebhookOptions) (*Webhook, error) {
+	u, err := url.Parse(options.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook url %q: expected an http or https URL", options.URL)
+	}
+	if options.Retries < 0 {
+		return nil, fmt.Errorf("webhook retries must not be negative")
+	}
+	if options.Backoff <= 0 {
+		options.Backoff = DefaultWebhookBackoff
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultWebhookTimeout
+	}
+
+	w := &Webhook{options: options, host: u.Host, client: options.Client}
+	if w.client == nil {
+		w.client = &http.Client{Timeout: options.Timeout}
+	}
+
+	if options.Template != "" {
+		w.template, err = template.New("webhook").Funcs(template.FuncMap{"json": toJSON}).Parse(options.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook template: %w", err)
+		}
+	}
+	return w, nil
+}
+
+func (w *Webhook) Name() string {
+	return "webhook " + w.host
+}
+
+func (w *Webhook) Background() bool {
+	return true
+}
+
+// Send posts the event, retrying network errors, 429 and 5xx responses with
+// exponential backoff
+func (w *Webhook) Send(event Event) error {
+	body, err := w.render(event)
+	if err != nil {
+		return err
+	}
+
+	backoff := w.options.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.options.Retries {
+			return fmt.Errorf("%w (after %d attempt(s))", err, attempt+1)
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (w *Webhook) render(event Event) ([]byte, error) {
+	if w.template == nil {
+		return json.Marshal(event)
+	}
+	var buf bytes.Buffer
+	if err := w.template.Execute(&buf, event); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// post sends one request, reporting whether a failure is worth retrying
+func (w *Webhook) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.options.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "harbinger")
+	for name, value := range w.options.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected response %s", resp.Status)
+}
+
+func toJSON(value interface{}) (string, error) {
+	out, err := json.Marshal(value)
+	return string(out), err
+}
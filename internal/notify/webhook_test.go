@@ -0,0 +1,182 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/javanhut/harbinger/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingServer answers with the given statuses in turn, then 200, and
+// keeps every request body
+type recordingServer struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   []string
+	headers  []http.Header
+}
+
+func newRecordingServer(t *testing.T, statuses ...int) (*recordingServer, *httptest.Server) {
+	rec := &recordingServer{statuses: statuses}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.bodies = append(rec.bodies, string(body))
+		rec.headers = append(rec.headers, r.Header.Clone())
+		status := http.StatusOK
+		if len(rec.statuses) > 0 {
+			status, rec.statuses = rec.statuses[0], rec.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return rec, server
+}
+
+func TestWebhook_SendsEventAsJSON(t *testing.T) {
+	rec, server := newRecordingServer(t)
+	hook, err := NewWebhook(WebhookOptions{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer secret"}})
+	require.NoError(t, err)
+
+	notifier := NewWithOptions(Options{Repository: "/src/api", Sinks: []Sink{hook}})
+	notifier.NotifyBehindRemote("main", 3)
+	notifier.Wait()
+
+	require.Len(t, rec.bodies, 1)
+	var event Event
+	require.NoError(t, json.Unmarshal([]byte(rec.bodies[0]), &event))
+	assert.Equal(t, EventBehindRemote, event.Type)
+	assert.Equal(t, LevelInfo, event.Level)
+	assert.Equal(t, "/src/api", event.Repository)
+	assert.Equal(t, "main", event.Branch)
+	assert.Equal(t, 3, event.Count)
+	assert.False(t, event.Time.IsZero())
+	assert.Equal(t, "Bearer secret", rec.headers[0].Get("Authorization"))
+	assert.Equal(t, "application/json", rec.headers[0].Get("Content-Type"))
+}
+
+func TestWebhook_TemplateAndEvents(t *testing.T) {
+	rec, server := newRecordingServer(t)
+	hook, err := NewWebhook(WebhookOptions{
+		URL:      server.URL,
+		Template: `{"text": {{json (printf "%s: %s" .Title .Message)}}}`,
+	})
+	require.NoError(t, err)
+	filter, err := NewFilter(config.EventFilter{Events: []string{EventConflicts}})
+	require.NoError(t, err)
+
+	notifier := NewWithOptions(Options{Sinks: []Sink{Filtered(hook, filter)}})
+	notifier.NotifyInSync("main")
+	notifier.NotifyConflicts(2)
+	notifier.Wait()
+
+	require.Len(t, rec.bodies, 1, "only subscribed events are sent")
+	assert.JSONEq(t, `{"text": "Merge Conflicts Detected: Found 2 potential merge conflicts that need resolution"}`, rec.bodies[0])
+}
+
+func TestWebhook_Retries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		attempts int
+		wantErr  string
+	}{
+		{"recovers after server errors", []int{500, 503}, 2, 3, ""},
+		{"retries rate limits", []int{429}, 1, 2, ""},
+		{"gives up after the last retry", []int{502, 502, 502}, 1, 2, "after 2 attempt(s)"},
+		{"does not retry client errors", []int{400}, 3, 1, "400 Bad Request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, server := newRecordingServer(t, tt.statuses...)
+			hook, err := NewWebhook(WebhookOptions{URL: server.URL, Retries: tt.retries, Backoff: time.Millisecond})
+			require.NoError(t, err)
+
+			err = hook.Send(Event{Type: EventConflicts})
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, rec.bodies, tt.attempts)
+		})
+	}
+}
+
+func TestWebhook_RetriesNetworkErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	var attempts int32
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&attempts, 1)
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	hook, err := NewWebhook(WebhookOptions{URL: url, Retries: 2, Backoff: time.Millisecond, Client: client})
+	require.NoError(t, err)
+
+	assert.Error(t, hook.Send(Event{}))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestNewWebhook_Invalid(t *testing.T) {
+	for name, options := range map[string]WebhookOptions{
+		"missing url":  {},
+		"not http":     {URL: "ftp://example.com"},
+		"bad template": {URL: "https://example.com", Template: "{{.Title"},
+		"retries":      {URL: "https://example.com", Retries: -1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewWebhook(options)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestOptionsFromConfig_Webhooks(t *testing.T) {
+	rec, server := newRecordingServer(t)
+	t.Setenv("CHAT_TOKEN", "s3cret")
+
+	options, err := OptionsFromConfig(&config.Config{
+		Notifications: false,
+		Webhooks: []config.WebhookConfig{{
+			URL:     server.URL + "/hooks",
+			Headers: map[string]string{"X-Token": "${CHAT_TOKEN}"},
+		}},
+	})
+	require.NoError(t, err)
+	assert.False(t, options.Desktop)
+	require.Len(t, options.Sinks, 1)
+
+	require.NoError(t, options.Sinks[0].Send(Event{Type: EventInSync}))
+	assert.Equal(t, "s3cret", rec.headers[0].Get("X-Token"))
+
+	_, err = OptionsFromConfig(&config.Config{Webhooks: []config.WebhookConfig{{URL: "chat"}}})
+	assert.ErrorContains(t, err, "webhooks[0]")
+}
+
+func TestEventTypes_MatchConfigSchema(t *testing.T) {
+	schema, err := config.Schema()
+	require.NoError(t, err)
+	for _, eventType := range EventTypes() {
+		assert.Contains(t, string(schema), `"`+eventType+`"`, "webhooks.events should list %s", eventType)
+	}
+}
@@ -1,20 +1,246 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
-
-	"gopkg.in/yaml.v3"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	PollInterval   string   `yaml:"poll_interval"`
+	PollInterval   Duration `yaml:"poll_interval"`
 	Editor         string   `yaml:"editor"`
 	Notifications  bool     `yaml:"notifications"`
 	IgnoreBranches []string `yaml:"ignore_branches"`
 	AutoResolve    bool     `yaml:"auto_resolve"`
 	AutoSync       bool     `yaml:"auto_sync"`
-	AutoPull       bool     `yaml:"auto_pull"` // Deprecated: use auto_sync instead
+	AutoPull       bool     `yaml:"auto_pull"`     // Deprecated: use auto_sync instead
+	SyncStrategy   string   `yaml:"sync_strategy"` // "merge", "rebase" or "ff-only"
+	AutoStash      bool     `yaml:"auto_stash"`    // Stash uncommitted changes around auto-sync
+
+	// ResolveRules pick a resolution strategy for conflicted files by pattern
+	ResolveRules []ResolveRule `yaml:"resolve_rules,omitempty"`
+
+	// Regenerators enable resolving generated files such as lockfiles by
+	// taking one side and re-running the tool that produces them
+	Regenerators []RegeneratorConfig `yaml:"regenerators,omitempty"`
+
+	// Repositories lists the repositories watched by the harbinger daemon
+	Repositories []RepositoryConfig `yaml:"repositories,omitempty"`
+
+	// Webhooks post notifications to HTTP endpoints such as a team chat
+	Webhooks []WebhookConfig `yaml:"webhooks,omitempty"`
+
+	// Notifiers enable further notification backends, each with its own filter
+	Notifiers []NotifierConfig `yaml:"notifiers,omitempty"`
+
+	// NotificationDedup repeats a notification only when the state it reports,
+	// such as the remote commit or the set of conflicting files, changes
+	NotificationDedup bool `yaml:"notification_dedup"`
+	// NotificationRateLimits is the least time between two notifications of
+	// an event type; "default" applies to the types not listed
+	NotificationRateLimits map[string]Duration `yaml:"notification_rate_limits,omitempty"`
+	// NotificationDigest batches notifications and sends them together once
+	// per period
+	NotificationDigest Duration `yaml:"notification_digest,omitempty"`
+	// QuietHours hold notifications back until the window ends
+	QuietHours []QuietHours `yaml:"quiet_hours,omitempty"`
+
+	// Hooks run shell commands when a monitor publishes an event, by event
+	// name, e.g. auto_pull: [make test]
+	Hooks map[string][]string `yaml:"hooks,omitempty"`
+	// HookTimeout stops a hook command that runs longer
+	HookTimeout Duration `yaml:"hook_timeout,omitempty"`
+	// HookConcurrency is how many hook commands a monitor runs at once
+	HookConcurrency int `yaml:"hook_concurrency,omitempty"`
+}
+
+// QuietHours is a daily do-not-disturb window in local time. An end before
+// the start ends the next day.
+type QuietHours struct {
+	Start string   `yaml:"start"`          // e.g. "22:00"
+	End   string   `yaml:"end"`            // e.g. "07:00"
+	Days  []string `yaml:"days,omitempty"` // Days the window starts on, e.g. [sat, sun]; every day by default
+}
+
+// RegeneratorConfig enables a regenerator. Fields left empty fall back to
+// the built-in regenerator of the same name (go, npm, yarn or cargo).
+type RegeneratorConfig struct {
+	Name    string   `yaml:"name"`
+	Files   []string `yaml:"files,omitempty"`
+	Command string   `yaml:"command,omitempty"` // Split on spaces, not run through a shell
+	Side    string   `yaml:"side,omitempty"`    // "ours" or "theirs"
+}
+
+// ResolveRule resolves conflicted files matching Pattern with Strategy
+// ("ours", "theirs" or "union") without asking
+type ResolveRule struct {
+	Pattern  string `yaml:"pattern"`
+	Strategy string `yaml:"strategy"`
+}
+
+// RepositoryConfig describes a single repository monitored by the daemon
+type RepositoryConfig struct {
+	Path         string   `yaml:"path"`
+	PollInterval Duration `yaml:"poll_interval,omitempty"` // Falls back to the global poll_interval
+	RemoteBranch string   `yaml:"remote_branch,omitempty"`
+	Remote       string   `yaml:"remote,omitempty"`
+	Worktrees    bool     `yaml:"worktrees,omitempty"`  // Monitor every worktree of the repository
+	Submodules   bool     `yaml:"submodules,omitempty"` // Report submodules behind their upstream
+}
+
+// WebhookConfig posts notifications to a URL. ${VAR} references in the URL
+// and header values are expanded from the environment, so secrets can stay
+// out of the file.
+type WebhookConfig struct {
+	URL      string            `yaml:"url"`
+	Headers  map[string]string `yaml:"headers,omitempty"`
+	Template string            `yaml:"template,omitempty"` // Go template for the request body, the event as JSON by default
+	Retries  int               `yaml:"retries,omitempty"`  // Extra attempts after a failed delivery
+	Backoff  Duration          `yaml:"backoff,omitempty"`  // Wait before the first retry, doubled for each one after it
+	Timeout  Duration          `yaml:"timeout,omitempty"`  // Per attempt
+
+	EventFilter `yaml:",inline"`
+}
+
+// EventFilter selects the notifications a backend receives
+type EventFilter struct {
+	Events   []string `yaml:"events,omitempty"`    // Event types to send, all of them by default
+	MinLevel string   `yaml:"min_level,omitempty"` // "info" (default), "warn" or "error"
+}
+
+// NotifierConfig enables a notification backend of the given type. The
+// webhook fields apply to webhook notifiers; timeout also limits commands.
+type NotifierConfig struct {
+	Type    string `yaml:"type"`              // "desktop", "log", "webhook", "command" or "email"
+	Command string `yaml:"command,omitempty"` // Split on spaces, not run through a shell; gets the event as JSON on stdin
+
+	WebhookConfig `yaml:",inline"`
+	EmailConfig   `yaml:",inline"`
+}
+
+// EmailConfig sends notifications over SMTP. ${VAR} references in the
+// username and password are expanded from the environment.
+type EmailConfig struct {
+	SMTPHost string   `yaml:"smtp_host,omitempty"`
+	SMTPPort int      `yaml:"smtp_port,omitempty"` // 587 by default
+	Username string   `yaml:"username,omitempty"`  // Authenticates with PLAIN when set
+	Password string   `yaml:"password,omitempty"`
+	StartTLS string   `yaml:"starttls,omitempty"` // "required" (default), "optional" or "never"
+	From     string   `yaml:"from,omitempty"`
+	To       []string `yaml:"to,omitempty"`
+	Digest   Duration `yaml:"digest,omitempty"` // Collects notifications into one email per period
+}
+
+// DefaultPollInterval is used when neither a flag nor poll_interval sets one
+const DefaultPollInterval = 30 * time.Second
+
+// PollIntervalDuration returns poll_interval, falling back to
+// DefaultPollInterval when it is unset
+func (c *Config) PollIntervalDuration() (time.Duration, error) {
+	if c.PollInterval == 0 {
+		return DefaultPollInterval, nil
+	}
+	if c.PollInterval < 0 {
+		return 0, fmt.Errorf("invalid poll_interval %q: must be positive", c.PollInterval)
+	}
+	return time.Duration(c.PollInterval), nil
+}
+
+// IsBranchIgnored reports whether branch matches one of the ignore_branches
+// glob patterns, e.g. "main" or "release/*"
+func (c *Config) IsBranchIgnored(branch string) bool {
+	for _, pattern := range c.IgnoreBranches {
+		if matched, _ := path.Match(pattern, branch); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateIgnoreBranches checks that every ignore_branches entry is a valid pattern
+func (c *Config) ValidateIgnoreBranches() error {
+	for _, pattern := range c.IgnoreBranches {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid ignore_branches pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Validate reports values that decode but cannot be used, such as a
+// non-positive poll_interval or a malformed ignore_branches pattern.
+// Strategies, regenerators and notifiers are checked by the packages that
+// use them.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.PollInterval < 0 {
+		errs = append(errs, fmt.Errorf("invalid poll_interval %q: must be positive", c.PollInterval))
+	}
+	if err := c.ValidateIgnoreBranches(); err != nil {
+		errs = append(errs, err)
+	}
+	for i, repo := range c.Repositories {
+		if repo.Path == "" {
+			errs = append(errs, fmt.Errorf("repositories[%d]: path is required", i))
+		}
+		if repo.PollInterval < 0 {
+			errs = append(errs, fmt.Errorf("repositories[%d]: invalid poll_interval %q: must be positive", i, repo.PollInterval))
+		}
+	}
+	for i, notifier := range c.Notifiers {
+		switch {
+		case notifier.Type == "":
+			errs = append(errs, fmt.Errorf("notifiers[%d]: type is required", i))
+		case notifier.Type == "webhook" && notifier.URL == "":
+			errs = append(errs, fmt.Errorf("notifiers[%d]: url is required", i))
+		case notifier.Type == "command" && notifier.Command == "":
+			errs = append(errs, fmt.Errorf("notifiers[%d]: command is required", i))
+		case notifier.Type == "email" && (notifier.SMTPHost == "" || notifier.From == "" || len(notifier.To) == 0):
+			errs = append(errs, fmt.Errorf("notifiers[%d]: smtp_host, from and to are required", i))
+		}
+	}
+	for eventType, limit := range c.NotificationRateLimits {
+		if limit < 0 {
+			errs = append(errs, fmt.Errorf("notification_rate_limits.%s must not be negative", eventType))
+		}
+	}
+	if c.NotificationDigest < 0 {
+		errs = append(errs, fmt.Errorf("invalid notification_digest %q: must not be negative", c.NotificationDigest))
+	}
+	for i, quiet := range c.QuietHours {
+		if quiet.Start == "" || quiet.End == "" {
+			errs = append(errs, fmt.Errorf("quiet_hours[%d]: start and end are required", i))
+		}
+	}
+	for event, commands := range c.Hooks {
+		for i, command := range commands {
+			if strings.TrimSpace(command) == "" {
+				errs = append(errs, fmt.Errorf("hooks.%s[%d]: command is empty", event, i))
+			}
+		}
+	}
+	if c.HookTimeout < 0 {
+		errs = append(errs, fmt.Errorf("invalid hook_timeout %q: must not be negative", c.HookTimeout))
+	}
+	if c.HookConcurrency < 0 {
+		errs = append(errs, fmt.Errorf("hook_concurrency must not be negative"))
+	}
+	for i, hook := range c.Webhooks {
+		if hook.URL == "" {
+			errs = append(errs, fmt.Errorf("webhooks[%d]: url is required", i))
+		}
+		if hook.Retries < 0 {
+			errs = append(errs, fmt.Errorf("webhooks[%d]: retries must not be negative", i))
+		}
+		if hook.Backoff < 0 || hook.Timeout < 0 {
+			errs = append(errs, fmt.Errorf("webhooks[%d]: backoff and timeout must not be negative", i))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 var (
@@ -35,52 +261,23 @@ func SetConfigFile(file string) {
 	configName = filepath.Base(file)
 }
 
+// Load reads the global config file over the built-in defaults, then
+// applies the HARBINGER_* environment overrides
 func Load() (*Config, error) {
-	cfg := &Config{
-		PollInterval:  "30s",
-		Editor:        os.Getenv("EDITOR"),
-		Notifications: true,
-		AutoResolve:   true,
-		AutoSync:      false, // Default to false for safety
-		AutoPull:      false, // Deprecated: kept for backward compatibility
-	}
-
-	if configPath == "" || configName == "" {
-		return cfg, nil
-	}
-
-	configFile := filepath.Join(configPath, configName)
-	data, err := os.ReadFile(configFile)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// Config file doesn't exist, use defaults
-			return cfg, nil
-		}
-		return nil, err
-	}
-
-	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return nil, err
-	}
-
-	// Backward compatibility: if auto_pull is set but auto_sync is not, use auto_pull value
-	if cfg.AutoPull && !cfg.AutoSync {
-		cfg.AutoSync = cfg.AutoPull
-	}
-
-	return cfg, nil
+	cfg, _, err := load(Layers("", ""), true)
+	return cfg, err
 }
 
-func Save(cfg *Config) error {
-	if configPath == "" || configName == "" {
-		return nil
-	}
-
-	data, err := yaml.Marshal(cfg)
-	if err != nil {
-		return err
+// defaults returns the built-in configuration
+func defaults() *Config {
+	return &Config{
+		PollInterval:      Duration(DefaultPollInterval),
+		Editor:            os.Getenv("EDITOR"),
+		Notifications:     true,
+		NotificationDedup: true,
+		AutoResolve:       true,
+		AutoSync:          false, // Default to false for safety
+		AutoPull:          false, // Deprecated: kept for backward compatibility
+		SyncStrategy:      "merge",
 	}
-
-	configFile := filepath.Join(configPath, configName)
-	return os.WriteFile(configFile, data, 0644)
 }
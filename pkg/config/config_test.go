@@ -4,9 +4,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 func TestLoad_Defaults(t *testing.T) {
@@ -27,7 +29,7 @@ func TestLoad_Defaults(t *testing.T) {
 	assert.NotNil(t, cfg)
 
 	// Test default values
-	assert.Equal(t, "30s", cfg.PollInterval)
+	assert.Equal(t, "30s", cfg.PollInterval.String())
 	assert.Equal(t, true, cfg.Notifications)
 	assert.Equal(t, true, cfg.AutoResolve)
 	assert.Equal(t, false, cfg.AutoPull) // Should default to false for safety
@@ -69,7 +71,7 @@ ignore_branches:
 	assert.NotNil(t, cfg)
 
 	// Test loaded values
-	assert.Equal(t, "60s", cfg.PollInterval)
+	assert.Equal(t, time.Minute, time.Duration(cfg.PollInterval))
 	assert.Equal(t, "vim", cfg.Editor)
 	assert.Equal(t, false, cfg.Notifications)
 	assert.Equal(t, false, cfg.AutoResolve)
@@ -124,7 +126,7 @@ func TestLoad_NonExistentConfig(t *testing.T) {
 	assert.NotNil(t, cfg)
 
 	// Should have default values
-	assert.Equal(t, "30s", cfg.PollInterval)
+	assert.Equal(t, "30s", cfg.PollInterval.String())
 	assert.Equal(t, true, cfg.Notifications)
 }
 
@@ -144,7 +146,7 @@ func TestSave(t *testing.T) {
 	SetConfigName(".harbinger.yaml")
 
 	cfg := &Config{
-		PollInterval:   "45s",
+		PollInterval:   Duration(45 * time.Second),
 		Editor:         "code",
 		Notifications:  true,
 		AutoResolve:    false,
@@ -183,7 +185,7 @@ func TestSave_NoConfigPath(t *testing.T) {
 	configName = ""
 
 	cfg := &Config{
-		PollInterval: "30s",
+		PollInterval: Duration(30 * time.Second),
 	}
 
 	err := Save(cfg)
@@ -257,3 +259,107 @@ func TestConfig_WithEnvironmentEditor(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "nano", cfg.Editor)
 }
+
+func TestConfig_PollIntervalDuration(t *testing.T) {
+	interval, err := (&Config{}).PollIntervalDuration()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultPollInterval, interval)
+
+	interval, err = (&Config{PollInterval: Duration(2 * time.Minute)}).PollIntervalDuration()
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, interval)
+
+	_, err = (&Config{PollInterval: Duration(-time.Minute)}).PollIntervalDuration()
+	assert.Error(t, err)
+}
+
+func TestDuration_YAML(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte("poll_interval: 1m30s\n"), &cfg))
+	assert.Equal(t, 90*time.Second, time.Duration(cfg.PollInterval))
+
+	err := yaml.Unmarshal([]byte("poll_interval: soon\n"), &cfg)
+	assert.ErrorContains(t, err, `invalid duration "soon"`)
+
+	for value, want := range map[time.Duration]string{
+		30 * time.Second:           "30s",
+		time.Minute:                "1m",
+		2 * time.Hour:              "2h",
+		time.Hour + 30*time.Second: "1h0m30s",
+		1500 * time.Millisecond:    "1.5s",
+	} {
+		assert.Equal(t, want, Duration(value).String())
+	}
+}
+
+func TestConfig_IsBranchIgnored(t *testing.T) {
+	cfg := &Config{IgnoreBranches: []string{"main", "release/*", "wip-*"}}
+	require.NoError(t, cfg.ValidateIgnoreBranches())
+
+	assert.True(t, cfg.IsBranchIgnored("main"))
+	assert.True(t, cfg.IsBranchIgnored("release/1.2"))
+	assert.True(t, cfg.IsBranchIgnored("wip-parser"))
+	assert.False(t, cfg.IsBranchIgnored("mainline"))
+	assert.False(t, cfg.IsBranchIgnored("release/1.2/hotfix"))
+	assert.False(t, (&Config{}).IsBranchIgnored("main"))
+
+	assert.Error(t, (&Config{IgnoreBranches: []string{"feature/["}}).ValidateIgnoreBranches())
+}
+
+func TestConfig_ValidateWebhooks(t *testing.T) {
+	cfg := &Config{Webhooks: []WebhookConfig{
+		{URL: "https://chat.example.com/hook", Retries: 3, Backoff: Duration(time.Second)},
+		{Retries: -1},
+		{URL: "https://chat.example.com/hook", Timeout: Duration(-time.Second)},
+	}}
+
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "webhooks[1]: url is required")
+	assert.ErrorContains(t, err, "webhooks[1]: retries must not be negative")
+	assert.ErrorContains(t, err, "webhooks[2]: backoff and timeout")
+	assert.NotContains(t, err.Error(), "webhooks[0]")
+}
+
+func TestConfig_ValidateNotifiers(t *testing.T) {
+	cfg := &Config{Notifiers: []NotifierConfig{
+		{Type: "log", WebhookConfig: WebhookConfig{EventFilter: EventFilter{MinLevel: "warn"}}},
+		{Command: "./notify.sh"},
+		{Type: "command"},
+		{Type: "email", EmailConfig: EmailConfig{SMTPHost: "smtp.example.com", From: "harbinger@example.com"}},
+	}}
+
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "notifiers[1]: type is required")
+	assert.ErrorContains(t, err, "notifiers[2]: command is required")
+	assert.ErrorContains(t, err, "notifiers[3]: smtp_host, from and to are required")
+	assert.NotContains(t, err.Error(), "notifiers[0]")
+}
+
+func TestConfig_ValidateNotificationPolicy(t *testing.T) {
+	cfg := &Config{
+		NotificationRateLimits: map[string]Duration{"default": Duration(time.Minute), "fetch_failed": Duration(-time.Minute)},
+		NotificationDigest:     Duration(-time.Minute),
+		QuietHours:             []QuietHours{{Start: "22:00", End: "07:00"}, {Start: "12:00"}},
+	}
+
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "notification_rate_limits.fetch_failed must not be negative")
+	assert.ErrorContains(t, err, "notification_digest")
+	assert.ErrorContains(t, err, "quiet_hours[1]: start and end are required")
+	assert.NotContains(t, err.Error(), "quiet_hours[0]")
+	assert.True(t, defaults().NotificationDedup)
+}
+
+func TestConfig_ValidateHooks(t *testing.T) {
+	cfg := &Config{
+		Hooks:           map[string][]string{"auto_pull": {"make test", " "}},
+		HookTimeout:     Duration(-time.Second),
+		HookConcurrency: -1,
+	}
+
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "hooks.auto_pull[1]: command is empty")
+	assert.ErrorContains(t, err, "hook_timeout")
+	assert.ErrorContains(t, err, "hook_concurrency")
+	assert.NotContains(t, err.Error(), "auto_pull[0]")
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Get returns the value of a top-level key in cfg as YAML
+func Get(cfg *Config, key string) (string, error) {
+	if !isKey(key) {
+		return "", fmt.Errorf("unknown config key %q", key)
+	}
+
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		return "", err
+	}
+	value := mappingValue(&doc, key)
+	if value == nil {
+		// Empty optional sections are left out when encoding
+		return "", nil
+	}
+	if value.Kind == yaml.ScalarNode {
+		return value.Value, nil
+	}
+	out, err := encodeNode(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(out, "\n"), nil
+}
+
+// SetValue sets a top-level key in the layer's file to value, which is parsed
+// as YAML (e.g. "1m", "true" or "[main, release/*]"). Comments and the order
+// of the other keys are kept, and the file is only written if the result is
+// still a valid config for its layer.
+func SetValue(layer Layer, key, value string) error {
+	if !isKey(key) {
+		return fmt.Errorf("unknown config key %q", key)
+	}
+
+	var parsed yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: ""}
+	if len(parsed.Content) > 0 {
+		valueNode = parsed.Content[0]
+	}
+
+	doc, err := readDocument(layer.Path)
+	if err != nil {
+		return err
+	}
+	setMappingValue(doc.Content[0], key, valueNode)
+	return writeDocument(layer, doc)
+}
+
+// Save writes cfg to the global config file. Comments already in the file
+// are kept for the keys that are still present.
+func Save(cfg *Config) error {
+	if configPath == "" || configName == "" {
+		return nil
+	}
+
+	var encoded yaml.Node
+	if err := encoded.Encode(cfg); err != nil {
+		return err
+	}
+
+	layer := Layer{Name: "global", Path: filepath.Join(configPath, configName)}
+	doc, err := readDocument(layer.Path)
+	if err != nil {
+		return err
+	}
+	mapping := doc.Content[0]
+
+	// Drop keys cfg no longer sets, then update or append the rest
+	var kept []*yaml.Node
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mappingValue(&encoded, mapping.Content[i].Value) != nil {
+			kept = append(kept, mapping.Content[i], mapping.Content[i+1])
+		}
+	}
+	mapping.Content = kept
+	for i := 0; i+1 < len(encoded.Content); i += 2 {
+		setMappingValue(mapping, encoded.Content[i].Value, encoded.Content[i+1])
+	}
+	return writeDocument(layer, doc)
+}
+
+// Init creates the layer's config file. The global file gets every setting
+// with its default value and a comment describing it; repository and
+// personal files start empty, since anything they set overrides the global
+// file. An existing file is only replaced when force is set.
+func Init(layer Layer, force bool) error {
+	if _, err := os.Stat(layer.Path); err == nil && !force {
+		return fmt.Errorf("%s already exists", layer.Path)
+	}
+
+	doc := &yaml.Node{Kind: yaml.DocumentNode}
+	if layer.Name == "global" {
+		cfg := defaults()
+		// Leave the editor to $EDITOR instead of pinning its current value
+		cfg.Editor = ""
+		if err := doc.Encode(cfg); err != nil {
+			return err
+		}
+		doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{doc}}
+		mapping := doc.Content[0]
+		for i := 0; i+1 < len(mapping.Content); i += 2 {
+			mapping.Content[i].HeadComment = Description(mapping.Content[i].Value)
+		}
+		doc.HeadComment = "Harbinger configuration, see `harbinger config schema` for every option"
+	} else {
+		doc.HeadComment = fmt.Sprintf("Harbinger %s overrides, merged over the global config\nexample:\n  auto_sync: true", layer.Name)
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode}}
+	}
+	return writeDocument(layer, doc)
+}
+
+// readDocument parses a config file into a document holding a mapping,
+// creating an empty one if the file does not exist or has no keys
+func readDocument(path string) (*yaml.Node, error) {
+	doc := &yaml.Node{Kind: yaml.DocumentNode}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := yaml.Unmarshal(data, doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if doc.Kind == 0 {
+		// A file with nothing but comments parses to an empty node
+		doc.Kind = yaml.DocumentNode
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: expected a mapping of config keys", path)
+	}
+	// Appended keys would otherwise be written inline into "{}"
+	doc.Content[0].Style = 0
+	return doc, nil
+}
+
+// writeDocument validates the document for its layer and writes it
+func writeDocument(layer Layer, doc *yaml.Node) error {
+	out, err := encodeNode(doc)
+	if err != nil {
+		return err
+	}
+
+	if err := checkLayer(layer, []byte(out)); err != nil {
+		return fmt.Errorf("%s: %w", layer.Path, err)
+	}
+	var cfg Config
+	if err := decodeStrict([]byte(out), &cfg); err != nil {
+		return fmt.Errorf("%s: %w", layer.Path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", layer.Path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(layer.Path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(layer.Path, []byte(out), 0644)
+}
+
+func encodeNode(node *yaml.Node) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// mappingValue returns the value of key in a mapping or a document holding
+// one, or nil if it is not set
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue replaces the value of key, keeping the comment after the
+// old value, or appends the key if it is not set
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			if value.LineComment == "" {
+				value.LineComment = mapping.Content[i+1].LineComment
+			}
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		value,
+	)
+}
+
+func isKey(key string) bool {
+	for _, k := range Keys() {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
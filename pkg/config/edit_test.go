@@ -0,0 +1,135 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetValue_KeepsComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".harbinger.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("# My settings\npoll_interval: 30s # check often\n\n# Branches I never sync\nignore_branches:\n  - main\n"), 0644))
+	layer := Layer{Name: "global", Path: path}
+
+	require.NoError(t, SetValue(layer, "poll_interval", "2m"))
+	require.NoError(t, SetValue(layer, "auto_sync", "true"))
+	require.NoError(t, SetValue(layer, "ignore_branches", "[main, release/*]"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "# My settings\npoll_interval: 2m # check often\n# Branches I never sync\nignore_branches: [main, release/*]\nauto_sync: true\n", string(data))
+}
+
+func TestSetValue_Rejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".harbinger.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("auto_sync: true\n"), 0644))
+	layer := Layer{Name: "global", Path: path}
+
+	assert.ErrorContains(t, SetValue(layer, "auto_sinc", "true"), "unknown config key")
+	assert.ErrorContains(t, SetValue(layer, "poll_interval", "soon"), "invalid duration")
+	assert.ErrorContains(t, SetValue(layer, "poll_interval", "-1m"), "must be positive")
+	assert.ErrorContains(t, SetValue(layer, "ignore_branches", "['[']"), "invalid ignore_branches pattern")
+
+	shared := Layer{Name: "repository", Path: path, Shared: true}
+	assert.Error(t, SetValue(shared, "repositories", "[{path: ~/src}]"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "auto_sync: true\n", string(data), "rejected values are not written")
+}
+
+func TestSave_KeepsComments(t *testing.T) {
+	dir := t.TempDir()
+	originalConfigPath := configPath
+	originalConfigName := configName
+	defer func() {
+		configPath = originalConfigPath
+		configName = originalConfigName
+	}()
+	SetConfigFile(filepath.Join(dir, ".harbinger.yaml"))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".harbinger.yaml"), []byte("# Poll less on battery\npoll_interval: 5m\n"), 0644))
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	cfg.AutoSync = true
+	require.NoError(t, Save(cfg))
+
+	data, err := os.ReadFile(filepath.Join(dir, ".harbinger.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# Poll less on battery\npoll_interval: 5m\n")
+	assert.Contains(t, string(data), "auto_sync: true\n")
+}
+
+func TestInit(t *testing.T) {
+	dir := t.TempDir()
+	global := Layer{Name: "global", Path: filepath.Join(dir, "global.yaml")}
+	require.NoError(t, Init(global, false))
+	assert.ErrorContains(t, Init(global, false), "already exists")
+	require.NoError(t, Init(global, true))
+
+	data, err := os.ReadFile(global.Path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# How often to check for remote changes, e.g. 30s or 1m\npoll_interval: 30s\n")
+
+	cfg, _, err := LoadLayers([]Layer{global})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultPollInterval, time.Duration(cfg.PollInterval))
+
+	personal := Layer{Name: "personal", Path: filepath.Join(dir, ".git", PersonalFile)}
+	require.NoError(t, Init(personal, false))
+	require.NoError(t, SetValue(personal, "auto_stash", "true"))
+	data, err = os.ReadFile(personal.Path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "\nauto_stash: true\n")
+}
+
+func TestGet(t *testing.T) {
+	cfg := &Config{PollInterval: Duration(time.Minute), IgnoreBranches: []string{"main"}}
+
+	value, err := Get(cfg, "poll_interval")
+	require.NoError(t, err)
+	assert.Equal(t, "1m", value)
+
+	value, err = Get(cfg, "ignore_branches")
+	require.NoError(t, err)
+	assert.Equal(t, "- main", value)
+
+	_, err = Get(cfg, "nope")
+	assert.Error(t, err)
+}
+
+func TestLoad_UnknownKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".harbinger.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("auto_sinc: true\n"), 0644))
+
+	_, _, err := LoadLayers([]Layer{{Name: "global", Path: path}})
+	assert.ErrorContains(t, err, "field auto_sinc not found")
+}
+
+func TestSchema(t *testing.T) {
+	data, err := Schema()
+	require.NoError(t, err)
+
+	var schema struct {
+		Properties map[string]struct {
+			Type  string   `json:"type"`
+			Enum  []string `json:"enum"`
+			Items struct {
+				Required []string `json:"required"`
+			} `json:"items"`
+		} `json:"properties"`
+		AdditionalProperties bool `json:"additionalProperties"`
+	}
+	require.NoError(t, json.Unmarshal(data, &schema))
+
+	assert.False(t, schema.AdditionalProperties)
+	assert.Len(t, schema.Properties, len(Keys()))
+	assert.Equal(t, "string", schema.Properties["poll_interval"].Type)
+	assert.Equal(t, []string{"merge", "rebase", "ff-only"}, schema.Properties["sync_strategy"].Enum)
+	assert.Equal(t, []string{"path"}, schema.Properties["repositories"].Items.Required)
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variables that override config keys
+const EnvPrefix = "HARBINGER_"
+
+// EnvName returns the environment variable overriding a top-level key,
+// e.g. HARBINGER_AUTO_SYNC for auto_sync
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+// EnvOverrides returns the set HARBINGER_* variables by config key
+func EnvOverrides() map[string]string {
+	overrides := map[string]string{}
+	for _, key := range Keys() {
+		if value, ok := os.LookupEnv(EnvName(key)); ok {
+			overrides[key] = value
+		}
+	}
+	return overrides
+}
+
+// applyEnv overrides cfg with the HARBINGER_* variables. Lists of strings
+// are comma-separated, lists of objects and maps are given as YAML or JSON, and
+// everything else is written as in the config file.
+func applyEnv(cfg *Config, origins Origins) error {
+	fields := map[string]reflect.Type{}
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		fields[yamlName(t.Field(i))] = t.Field(i).Type
+	}
+
+	for _, key := range Keys() {
+		value, ok := os.LookupEnv(EnvName(key))
+		if !ok {
+			continue
+		}
+
+		node, err := envNode(fields[key], value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", EnvName(key), err)
+		}
+		mapping := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+			node,
+		}}
+		// Round-trip through the strict decoder so typos inside list
+		// entries are reported just like in a config file
+		data, err := encodeNode(mapping)
+		if err != nil {
+			return fmt.Errorf("%s: %w", EnvName(key), err)
+		}
+		if err := decodeStrict([]byte(data), cfg); err != nil {
+			return fmt.Errorf("%s: %w", EnvName(key), err)
+		}
+		origins[key] = "$" + EnvName(key)
+	}
+	return nil
+}
+
+// envNode converts an environment variable into the YAML value of a key
+func envNode(t reflect.Type, value string) (*yaml.Node, error) {
+	switch {
+	case t.Kind() == reflect.String:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}, nil
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+		list := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item})
+			}
+		}
+		return list, nil
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Map:
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(value), &doc); err != nil {
+			return nil, err
+		}
+		if len(doc.Content) == 0 && t.Kind() == reflect.Map {
+			return &yaml.Node{Kind: yaml.MappingNode}, nil
+		}
+		if len(doc.Content) == 0 {
+			return &yaml.Node{Kind: yaml.SequenceNode}, nil
+		}
+		return doc.Content[0], nil
+	default:
+		// Booleans and durations are validated by decoding
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: value}, nil
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "HARBINGER_AUTO_SYNC", EnvName("auto_sync"))
+	assert.Equal(t, "HARBINGER_IGNORE_BRANCHES", EnvName("ignore_branches"))
+}
+
+func TestLoad_EnvOverrides(t *testing.T) {
+	repo, gitDir := setupLayers(t, "auto_sync: false\npoll_interval: 1m\n", "", "editor: vim\n")
+	t.Setenv("HARBINGER_AUTO_SYNC", "true")
+	t.Setenv("HARBINGER_IGNORE_BRANCHES", "main, release/*,")
+	t.Setenv("HARBINGER_EDITOR", "code --wait")
+	t.Setenv("HARBINGER_RESOLVE_RULES", `[{"pattern": "go.sum", "strategy": "theirs"}]`)
+
+	cfg, origins, err := LoadWithOrigins(repo, gitDir)
+	require.NoError(t, err)
+	assert.True(t, cfg.AutoSync, "the environment wins over the config files")
+	assert.Equal(t, []string{"main", "release/*"}, cfg.IgnoreBranches)
+	assert.Equal(t, "code --wait", cfg.Editor)
+	assert.Equal(t, []ResolveRule{{Pattern: "go.sum", Strategy: "theirs"}}, cfg.ResolveRules)
+	assert.Equal(t, time.Minute, time.Duration(cfg.PollInterval))
+
+	assert.Equal(t, "$HARBINGER_AUTO_SYNC", origins.Of("auto_sync"))
+	assert.Contains(t, origins.Of("poll_interval"), ".harbinger.yaml")
+
+	global, err := Load()
+	require.NoError(t, err)
+	assert.True(t, global.AutoSync, "Load applies the overrides too")
+
+	files, _, err := LoadLayers(Layers(repo, gitDir))
+	require.NoError(t, err)
+	assert.False(t, files.AutoSync, "LoadLayers reads only the files")
+
+	assert.Len(t, EnvOverrides(), 4)
+}
+
+func TestLoad_InvalidEnvOverride(t *testing.T) {
+	setupLayers(t, "", "", "")
+
+	for name, value := range map[string]string{
+		"HARBINGER_AUTO_SYNC":     "maybe",
+		"HARBINGER_POLL_INTERVAL": "soon",
+		"HARBINGER_REPOSITORIES":  "[{path: /src, pol: 1m}]",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			_, err := Load()
+			assert.ErrorContains(t, err, name)
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// RepositoryFile is the shared config committed at the repository root
+	RepositoryFile = ".harbinger.yaml"
+	// PersonalFile is the untracked config inside the repository's git
+	// directory, for overrides that only apply to one clone
+	PersonalFile = "harbinger.yaml"
+
+	// OriginDefault is the origin of values nobody configured
+	OriginDefault = "default"
+	// OriginEditorEnv is the origin of an editor taken from $EDITOR
+	OriginEditorEnv = "$EDITOR"
+)
+
+// Layer is a config file merged over the ones before it
+type Layer struct {
+	// Name is "global", "repository" or "personal"
+	Name string
+	Path string
+	// Shared layers are committed to the repository and written by anyone
+	// with push access, so they may not configure commands to run
+	Shared bool
+}
+
+// Origins maps each top-level config key to the file its value came from,
+// OriginDefault or OriginEditorEnv
+type Origins map[string]string
+
+// Of returns the origin of a top-level config key
+func (o Origins) Of(key string) string {
+	if origin, ok := o[key]; ok {
+		return origin
+	}
+	return OriginDefault
+}
+
+// Layers returns the config files for a repository from lowest to highest
+// precedence: the global file, <repoPath>/.harbinger.yaml and
+// <gitDir>/harbinger.yaml. Empty paths leave the repository layers out.
+func Layers(repoPath, gitDir string) []Layer {
+	var layers []Layer
+	if configPath != "" && configName != "" {
+		layers = append(layers, Layer{Name: "global", Path: filepath.Join(configPath, configName)})
+	}
+	if repoPath != "" {
+		layers = append(layers, Layer{Name: "repository", Path: filepath.Join(repoPath, RepositoryFile), Shared: true})
+	}
+	if gitDir != "" {
+		layers = append(layers, Layer{Name: "personal", Path: filepath.Join(gitDir, PersonalFile)})
+	}
+	return layers
+}
+
+// LoadRepository loads the global config with the repository's shared and
+// personal config files merged over it
+func LoadRepository(repoPath, gitDir string) (*Config, error) {
+	cfg, _, err := LoadWithOrigins(repoPath, gitDir)
+	return cfg, err
+}
+
+// LoadWithOrigins is LoadRepository that also reports where each value came from
+func LoadWithOrigins(repoPath, gitDir string) (*Config, Origins, error) {
+	return load(Layers(repoPath, gitDir), true)
+}
+
+// LoadLayers merges the given config files over the defaults, without the
+// environment overrides
+func LoadLayers(layers []Layer) (*Config, Origins, error) {
+	return load(layers, false)
+}
+
+// load merges the layers over the defaults, then applies the HARBINGER_*
+// environment overrides if env is set. Every key a file or variable sets
+// replaces the value from the layers before it, lists included.
+func load(layers []Layer, env bool) (*Config, Origins, error) {
+	cfg := defaults()
+	origins := Origins{}
+	if cfg.Editor != "" {
+		origins["editor"] = OriginEditorEnv
+	}
+
+	for _, layer := range layers {
+		data, err := os.ReadFile(layer.Path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, nil, err
+		}
+
+		var keys map[string]yaml.Node
+		if err := yaml.Unmarshal(data, &keys); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", layer.Path, err)
+		}
+		if err := checkLayer(layer, data); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", layer.Path, err)
+		}
+
+		if err := decodeStrict(data, cfg); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", layer.Path, err)
+		}
+		for key := range keys {
+			origins[key] = layer.Path
+		}
+	}
+
+	if env {
+		if err := applyEnv(cfg, origins); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Backward compatibility: if auto_pull is set but auto_sync is not, use auto_pull value
+	if cfg.AutoPull && !cfg.AutoSync {
+		cfg.AutoSync = cfg.AutoPull
+	}
+
+	return cfg, origins, nil
+}
+
+// checkLayer decodes a single config file on its own and checks it may
+// be used at its layer
+func checkLayer(layer Layer, data []byte) error {
+	var values Config
+	if err := decodeStrict(data, &values); err != nil {
+		return err
+	}
+	if layer.Shared {
+		return checkShared(&values)
+	}
+	return nil
+}
+
+// decodeStrict decodes YAML into out, rejecting keys the config does not
+// have so that typos are reported instead of silently ignored
+func decodeStrict(data []byte, out *Config) error {
+	clearMaps(data, out)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// clearMaps empties the map-valued keys the document sets, so that like
+// every other key they replace the value from the layers before it instead
+// of being merged into it
+func clearMaps(data []byte, out *Config) {
+	var keys map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		// Reported by the decoder
+		return
+	}
+	v := reflect.ValueOf(out).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if _, ok := keys[yamlName(v.Type().Field(i))]; ok && v.Field(i).Kind() == reflect.Map {
+			v.Field(i).Set(reflect.Zero(v.Field(i).Type()))
+		}
+	}
+}
+
+// checkShared rejects settings a committed config file may not change
+func checkShared(cfg *Config) error {
+	for _, regen := range cfg.Regenerators {
+		if regen.Command != "" {
+			return fmt.Errorf("regenerator %q sets a command, which is only allowed in the global or personal config", regen.Name)
+		}
+	}
+	if cfg.Editor != "" {
+		// harbinger resolve runs the editor for whoever resolves a conflict
+		return fmt.Errorf("editor is only allowed in the global or personal config")
+	}
+	if len(cfg.Repositories) > 0 {
+		return fmt.Errorf("repositories can only be listed in the global config")
+	}
+	if len(cfg.Webhooks) > 0 {
+		// A cloned repository must not make harbinger post to arbitrary URLs
+		return fmt.Errorf("webhooks are only allowed in the global or personal config")
+	}
+	if len(cfg.Hooks) > 0 {
+		return fmt.Errorf("hooks are only allowed in the global or personal config")
+	}
+	for _, notifier := range cfg.Notifiers {
+		if notifier.Type != "desktop" && notifier.Type != "log" {
+			return fmt.Errorf("%s notifiers are only allowed in the global or personal config", notifier.Type)
+		}
+	}
+	return nil
+}
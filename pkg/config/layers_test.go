@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupLayers writes the global, repository and personal config files and
+// returns the repository and git directory. Empty content skips a file.
+func setupLayers(t *testing.T, global, repository, personal string) (string, string) {
+	t.Helper()
+	originalConfigPath := configPath
+	originalConfigName := configName
+	t.Cleanup(func() {
+		configPath = originalConfigPath
+		configName = originalConfigName
+	})
+
+	home := t.TempDir()
+	repo := t.TempDir()
+	gitDir := filepath.Join(repo, ".git")
+	require.NoError(t, os.MkdirAll(gitDir, 0755))
+	SetConfigFile(filepath.Join(home, ".harbinger.yaml"))
+
+	for path, content := range map[string]string{
+		filepath.Join(home, ".harbinger.yaml"): global,
+		filepath.Join(repo, RepositoryFile):    repository,
+		filepath.Join(gitDir, PersonalFile):    personal,
+	} {
+		if content != "" {
+			require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		}
+	}
+	return repo, gitDir
+}
+
+func TestLoadWithOrigins_Layering(t *testing.T) {
+	t.Setenv("EDITOR", "nano")
+	repo, gitDir := setupLayers(t,
+		"poll_interval: 1m\nauto_sync: false\nignore_branches: [main, master]\n",
+		"auto_sync: true\nignore_branches: [docs/*]\n",
+		"poll_interval: 5m\n",
+	)
+
+	cfg, origins, err := LoadWithOrigins(repo, gitDir)
+	require.NoError(t, err)
+
+	assert.Equal(t, "5m", cfg.PollInterval.String())
+	assert.True(t, cfg.AutoSync)
+	assert.Equal(t, []string{"docs/*"}, cfg.IgnoreBranches, "lists are replaced, not merged")
+	assert.True(t, cfg.AutoResolve)
+
+	assert.Equal(t, filepath.Join(gitDir, PersonalFile), origins.Of("poll_interval"))
+	assert.Equal(t, filepath.Join(repo, RepositoryFile), origins.Of("auto_sync"))
+	assert.Equal(t, OriginEditorEnv, origins.Of("editor"))
+	assert.Equal(t, OriginDefault, origins.Of("auto_resolve"))
+
+	global, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "1m", global.PollInterval.String(), "Load only reads the global file")
+	assert.False(t, global.AutoSync)
+}
+
+func TestLoadWithOrigins_SharedFileRestrictions(t *testing.T) {
+	repo, gitDir := setupLayers(t, "", "regenerators:\n  - name: go\n    command: curl example.com\n", "")
+	_, err := LoadRepository(repo, gitDir)
+	assert.ErrorContains(t, err, "only allowed in the global or personal config")
+
+	repo, gitDir = setupLayers(t, "", "repositories:\n  - path: ~/src\n", "")
+	_, err = LoadRepository(repo, gitDir)
+	assert.ErrorContains(t, err, "global config")
+
+	repo, gitDir = setupLayers(t, "", "webhooks:\n  - url: https://example.com/hook\n", "")
+	_, err = LoadRepository(repo, gitDir)
+	assert.ErrorContains(t, err, "webhooks are only allowed in the global or personal config")
+
+	repo, gitDir = setupLayers(t, "", "notifiers:\n  - type: command\n    command: ./notify.sh\n", "")
+	_, err = LoadRepository(repo, gitDir)
+	assert.ErrorContains(t, err, "command notifiers are only allowed in the global or personal config")
+
+	repo, gitDir = setupLayers(t, "", "editor: sh ./scripts/edit.sh\n", "")
+	_, err = LoadRepository(repo, gitDir)
+	assert.ErrorContains(t, err, "editor is only allowed in the global or personal config")
+
+	repo, gitDir = setupLayers(t, "", "hooks:\n  auto_pull: [make test]\n", "")
+	_, err = LoadRepository(repo, gitDir)
+	assert.ErrorContains(t, err, "hooks are only allowed in the global or personal config")
+
+	repo, gitDir = setupLayers(t, "", "notifiers:\n  - type: desktop\n    min_level: warn\n", "")
+	cfg, err := LoadRepository(repo, gitDir)
+	require.NoError(t, err)
+	assert.Equal(t, "warn", cfg.Notifiers[0].MinLevel)
+
+	repo, gitDir = setupLayers(t, "", "regenerators:\n  - name: go\n", "regenerators:\n  - name: go\n    command: go mod tidy\n")
+	cfg, err = LoadRepository(repo, gitDir)
+	require.NoError(t, err)
+	assert.Equal(t, "go mod tidy", cfg.Regenerators[0].Command)
+}
+
+func TestLoadWithOrigins_InvalidLayer(t *testing.T) {
+	repo, gitDir := setupLayers(t, "", "auto_sync: [oops\n", "")
+	_, err := LoadRepository(repo, gitDir)
+	assert.ErrorContains(t, err, RepositoryFile)
+}
+
+func TestLayers(t *testing.T) {
+	setupLayers(t, "", "", "")
+
+	assert.Len(t, Layers("", ""), 1)
+	layers := Layers("/src/app", "/src/app/.git")
+	require.Len(t, layers, 3)
+	assert.Equal(t, []string{"global", "repository", "personal"}, []string{layers[0].Name, layers[1].Name, layers[2].Name})
+	assert.True(t, layers[1].Shared)
+	assert.Equal(t, "/src/app/.git/harbinger.yaml", layers[2].Path)
+}
+
+func TestLoadWithOrigins_MapsReplaced(t *testing.T) {
+	repo, gitDir := setupLayers(t, "notification_rate_limits:\n  default: 5m\n  fetch_failed: 1h\n", "", "notification_rate_limits:\n  behind_remote: 30m\n")
+	cfg, err := LoadRepository(repo, gitDir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Duration{"behind_remote": Duration(30 * time.Minute)}, cfg.NotificationRateLimits)
+
+	t.Setenv("HARBINGER_NOTIFICATION_RATE_LIMITS", "{default: 1m}")
+	cfg, err = LoadRepository(repo, gitDir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Duration{"default": Duration(time.Minute)}, cfg.NotificationRateLimits)
+}
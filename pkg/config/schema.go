@@ -0,0 +1,222 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// durationPattern matches the strings time.ParseDuration accepts
+const durationPattern = `^-?([0-9]+(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+$`
+
+// fieldDoc documents a config key, by its dotted YAML path
+type fieldDoc struct {
+	Description string
+	Enum        []string
+	Keys        []string // The keys a map may have
+	Required    bool
+}
+
+// eventTypes and levels mirror the notify package, which checks them
+var (
+	eventTypes = []string{
+		"remote_change", "out_of_sync", "conflicts", "in_sync", "auto_pull", "behind_remote", "submodule_drift", "stash_not_restored",
+		"auto_sync_failed", "fetch_failed",
+	}
+	levels   = []string{"info", "warn", "error"}
+	weekdays = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+	// hookEvents mirror the kinds of the events package
+	hookEvents = []string{
+		"remote_change", "branch_switched", "in_sync", "behind_remote", "conflicts",
+		"auto_pull", "auto_sync_failed", "fetch_failed", "submodule_drift", "stash_not_restored",
+	}
+)
+
+var fieldDocs = map[string]fieldDoc{
+	"poll_interval":   {Description: "How often to check for remote changes, e.g. 30s or 1m"},
+	"editor":          {Description: "Editor for conflicted files, with arguments (defaults to $EDITOR)"},
+	"notifications":   {Description: "Send desktop notifications (they are always logged)"},
+	"ignore_branches": {Description: "Branch glob patterns on which checks are skipped, e.g. main or release/*"},
+	"auto_resolve":    {Description: "Resolve conflicts automatically when out of sync"},
+	"auto_sync":       {Description: "Pull remote commits when the branch is behind and the working tree is clean"},
+	"auto_pull":       {Description: "Deprecated: use auto_sync"},
+	"sync_strategy":   {Description: "How auto-sync updates the branch", Enum: []string{"merge", "rebase", "ff-only"}},
+	"auto_stash":      {Description: "Stash uncommitted changes around auto-sync"},
+
+	"resolve_rules":          {Description: "Strategies applied to matching conflicted files, first match wins"},
+	"resolve_rules.pattern":  {Description: "Glob matched against the file path or its base name", Required: true},
+	"resolve_rules.strategy": {Description: "Side to keep", Enum: []string{"ours", "theirs", "union"}, Required: true},
+
+	"regenerators":         {Description: "Generated files rebuilt by taking one side and re-running a tool"},
+	"regenerators.name":    {Description: "A built-in regenerator (go, npm, yarn, cargo) or a custom name", Required: true},
+	"regenerators.files":   {Description: "Glob patterns of the generated files"},
+	"regenerators.command": {Description: "Command that regenerates the files, split on spaces"},
+	"regenerators.side":    {Description: "Side to take before regenerating", Enum: []string{"ours", "theirs"}},
+
+	"repositories":               {Description: "Repositories monitored by the daemon"},
+	"repositories.path":          {Description: "Path to the repository, ~ is expanded", Required: true},
+	"repositories.poll_interval": {Description: "Overrides the global poll_interval"},
+	"repositories.remote_branch": {Description: "Remote branch to compare against"},
+	"repositories.remote":        {Description: "Remote to compare against instead of the branch's upstream remote"},
+	"repositories.worktrees":     {Description: "Monitor every worktree of the repository"},
+	"repositories.submodules":    {Description: "Report submodules that are behind their upstream"},
+
+	"webhooks":           {Description: "HTTP endpoints that receive notifications, e.g. a team chat"},
+	"webhooks.url":       {Description: "URL to POST events to, ${VAR} is expanded from the environment", Required: true},
+	"webhooks.headers":   {Description: "Request headers, ${VAR} in values is expanded from the environment"},
+	"webhooks.template":  {Description: "Go template for the request body; the event as JSON by default"},
+	"webhooks.events":    {Description: "Event types to send, all of them by default", Enum: eventTypes},
+	"webhooks.min_level": {Description: "Least severe level to send", Enum: levels},
+	"webhooks.retries":   {Description: "Extra attempts after a failed delivery"},
+	"webhooks.backoff":   {Description: "Wait before the first retry, doubled for each one after it (default 1s)"},
+	"webhooks.timeout":   {Description: "Timeout of each attempt (default 10s)"},
+
+	"notifiers":           {Description: "Notification backends, each with its own event filter and level threshold"},
+	"notifiers.type":      {Description: "Backend to send to", Enum: []string{"desktop", "log", "webhook", "command", "email"}, Required: true},
+	"notifiers.command":   {Description: "Command run for each event with the event as JSON on stdin, split on spaces"},
+	"notifiers.events":    {Description: "Event types to send, all of them by default", Enum: eventTypes},
+	"notifiers.min_level": {Description: "Least severe level to send", Enum: levels},
+	"notifiers.url":       {Description: "Webhook URL, ${VAR} is expanded from the environment"},
+	"notifiers.headers":   {Description: "Webhook request headers, ${VAR} in values is expanded from the environment"},
+	"notifiers.template":  {Description: "Go template for the webhook request body; the event as JSON by default"},
+	"notifiers.retries":   {Description: "Extra webhook attempts after a failed delivery"},
+	"notifiers.backoff":   {Description: "Wait before the first webhook retry, doubled for each one after it (default 1s)"},
+	"notifiers.timeout":   {Description: "Timeout of each webhook attempt, command run or email delivery (default 10s)"},
+	"notifiers.smtp_host": {Description: "SMTP server that sends the emails"},
+	"notifiers.smtp_port": {Description: "SMTP server port (default 587)"},
+	"notifiers.username":  {Description: "SMTP username, ${VAR} is expanded from the environment"},
+	"notifiers.password":  {Description: "SMTP password, ${VAR} is expanded from the environment"},
+	"notifiers.starttls":  {Description: "Whether to encrypt the connection with STARTTLS (default required)", Enum: []string{"required", "optional", "never"}},
+	"notifiers.from":      {Description: "Sender address of the emails"},
+	"notifiers.to":        {Description: "Recipient addresses of the emails"},
+	"notifiers.digest":    {Description: "Collect notifications into one email per period, e.g. 30m"},
+
+	"notification_dedup":       {Description: "Repeat a notification only when the state it reports changes, e.g. the remote commit or the conflicting files"},
+	"notification_rate_limits": {Description: "Least time between notifications of an event type, e.g. {default: 5m, fetch_failed: 1h}", Keys: append([]string{"default"}, eventTypes...)},
+	"notification_digest":      {Description: "Batch notifications and send them together once per period, e.g. 10m"},
+	"quiet_hours":              {Description: "Do-not-disturb windows; notifications are held back until they end and always logged"},
+	"quiet_hours.start":        {Description: "Start of the window in local time, e.g. 22:00", Required: true},
+	"quiet_hours.end":          {Description: "End of the window, e.g. 07:00; an end before the start ends the next day", Required: true},
+	"quiet_hours.days":         {Description: "Days the window starts on, every day by default", Enum: weekdays},
+
+	"hooks":            {Description: "Shell commands run on monitor events, by event name; they get the event as JSON on stdin and in HARBINGER_EVENT_* variables", Keys: hookEvents},
+	"hook_timeout":     {Description: "Time after which a hook command is stopped (default 1m)"},
+	"hook_concurrency": {Description: "How many hook commands a monitor runs at once (default 4)"},
+}
+
+// Keys returns the top-level config keys in file order
+func Keys() []string {
+	t := reflect.TypeOf(Config{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if name := yamlName(t.Field(i)); name != "" {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+// Description returns the documentation of a dotted config key
+func Description(key string) string {
+	return fieldDocs[key].Description
+}
+
+// Schema returns a JSON schema describing the config file, for editors
+// and CI checks
+func Schema() ([]byte, error) {
+	schema := typeSchema(reflect.TypeOf(Config{}), "")
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "Harbinger configuration"
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func typeSchema(t reflect.Type, path string) map[string]interface{} {
+	if t == reflect.TypeOf(Duration(0)) {
+		return map[string]interface{}{"type": "string", "pattern": durationPattern}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Map:
+		schema := map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), path+".*")}
+		if keys := fieldDocs[path].Keys; len(keys) > 0 {
+			schema["propertyNames"] = map[string]interface{}{"enum": keys}
+		}
+		return schema
+	case reflect.Slice:
+		items := typeSchema(t.Elem(), path)
+		if enum := fieldDocs[path].Enum; len(enum) > 0 && t.Elem().Kind() == reflect.String {
+			// The enum applies to each entry of a list of strings
+			items["enum"] = enum
+		}
+		return map[string]interface{}{"type": "array", "items": items}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		var required []string
+		addProperties(t, path, properties, &required)
+
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			sort.Strings(required)
+			schema["required"] = required
+		}
+		return schema
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+// addProperties describes the fields of a struct, including the fields of
+// structs inlined into it
+func addProperties(t reflect.Type, path string, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if isInline(field) {
+			addProperties(field.Type, path, properties, required)
+			continue
+		}
+		name := yamlName(field)
+		if name == "" {
+			continue
+		}
+		key := name
+		if path != "" {
+			key = path + "." + name
+		}
+
+		property := typeSchema(field.Type, key)
+		doc := fieldDocs[key]
+		if doc.Description != "" {
+			property["description"] = doc.Description
+		}
+		if len(doc.Enum) > 0 && field.Type.Kind() != reflect.Slice {
+			property["enum"] = doc.Enum
+		}
+		if doc.Required {
+			*required = append(*required, name)
+		}
+		properties[name] = property
+	}
+}
+
+func isInline(field reflect.StructField) bool {
+	_, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	return field.Anonymous && options == "inline"
+}
+
+// yamlName returns the key a struct field is stored under
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
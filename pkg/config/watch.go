@@ -0,0 +1,92 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WatchInterval is how often running monitors look for edited config files
+const WatchInterval = 2 * time.Second
+
+// Watcher detects edits to config files by comparing their size and
+// modification time, so it works wherever os.Stat does
+type Watcher struct {
+	layers []Layer
+	stamps []fileStamp
+}
+
+type fileStamp struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// NewWatcher starts watching the files of the given layers
+func NewWatcher(layers []Layer) *Watcher {
+	w := &Watcher{layers: layers}
+	w.stamps = w.stat()
+	return w
+}
+
+// Changed reports whether any file was created, edited or removed since the
+// watcher was created or Changed last returned true
+func (w *Watcher) Changed() bool {
+	stamps := w.stat()
+	changed := false
+	for i := range stamps {
+		if stamps[i] != w.stamps[i] {
+			changed = true
+			break
+		}
+	}
+	w.stamps = stamps
+	return changed
+}
+
+func (w *Watcher) stat() []fileStamp {
+	stamps := make([]fileStamp, len(w.layers))
+	for i, layer := range w.layers {
+		if info, err := os.Stat(layer.Path); err == nil {
+			stamps[i] = fileStamp{exists: true, size: info.Size(), modTime: info.ModTime()}
+		}
+	}
+	return stamps
+}
+
+// Changes describes the top-level keys whose values differ between two
+// configs, e.g. "auto_resolve: true -> false"
+func Changes(old, updated *Config) []string {
+	var changes []string
+	for _, key := range Keys() {
+		before, after := flowValue(old, key), flowValue(updated, key)
+		if before != after {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", key, before, after))
+		}
+	}
+	return changes
+}
+
+// flowValue returns a key's value as single-line YAML, or "(unset)"
+func flowValue(cfg *Config, key string) string {
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		return "(unknown)"
+	}
+	value := mappingValue(&doc, key)
+	if value == nil {
+		return "(unset)"
+	}
+	if value.Kind == yaml.ScalarNode {
+		return value.Value
+	}
+	value.Style = yaml.FlowStyle
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return "(unknown)"
+	}
+	return string(bytes.TrimSpace(out))
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher_Changed(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	watcher := NewWatcher([]Layer{{Name: "global", Path: path}})
+	assert.False(t, watcher.Changed())
+
+	require.NoError(t, os.WriteFile(path, []byte("auto_sync: true\n"), 0644))
+	assert.True(t, watcher.Changed(), "a created file is a change")
+	assert.False(t, watcher.Changed(), "the change is only reported once")
+
+	require.NoError(t, os.WriteFile(path, []byte("auto_sync: false\n"), 0644))
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+	assert.True(t, watcher.Changed())
+
+	require.NoError(t, os.Remove(path))
+	assert.True(t, watcher.Changed(), "a removed file is a change")
+}
+
+func TestChanges(t *testing.T) {
+	old := defaults()
+	updated := defaults()
+	assert.Empty(t, Changes(old, updated))
+
+	updated.AutoResolve = false
+	updated.PollInterval = Duration(time.Minute)
+	updated.IgnoreBranches = []string{"main", "release/*"}
+	assert.Equal(t, []string{
+		"poll_interval: 30s -> 1m",
+		"ignore_branches: [] -> [main, release/*]",
+		"auto_resolve: true -> false",
+	}, Changes(old, updated))
+}